@@ -1,12 +1,18 @@
 package main
 
 import (
+	"compress/gzip"
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"time"
 
 	"cross-file-obfuscator/obfuscator"
 )
@@ -27,17 +33,21 @@ func printLogo() {
 	fmt.Println()
 }
 
-// checkAndHandleExistingDir 检查输出目录是否存在，如果存在则询问用户是否覆盖
-func checkAndHandleExistingDir(outDir string) error {
+// checkAndHandleExistingDir 检查输出目录是否存在，如果存在则询问用户是否覆盖（force 时直接删除）
+func checkAndHandleExistingDir(outDir string, force bool) error {
 	if _, err := os.Stat(outDir); err == nil {
 		// 目录存在
 		fmt.Printf("\n\033[33m⚠️  警告: 输出目录已存在: %s\033[0m\n", outDir)
-		fmt.Print("是否删除现有目录并继续? [y/N]: ")
-		
+
 		var response string
-		fmt.Scanln(&response)
-		response = strings.ToLower(strings.TrimSpace(response))
-		
+		if force {
+			response = "y"
+		} else {
+			fmt.Print("是否删除现有目录并继续? [y/N]: ")
+			fmt.Scanln(&response)
+			response = strings.ToLower(strings.TrimSpace(response))
+		}
+
 		if response == "y" || response == "yes" {
 			fmt.Printf("正在删除目录: %s\n", outDir)
 			if err := os.RemoveAll(outDir); err != nil {
@@ -45,18 +55,52 @@ func checkAndHandleExistingDir(outDir string) error {
 			}
 			fmt.Println("\033[32m✓ 目录已删除\033[0m")
 		} else {
-			return fmt.Errorf("用户取消操作")
+			return fmt.Errorf("用户取消操作（使用 -force 跳过确认）")
 		}
 	}
 	return nil
 }
 
+// isArchivePath 判断 -o 是否指向归档文件（.zip、.tar、.tar.gz、.tgz）
+func isArchivePath(path string) bool {
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(path, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// createArchiveSink 按扩展名创建归档输出，返回的函数在写入完成后关闭压缩流和文件
+func createArchiveSink(path string) (obfuscator.OutputSink, func() error, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch {
+	case strings.HasSuffix(path, ".zip"):
+		return obfuscator.NewZipSink(f), f.Close, nil
+	case strings.HasSuffix(path, ".tar"):
+		return obfuscator.NewTarSink(f), f.Close, nil
+	default:
+		gz := gzip.NewWriter(f)
+		closeAll := func() error {
+			if err := gz.Close(); err != nil {
+				f.Close()
+				return err
+			}
+			return f.Close()
+		}
+		return obfuscator.NewTarSink(gz), closeAll, nil
+	}
+}
+
 func printUsage() {
 	fmt.Println("用法: cross-file-obfuscator [选项] <项目目录>")
 	fmt.Println()
 	fmt.Println("基础选项:")
 	fmt.Println("  -h                          显示帮助信息")
-	fmt.Println("  -o string                   输出目录")
+	fmt.Println("  -o string                   输出目录，或以 .zip/.tar/.tar.gz/.tgz 结尾的归档文件")
 	fmt.Println("  -encrypt-strings            加密字符串字面量")
 	fmt.Println("  -inject-junk                注入垃圾代码")
 	fmt.Println("  -obfuscate-filenames        混淆文件名")
@@ -64,7 +108,22 @@ func printUsage() {
 	fmt.Println("  -remove-comments            移除注释 (默认: true)")
 	fmt.Println("  -preserve-reflection        保留反射类型 (默认: true)")
 	fmt.Println("  -skip-generated             跳过生成的代码 (默认: true)")
+	fmt.Println("  -generated-policy string    按生成器设置生成代码策略，例如: 'sqlc=private,stringer=full,*=skip'")
 	fmt.Println("  -exclude string             排除文件模式")
+	fmt.Println("  -obfuscate-vendor           vendor 第三方依赖并一同做源码混淆")
+	fmt.Println("  -synthesize-tags            为序列化字段补全原名标签后混淆字段名")
+	fmt.Println("  -encrypt-cgo-strings        同样加密 cgo 文件中的 Go 字符串 (配合 -encrypt-strings)")
+	fmt.Println("  -explain string             只分析项目，输出某个名称未被混淆的全部原因和位置")
+	fmt.Println("  -verify-tests               混淆后在输出目录运行 go test ./... 验证行为一致")
+	fmt.Println("  -j int                      并发处理包的任务数 (默认: CPU 核数，输出与并发数无关)")
+	fmt.Println("  -force                      输出目录或归档已存在时直接覆盖，不再询问")
+	fmt.Println("  -passes string              转换 Pass 的执行顺序，未列出的不执行 (默认: comments,rename,junk,format,strings)")
+	fmt.Println("  -report string              写出运行报告，以 .sarif 结尾时为 SARIF 格式，否则为 JSON")
+	fmt.Println("  -thresholds string          覆盖率阈值，未达到时以非零状态退出，例如: 'idents=60,func=90,strings=95,symbols=0'")
+	fmt.Println("  -scan-binary string         混淆后扫描已编译的二进制，统计仍可读的符号和明文字符串")
+	fmt.Println("  -watch                      监视项目，源码变化后增量更新输出目录 (配合 -build-with-linker 或 -auto 时重新编译)")
+	fmt.Println("  -overlay                    只写出改动的 Go 文件和 overlay.json，在原项目中用 go build -overlay 编译")
+	fmt.Println("                              (与 -encrypt-strings 同用时解密包只存在于 overlay 中，go vet 无法检查，go test 需加 -vet=off)")
 	fmt.Println()
 	fmt.Println("高级选项:")
 	fmt.Println("  -build-with-linker          直接编译并应用链接器混淆 ")
@@ -75,8 +134,19 @@ func printUsage() {
 	fmt.Println("  -obfuscate-third-party      混淆第三方依赖包 (谨慎使用)")
 	fmt.Println("  -only-project               只混淆项目包，保留标准库 (最小化 pclntab)")
 	fmt.Println("  -disable-pclntab            完全禁用 pclntab 修改 (最安全)")
+	fmt.Println("  -self-check                 后处理后运行二进制自检，失败时自动回退")
+	fmt.Println("  -smoke string               自检时传给二进制的参数 (默认: '-h')")
+	fmt.Println("  -targets string             交叉编译目标列表 (格式: 'linux/amd64,windows/amd64,darwin/arm64')")
+	fmt.Println("  -dist string                交叉编译产物目录 (配合 -targets, 默认: 'dist')")
+	fmt.Println("  -toolexec                   以 go build -toolexec 包装器方式编译 (不复制源码，依赖和标准库同样改写)")
+	fmt.Println("                              (只改名局部变量和包级未导出的函数、变量、常量；类型、方法、字段和导出名称保持原名)")
+	fmt.Println("  -seed string                命名种子 (源码混淆和 -toolexec 模式，相同种子得到相同输出，默认随机)")
 	fmt.Println("  -auto                       自动模式：全功能混淆 + 自动编译 (推荐!)")
 	fmt.Println()
+	fmt.Println("子命令:")
+	fmt.Println("  scan [-json] [-allow kinds] [-ignore values] [-min-length n] <二进制> <源码目录>")
+	fmt.Println("                              报告二进制中仍然出现的原始标识符、包路径、文件路径、字符串和注释，存在泄露时以非零状态退出")
+	fmt.Println()
 	fmt.Println("示例:")
 	fmt.Println("  # 🚀 自动模式 - 一键全功能混淆 (推荐！)")
 	fmt.Println("  ./cross-file-obfuscator -auto -output-bin myapp ./my-project")
@@ -93,16 +163,36 @@ func printUsage() {
 	fmt.Println("  # 编译为 Windows 64位程序")
 	fmt.Println("  GOOS=windows GOARCH=amd64 ./cross-file-obfuscator -auto -output-bin app.exe ./my-project")
 	fmt.Println()
+	fmt.Println("  # 一次构建多个平台（产物、映射文件和汇总表写入 dist/）")
+	fmt.Println("  ./cross-file-obfuscator -auto -targets linux/amd64,windows/amd64,darwin/arm64 -output-bin app ./my-project")
+	fmt.Println()
+	fmt.Println("  # 发布前检查二进制是否泄露源码信息")
+	fmt.Println("  ./cross-file-obfuscator scan myapp ./my-project")
+	fmt.Println()
 	fmt.Println("详细文档: README.md")
 }
 
 func main() {
+	// 作为 go build -toolexec 包装器被调用时直接执行工具，不能输出任何其他内容
+	if obfuscator.IsToolexecInvocation() {
+		os.Exit(obfuscator.RunToolexec(os.Args[1:]))
+	}
+
+	// scan 子命令：检查二进制中残留的源码信息，输出可能是 JSON，因此不显示 Logo
+	if len(os.Args) > 1 && os.Args[1] == "scan" {
+		os.Exit(runScan(os.Args[2:]))
+	}
+
 	// 显示 Logo
 	printLogo()
+
+	// Ctrl+C 时取消正在进行的混淆（已写出的文件保留在输出目录）
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 	
 	// 基础选项
 	var (
-		outputDir          = flag.String("o", "", "输出目录 (默认: project_directory_obfuscated)")
+		outputDir          = flag.String("o", "", "输出目录，以 .zip/.tar/.tar.gz/.tgz 结尾时写入归档 (默认: project_directory_obfuscated)")
 		obfuscateExported  = flag.Bool("obfuscate-exported", false, "混淆导出的函数和变量 (可能破坏外部引用)")
 		obfuscateFileNames = flag.Bool("obfuscate-filenames", false, "混淆 Go 文件名")
 		encryptStrings     = flag.Bool("encrypt-strings", false, "加密字符串字面量并运行时解密")
@@ -110,7 +200,21 @@ func main() {
 		removeComments     = flag.Bool("remove-comments", true, "移除所有注释")
 		preserveReflection = flag.Bool("preserve-reflection", true, "保留反射中使用的类型/方法")
 		skipGeneratedCode  = flag.Bool("skip-generated", true, "跳过自动生成的代码文件")
+		generatedPolicy    = flag.String("generated-policy", "", "按生成器设置生成代码策略 skip/private/full，例如: 'sqlc=private,stringer=full,*=skip' (默认全部跳过)")
 		excludePatterns    = flag.String("exclude", "", "要排除的文件模式 (逗号分隔, 例如: -exclude '*_test.go,*.pb.go')")
+		obfuscateVendor    = flag.Bool("obfuscate-vendor", false, "通过 go mod vendor 对第三方依赖做源码级混淆")
+		synthesizeTags     = flag.Bool("synthesize-tags", false, "为 JSON/XML/YAML 等序列化字段补全携带原名的标签，然后混淆字段名")
+		encryptCgoStrings  = flag.Bool("encrypt-cgo-strings", false, "同样加密 cgo 文件（导入 \"C\"）中的 Go 字符串（配合 -encrypt-strings）")
+		explainName        = flag.String("explain", "", "只分析项目，输出指定名称未被混淆的原因和位置")
+		verifyTests        = flag.Bool("verify-tests", false, "混淆后在输出目录运行 go test ./...，验证测试仍然通过")
+		workers            = flag.Int("j", 0, "并发处理包的任务数（默认 CPU 核数）")
+		force              = flag.Bool("force", false, "输出目录或归档已存在时直接覆盖，不再询问")
+		passOrder          = flag.String("passes", "", "转换 Pass 的执行顺序，未列出的不执行 (逗号分隔，默认: comments,rename,junk,format,strings)")
+		thresholdSpec      = flag.String("thresholds", "", "覆盖率阈值，未达到时以非零状态退出：idents/strings/func/local 等为最低百分比，symbols/literals 为二进制中可读数量的上限")
+		scanBinary         = flag.String("scan-binary", "", "混淆后扫描已编译的二进制，统计 pclntab 中仍可读的符号和只读数据中的明文字符串")
+		watchMode          = flag.Bool("watch", false, "监视项目目录，源码变化后只重新混淆受影响的包（配合 -build-with-linker 或 -auto 时每次重新编译）")
+		overlayMode        = flag.Bool("overlay", false, "只写出改动的 Go 文件和 go build -overlay 使用的 overlay.json，不复制整个项目（加密字符串时 go vet 无法检查只存在于 overlay 中的解密包，go test 需加 -vet=off）")
+		reportPath         = flag.String("report", "", "写出运行报告（逐文件的改名、加密字符串、垃圾代码和风险位置），以 .sarif 结尾时为 SARIF 格式，否则为 JSON")
 		showHelp           = flag.Bool("h", false, "显示帮助信息")
 	)
 
@@ -124,6 +228,12 @@ func main() {
 		obfuscateThirdParty  = flag.Bool("obfuscate-third-party", false, "混淆第三方依赖包（谨慎使用）")
 		onlyObfuscateProject = flag.Bool("only-project", false, "只混淆项目包，保留标准库（最小化 pclntab，推荐 Windows）")
 		disablePclntab       = flag.Bool("disable-pclntab", false, "完全禁用 pclntab 修改（最安全但保护较弱）")
+		selfCheck            = flag.Bool("self-check", false, "后处理后运行二进制自检，失败时恢复备份并回退到更安全的配置")
+		smokeArgs            = flag.String("smoke", "", "自检时传给二进制的参数，例如: -smoke 'version' (默认: -h)")
+		buildTargets         = flag.String("targets", "", "交叉编译目标列表，例如: 'linux/amd64,windows/amd64,darwin/arm64'")
+		distDir              = flag.String("dist", "dist", "交叉编译产物目录（配合 -targets 使用）")
+		useToolexec          = flag.Bool("toolexec", false, "以 go build -toolexec 包装器方式编译（配合 -build-with-linker 使用）；只改名局部变量和包级未导出的函数、变量、常量，类型、方法、字段和导出名称保持原名")
+		namingSeed           = flag.String("seed", "", "命名种子，源码混淆和 -toolexec 模式中相同种子得到相同输出（默认随机）")
 		autoMode             = flag.Bool("auto", false, "自动模式：全功能混淆 + 自动编译（Windows 下自动使用最小化 pclntab）")
 	)
 
@@ -138,6 +248,70 @@ func main() {
 		os.Exit(0)
 	}
 
+	// 解析交叉编译目标列表
+	var targets []obfuscator.BuildTarget
+	if *buildTargets != "" {
+		var err error
+		if targets, err = obfuscator.ParseBuildTargets(*buildTargets); err != nil {
+			log.Fatalf("错误: %v", err)
+		}
+	}
+
+	// 解析生成代码策略
+	generatedPolicies, err := obfuscator.ParseGeneratedPolicies(*generatedPolicy)
+	if err != nil {
+		log.Fatalf("错误: %v", err)
+	}
+
+	// 解析转换 Pass 顺序
+	var passes []string
+	if *passOrder != "" {
+		passes = strings.Split(*passOrder, ",")
+		for i := range passes {
+			passes[i] = strings.TrimSpace(passes[i])
+		}
+	}
+
+	// 解析覆盖率阈值。二进制相关的阈值需要扫描二进制：-auto 单目标构建时自动扫描，否则需要 -scan-binary
+	thresholds, err := obfuscator.ParseThresholds(*thresholdSpec)
+	if err != nil {
+		log.Fatalf("错误: %v", err)
+	}
+	if len(thresholds) > 0 && *buildWithLinker && !*autoMode {
+		log.Fatal("错误: -thresholds 需要源码混淆，不能与单独的 -build-with-linker 一起使用")
+	}
+	if thresholds.NeedsBinary() && *scanBinary == "" && (!*autoMode || len(targets) > 0) {
+		log.Fatal("错误: symbols/literals 阈值需要扫描二进制，请使用 -scan-binary 或 -auto（单目标）")
+	}
+
+	// 归档输出只包含源码，之后的编译和测试需要输出目录
+	if *outputDir != "" && isArchivePath(*outputDir) && (*autoMode || *buildWithLinker || *verifyTests) {
+		log.Fatal("错误: 输出到归档时不能使用 -auto、-build-with-linker 或 -verify-tests")
+	}
+
+	// 监视模式持续更新输出目录，不支持一次性的检查和归档输出
+	if *watchMode {
+		if *outputDir != "" && isArchivePath(*outputDir) {
+			log.Fatal("错误: -watch 需要输出目录，不能输出到归档")
+		}
+		if *explainName != "" || *verifyTests || len(targets) > 0 || len(thresholds) > 0 || *scanBinary != "" || *useToolexec {
+			log.Fatal("错误: -watch 不能与 -explain、-verify-tests、-targets、-thresholds、-scan-binary 或 -toolexec 一起使用")
+		}
+	}
+
+	// overlay 模式只写出改动的文件，编译和测试在原项目目录中进行
+	if *overlayMode {
+		if *outputDir != "" && isArchivePath(*outputDir) {
+			log.Fatal("错误: -overlay 需要输出目录，不能输出到归档")
+		}
+		if *obfuscateVendor || *watchMode {
+			log.Fatal("错误: -overlay 不能与 -obfuscate-vendor 或 -watch 一起使用")
+		}
+		if *buildWithLinker && !*autoMode {
+			log.Fatal("错误: -overlay 需要源码混淆，不能与单独的 -build-with-linker 一起使用")
+		}
+	}
+
 	// 如果使用 auto 模式，执行全功能混淆
 	if *autoMode {
 		if flag.NArg() < 1 {
@@ -172,7 +346,7 @@ func main() {
 		}
 
 		// 检查输出目录是否已存在
-		if err := checkAndHandleExistingDir(outDir); err != nil {
+		if err := checkAndHandleExistingDir(outDir, *force); err != nil {
 			log.Fatalf("错误: %v", err)
 		}
 
@@ -185,8 +359,12 @@ func main() {
 			}
 		}
 
-		// 创建源码混淆器
-		sourceObf := obfuscator.New(projectRoot, outDir, &obfuscator.Config{
+		binName := *outputBinary
+		if binName == "" {
+			binName = "output_obfuscated"
+		}
+
+		autoConfig := &obfuscator.Config{
 			ObfuscateExported:  true,
 			ObfuscateFileNames: true,
 			EncryptStrings:     true,
@@ -194,13 +372,44 @@ func main() {
 			RemoveComments:     *removeComments,
 			PreserveReflection: *preserveReflection,
 			SkipGeneratedCode:  *skipGeneratedCode,
+			GeneratedPolicies:  generatedPolicies,
 			ExcludePatterns:    excludeList,
-		})
+			ObfuscateVendor:    *obfuscateVendor,
+			SynthesizeTags:     *synthesizeTags,
+			EncryptCgoStrings:  *encryptCgoStrings,
+			Seed:               *namingSeed,
+			Workers:            *workers,
+			Passes:             passes,
+			Overlay:            *overlayMode,
+		}
+
+		if *watchMode {
+			windows := os.Getenv("GOOS") == "windows" || os.Getenv("GOOS") == "" && runtime.GOOS == "windows"
+			runWatch(ctx, projectRoot, outDir, autoConfig, binName, *reportPath, func(embedded []string) *obfuscator.LinkConfig {
+				return &obfuscator.LinkConfig{
+					RemoveFuncNames:      true,
+					EntryPackage:         *entryPackage,
+					AutoDiscoverPackages: true,
+					OnlyObfuscateProject: windows,
+					SelfCheck:            *selfCheck,
+					SmokeArgs:            *smokeArgs,
+					EmbeddedFiles:        embedded,
+				}
+			})
+			return
+		}
+
+		// 创建源码混淆器
+		sourceObf := obfuscator.New(projectRoot, outDir, autoConfig, obfuscator.WithLogger(log.Default()))
 
 		// 执行源码混淆
-		if err := sourceObf.Run(); err != nil {
+		result, err := sourceObf.Run(ctx)
+		if err != nil {
 			log.Fatalf("源码混淆失败: %v", err)
 		}
+		if *verifyTests {
+			runTestVerification(ctx, sourceObf)
+		}
 
 		fmt.Println()
 		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
@@ -208,10 +417,43 @@ func main() {
 		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 		fmt.Println()
 
+		// overlay 模式在原项目目录中编译，由 overlay.json 替换改动的文件
+		buildDir := outDir
+		if result.Overlay != "" {
+			buildDir = projectRoot
+		}
+
 		// 第二步：链接器混淆
-		binName := *outputBinary
-		if binName == "" {
-			binName = "output_obfuscated"
+		// 指定了多个目标平台时按矩阵构建，每个 Windows 目标单独使用最小化 pclntab
+		if len(targets) > 0 {
+			linkConfig := &obfuscator.LinkConfig{
+				RemoveFuncNames:      true,
+				EntryPackage:         *entryPackage,
+				AutoDiscoverPackages: true,
+				ObfuscateThirdParty:  false,
+				WindowsOnlyProject:   true,
+				SelfCheck:            *selfCheck,
+				SmokeArgs:            *smokeArgs,
+				EmbeddedFiles:        sourceObf.EmbeddedFiles(),
+				Overlay:              result.Overlay,
+			}
+
+			linkerObf := obfuscator.NewLinkerObfuscator(buildDir, binName, linkConfig, linkerLogger())
+			if _, err := linkerObf.BuildMatrix(targets, *distDir); err != nil {
+				log.Fatalf("链接器混淆失败: %v", err)
+			}
+			finishCoverage(sourceObf, result, "", *reportPath, thresholds)
+
+			fmt.Println()
+			fmt.Println("╔══════════════════════════════════════════════════════════════╗")
+			fmt.Println("║                    ✅ 全功能混淆完成！                        ║")
+			fmt.Println("╚══════════════════════════════════════════════════════════════╝")
+			fmt.Printf("\n📦 混淆后的二进制目录: %s\n", *distDir)
+			fmt.Printf("📁 混淆后的源码目录: %s\n", outDir)
+			if result.Overlay != "" {
+				fmt.Printf("🗂️  overlay 文件: %s（在 %s 中使用 go build -overlay）\n", result.Overlay, projectRoot)
+			}
+			return
 		}
 
 		// 检测目标平台，智能选择 pclntab 混淆策略
@@ -254,13 +496,22 @@ func main() {
 			ObfuscateThirdParty:  false,     // AUTO 模式不混淆第三方包
 			OnlyObfuscateProject: isWindows, // ⭐ Windows: 最小化，其他: 完整
 			DisablePclntab:       false,     // 不完全禁用
+			SelfCheck:            *selfCheck,
+			SmokeArgs:            *smokeArgs,
+			EmbeddedFiles:        sourceObf.EmbeddedFiles(), // 嵌入数据区间不会被修改
+			Overlay:              result.Overlay,
 		}
 
-		linkerObf := obfuscator.NewLinkerObfuscator(outDir, binName, linkConfig)
+		linkerObf := obfuscator.NewLinkerObfuscator(buildDir, binName, linkConfig, linkerLogger())
 
 		if err := linkerObf.BuildWithLinkerObfuscation(); err != nil {
 			log.Fatalf("链接器混淆失败: %v", err)
 		}
+		scanPath := binName
+		if *scanBinary != "" {
+			scanPath = *scanBinary
+		}
+		finishCoverage(sourceObf, result, scanPath, *reportPath, thresholds)
 
 		fmt.Println()
 		fmt.Println("╔══════════════════════════════════════════════════════════════╗")
@@ -268,14 +519,16 @@ func main() {
 		fmt.Println("╚══════════════════════════════════════════════════════════════╝")
 		fmt.Printf("\n📦 混淆后的二进制文件: %s\n", binName)
 		fmt.Printf("📁 混淆后的源码目录: %s\n", outDir)
+		if result.Overlay != "" {
+			fmt.Printf("🗂️  overlay 文件: %s（在 %s 中使用 go build -overlay）\n", result.Overlay, projectRoot)
+		}
 		fmt.Println("\n验证混淆效果:")
-		fmt.Printf("  strings %s | grep -i 'main\\.' | wc -l\n", binName)
-		fmt.Printf("  strings %s | grep -i 'runtime\\.' | wc -l\n", binName)
+		fmt.Printf("  %s scan %s %s\n", os.Args[0], binName, projectRoot)
 		return
 	}
 
-	// 如果使用链接器构建模式
-	if *buildWithLinker {
+	// 如果使用链接器构建模式（-watch 时先做源码混淆，再编译输出目录）
+	if *buildWithLinker && !*watchMode {
 		if flag.NArg() < 1 {
 			log.Fatal("错误: 请指定项目目录")
 		}
@@ -288,20 +541,7 @@ func main() {
 		}
 
 		// 解析包名替换映射
-		pkgReplaceMap := make(map[string]string)
-		if *packageReplacements != "" {
-			pairs := strings.Split(*packageReplacements, ",")
-			for _, pair := range pairs {
-				parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
-				if len(parts) == 2 {
-					original := strings.TrimSpace(parts[0])
-					replacement := strings.TrimSpace(parts[1])
-					if original != "" && replacement != "" {
-						pkgReplaceMap[original] = replacement
-					}
-				}
-			}
-		}
+		pkgReplaceMap := parsePackageReplacements(*packageReplacements)
 
 		// 创建链接器混淆器
 		linkConfig := &obfuscator.LinkConfig{
@@ -312,9 +552,22 @@ func main() {
 			ObfuscateThirdParty:  *obfuscateThirdParty,  // 混淆第三方包
 			OnlyObfuscateProject: *onlyObfuscateProject, // 只混淆项目包
 			DisablePclntab:       *disablePclntab,       // 完全禁用 pclntab
+			SelfCheck:            *selfCheck,            // 后处理自检
+			SmokeArgs:            *smokeArgs,            // 自检参数
+			UseToolexec:          *useToolexec,          // -toolexec 包装器模式
+			ToolexecSeed:         *namingSeed,           // 命名种子
 		}
 
-		linkerObf := obfuscator.NewLinkerObfuscator(projectRoot, binName, linkConfig)
+		linkerObf := obfuscator.NewLinkerObfuscator(projectRoot, binName, linkConfig, linkerLogger())
+
+		// 按目标平台矩阵构建
+		if len(targets) > 0 {
+			if _, err := linkerObf.BuildMatrix(targets, *distDir); err != nil {
+				log.Fatalf("链接器混淆失败: %v", err)
+			}
+			fmt.Printf("\n✅ 成功! 混淆后的二进制目录: %s\n", *distDir)
+			return
+		}
 
 		// 执行构建和混淆
 		if err := linkerObf.BuildWithLinkerObfuscation(); err != nil {
@@ -323,8 +576,7 @@ func main() {
 
 		fmt.Printf("\n✅ 成功! 混淆后的二进制文件: %s\n", binName)
 		fmt.Println("\n验证混淆效果:")
-		fmt.Printf("  strings %s | grep -i 'main\\.' | head -20\n", binName)
-		fmt.Printf("  strings %s | grep -i 'runtime\\.' | head -20\n", binName)
+		fmt.Printf("  %s scan %s %s\n", os.Args[0], binName, projectRoot)
 		return
 	}
 
@@ -344,13 +596,18 @@ func main() {
 		*outputDir = projectRoot + "_obfuscated"
 	}
 
-	// 检查输出目录是否已存在
-	if err := checkAndHandleExistingDir(*outputDir); err != nil {
-		log.Fatalf("错误: %v", err)
-	}
+	// -explain 只做分析，不写出混淆后的代码
+	if *explainName == "" {
+		// 检查输出目录是否已存在
+		if err := checkAndHandleExistingDir(*outputDir, *force); err != nil {
+			log.Fatalf("错误: %v", err)
+		}
 
-	if err := os.MkdirAll(*outputDir, 0755); err != nil {
-		log.Fatalf("错误: 无法创建输出目录 %s: %v", *outputDir, err)
+		if !isArchivePath(*outputDir) {
+			if err := os.MkdirAll(*outputDir, 0755); err != nil {
+				log.Fatalf("错误: 无法创建输出目录 %s: %v", *outputDir, err)
+			}
+		}
 	}
 
 	// 解析排除模式
@@ -371,30 +628,197 @@ func main() {
 		RemoveComments:     *removeComments,
 		PreserveReflection: *preserveReflection,
 		SkipGeneratedCode:  *skipGeneratedCode,
+		GeneratedPolicies:  generatedPolicies,
 		ExcludePatterns:    excludePatternsList,
+		ObfuscateVendor:    *obfuscateVendor,
+		SynthesizeTags:     *synthesizeTags,
+		EncryptCgoStrings:  *encryptCgoStrings,
+		Seed:               *namingSeed,
+		Workers:            *workers,
+		Passes:             passes,
+		Overlay:            *overlayMode,
 	}
 
 	// 创建混淆器
-	obf := obfuscator.New(projectRoot, *outputDir, config)
+	opts := []obfuscator.Option{obfuscator.WithLogger(log.Default())}
+	closeArchive := func() error { return nil }
+	if *explainName == "" && isArchivePath(*outputDir) {
+		sink, closeFn, err := createArchiveSink(*outputDir)
+		if err != nil {
+			log.Fatalf("错误: 无法创建归档 %s: %v", *outputDir, err)
+		}
+		opts = append(opts, obfuscator.WithOutput(sink))
+		closeArchive = closeFn
+	}
+	obf := obfuscator.New(projectRoot, *outputDir, config, opts...)
+
+	if *explainName != "" {
+		if err := obf.Analyze(ctx); err != nil {
+			log.Fatalf("错误: %v", err)
+		}
+		printExplanation(*explainName, obf.Explain(*explainName), obf.ObjectRenames(*explainName), obf.LocalRenames(*explainName))
+		return
+	}
 
 	// 打印配置
 	printConfiguration(projectRoot, *outputDir, config, excludePatternsList)
 
+	if *watchMode {
+		binName := ""
+		var linkConfig func(embedded []string) *obfuscator.LinkConfig
+		if *buildWithLinker {
+			binName = *outputBinary
+			if binName == "" {
+				binName = "output_obfuscated"
+			}
+			linkConfig = func(embedded []string) *obfuscator.LinkConfig {
+				return &obfuscator.LinkConfig{
+					RemoveFuncNames:      true,
+					EntryPackage:         *entryPackage,
+					PackageReplacements:  parsePackageReplacements(*packageReplacements),
+					AutoDiscoverPackages: *autoDiscoverPkgs,
+					ObfuscateThirdParty:  *obfuscateThirdParty,
+					OnlyObfuscateProject: *onlyObfuscateProject,
+					DisablePclntab:       *disablePclntab,
+					SelfCheck:            *selfCheck,
+					SmokeArgs:            *smokeArgs,
+					EmbeddedFiles:        embedded,
+				}
+			}
+		}
+		runWatch(ctx, projectRoot, *outputDir, config, binName, *reportPath, linkConfig)
+		return
+	}
+
 	// 执行混淆
 	fmt.Println("开始混淆...")
-	if err := runObfuscation(obf, config, projectRoot, *outputDir); err != nil {
+	result, err := runObfuscation(ctx, obf)
+	if closeErr := closeArchive(); err == nil && closeErr != nil {
+		err = fmt.Errorf("写入归档失败: %v", closeErr)
+	}
+	if err != nil {
+		if isArchivePath(*outputDir) {
+			os.Remove(*outputDir)
+		}
 		log.Fatalf("错误: %v", err)
 	}
 
 	// 打印统计信息
-	stats := obf.GetStatistics()
-	printStatistics(stats)
+	printStatistics(result.Statistics)
+	if config.SynthesizeTags {
+		printTagReport(result.SynthesizedTags)
+	}
+	finishCoverage(obf, result, *scanBinary, *reportPath, thresholds)
+	if *verifyTests {
+		runTestVerification(ctx, obf)
+	}
 
 	fmt.Println("\n✅ 混淆完成!")
-	fmt.Println("请在输出目录中运行 'go build' 以验证编译。")
+	if result.OutputDir == "" {
+		fmt.Printf("混淆后的源码已写入归档: %s\n", *outputDir)
+		return
+	}
+	if result.Overlay != "" {
+		fmt.Printf("请在项目目录中运行 'cd %s && go build -overlay %s ./...' 以验证编译。\n", projectRoot, result.Overlay)
+		if result.Mapping.DecryptPackage != "" {
+			fmt.Println("注意: 解密包只存在于 overlay 中，go vet 无法检查它；运行测试时使用 'go test -vet=off -overlay ...'")
+		}
+	} else {
+		fmt.Println("请在输出目录中运行 'go build' 以验证编译。")
+	}
 	fmt.Println("\n提示: 使用 -build-with-linker 可以直接编译并应用链接器级别混淆")
 }
 
+// parsePackageReplacements 解析 -pkg-replace 的 'original1=new1,original2=new2' 映射
+func parsePackageReplacements(spec string) map[string]string {
+	pkgReplaceMap := make(map[string]string)
+	if spec == "" {
+		return pkgReplaceMap
+	}
+	for _, pair := range strings.Split(spec, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(parts) == 2 {
+			original := strings.TrimSpace(parts[0])
+			replacement := strings.TrimSpace(parts[1])
+			if original != "" && replacement != "" {
+				pkgReplaceMap[original] = replacement
+			}
+		}
+	}
+	return pkgReplaceMap
+}
+
+// runWatch 监视项目并保持输出目录同步，直到 Ctrl+C。linkConfig 非空时每次更新后
+// 通过链接器混淆重新编译 binName；reportPath 非空时每次更新后重写运行报告
+func runWatch(ctx context.Context, projectRoot, outDir string, config *obfuscator.Config, binName, reportPath string, linkConfig func(embedded []string) *obfuscator.LinkConfig) {
+	fmt.Printf("👀 监视模式: %s → %s（Ctrl+C 退出）\n", projectRoot, outDir)
+	var ignore []string
+	if binName != "" {
+		ignore = append(ignore, binName, binName+".backup")
+	}
+	if reportPath != "" {
+		ignore = append(ignore, reportPath)
+	}
+
+	onUpdate := func(u obfuscator.WatchUpdate) {
+		fmt.Println()
+		if len(u.Changed) > 0 {
+			fmt.Printf("📝 变化: %s\n", strings.Join(u.Changed, ", "))
+		}
+		if u.Err != nil {
+			fmt.Printf("❌ 更新失败（%v）: %v\n", u.Duration.Round(time.Millisecond), u.Err)
+			return
+		}
+		switch {
+		case u.Full:
+			fmt.Printf("✅ 已完整混淆 %d 个包（%v）\n", len(u.Packages), u.Duration.Round(time.Millisecond))
+		case len(u.Packages) > 0:
+			fmt.Printf("✅ 已重新混淆: %s（%v）\n", strings.Join(u.Packages, ", "), u.Duration.Round(time.Millisecond))
+		default:
+			fmt.Println("✅ 已同步非 Go 文件")
+			return
+		}
+		if reportPath != "" {
+			writeReport(reportPath, u.Result.Report)
+		}
+		if linkConfig == nil {
+			return
+		}
+		start := time.Now()
+		linkerObf := obfuscator.NewLinkerObfuscator(outDir, binName, linkConfig(u.Result.EmbeddedFiles), linkerLogger())
+		if err := linkerObf.BuildWithLinkerObfuscation(); err != nil {
+			fmt.Printf("❌ 编译失败: %v\n", err)
+			return
+		}
+		fmt.Printf("📦 已重新编译: %s（%v）\n", binName, time.Since(start).Round(time.Millisecond))
+	}
+
+	err := obfuscator.Watch(ctx, projectRoot, outDir, config, obfuscator.WatchOptions{
+		Ignore:   ignore,
+		OnUpdate: onUpdate,
+	}, obfuscator.WithEventHandler(func(e obfuscator.Event) {
+		// 每次更新都会重新执行全部阶段，只输出警告
+		if e.Kind == obfuscator.EventWarning {
+			fmt.Printf("⚠️  %s\n", e.Message)
+		}
+	}))
+	if err != nil && err != context.Canceled {
+		log.Fatalf("错误: %v", err)
+	}
+	fmt.Println("\n👋 已停止监视")
+}
+
+// runTestVerification 在混淆后的项目中运行测试，失败时输出测试日志并退出
+func runTestVerification(ctx context.Context, obf *obfuscator.Obfuscator) {
+	fmt.Println("\n🧪 在混淆后的项目中运行 go test ./...")
+	output, err := obf.VerifyTests(ctx)
+	if err != nil {
+		fmt.Println(output)
+		log.Fatalf("❌ 测试验证失败: %v", err)
+	}
+	fmt.Println("✅ 混淆后的测试全部通过")
+}
+
 func printConfiguration(projectRoot, outputDir string, config *obfuscator.Config, excludePatterns []string) {
 	fmt.Println("========================================")
 	fmt.Println("   Go 代码混淆器")
@@ -411,18 +835,90 @@ func printConfiguration(projectRoot, outputDir string, config *obfuscator.Config
 	}
 	fmt.Printf("  混淆文件名:       %v\n", config.ObfuscateFileNames)
 	fmt.Printf("  加密字符串:       %v\n", config.EncryptStrings)
+	if config.EncryptStrings {
+		fmt.Printf("  加密 cgo 字符串:  %v\n", config.EncryptCgoStrings)
+	}
 	fmt.Printf("  注入垃圾代码:     %v\n", config.InjectJunkCode)
 	fmt.Printf("  移除注释:         %v\n", config.RemoveComments)
 	fmt.Printf("  保留反射:         %v\n", config.PreserveReflection)
 	fmt.Printf("  跳过生成代码:     %v\n", config.SkipGeneratedCode)
+	if config.SkipGeneratedCode && len(config.GeneratedPolicies) > 0 {
+		fmt.Printf("  生成代码策略:     %v\n", config.GeneratedPolicies)
+	}
+	fmt.Printf("  混淆依赖(vendor): %v\n", config.ObfuscateVendor)
+	fmt.Printf("  合成结构体标签:   %v\n", config.SynthesizeTags)
+	if config.Overlay {
+		fmt.Println("  输出方式:         overlay（只写出改动的 Go 文件和 overlay.json）")
+	}
 	if len(excludePatterns) > 0 {
 		fmt.Printf("  排除模式:         %v\n", excludePatterns)
 	}
+	if config.Workers > 0 {
+		fmt.Printf("  并发任务数:       %d\n", config.Workers)
+	}
+	if config.Seed != "" {
+		fmt.Printf("  命名种子:         %s（相同种子得到相同输出）\n", config.Seed)
+	}
+	if len(config.Passes) > 0 {
+		fmt.Printf("  转换 Pass:        %s\n", strings.Join(config.Passes, ","))
+	}
 	fmt.Println()
 }
 
-func runObfuscation(obf *obfuscator.Obfuscator, config *obfuscator.Config, projectRoot, outputDir string) error {
-	return obf.Run()
+// finishCoverage 扫描二进制（binPath 非空时）、输出覆盖率、写出运行报告，然后检查阈值，未达到时退出
+func finishCoverage(obf *obfuscator.Obfuscator, result *obfuscator.Result, binPath, reportPath string, thresholds obfuscator.Thresholds) {
+	if binPath != "" {
+		if _, err := obf.ScanBinary(binPath); err != nil {
+			log.Fatalf("错误: %v", err)
+		}
+		// 扫描结果在 Run 之后才有，刷新结果中的统计
+		result.Statistics = obf.GetStatistics()
+		result.Report.Statistics = result.Statistics
+	}
+	printCoverage(result.Statistics.Coverage)
+	if reportPath != "" {
+		writeReport(reportPath, result.Report)
+	}
+	if len(thresholds) == 0 {
+		return
+	}
+	if failed := result.Statistics.Coverage.Check(thresholds); len(failed) > 0 {
+		fmt.Println("\n❌ 未达到覆盖率阈值:")
+		for _, f := range failed {
+			fmt.Printf("   - %s\n", f)
+		}
+		os.Exit(1)
+	}
+	fmt.Println("\n✅ 覆盖率达到全部阈值")
+}
+
+// writeReport 写出运行报告：.sarif 文件为 SARIF 格式，其余为 JSON
+func writeReport(path string, report *obfuscator.Report) {
+	f, err := os.Create(path)
+	if err != nil {
+		log.Fatalf("错误: 无法创建报告 %s: %v", path, err)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".sarif") {
+		err = report.WriteSARIF(f)
+	} else {
+		err = report.WriteJSON(f)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		log.Fatalf("错误: 写入报告 %s 失败: %v", path, err)
+	}
+	fmt.Printf("\n📄 运行报告已写入: %s（%d 个文件，%d 处风险）\n", path, len(report.Files), len(report.Risks))
+}
+
+func runObfuscation(ctx context.Context, obf *obfuscator.Obfuscator) (*obfuscator.Result, error) {
+	return obf.Run(ctx)
+}
+
+// linkerLogger 链接器混淆的进度直接输出到终端（不带时间前缀）
+func linkerLogger() obfuscator.Option {
+	return obfuscator.WithLogger(log.New(os.Stdout, "", 0))
 }
 
 func printStatistics(stats *obfuscator.Statistics) {
@@ -430,10 +926,213 @@ func printStatistics(stats *obfuscator.Statistics) {
 	fmt.Println("========================================")
 	fmt.Println("   混淆统计")
 	fmt.Println("========================================")
+	if stats.TotalFiles > 0 {
+		fmt.Printf("混淆文件:   %d / %d\n", stats.ObfuscatedFiles, stats.TotalFiles)
+	}
 	fmt.Printf("受保护名称: %d\n", stats.ProtectedNames)
 	fmt.Printf("混淆函数:   %d\n", stats.FunctionsObf)
 	fmt.Printf("混淆变量:   %d\n", stats.VariablesObf)
+	if stats.FieldsObf > 0 {
+		fmt.Printf("混淆字段:   %d\n", stats.FieldsObf)
+	}
+	if stats.IdentsRenamed > 0 {
+		fmt.Printf("改名标识符: %d\n", stats.IdentsRenamed)
+	}
+	if stats.StringsEncrypt > 0 {
+		fmt.Printf("加密字符串: %d\n", stats.StringsEncrypt)
+	}
+	if stats.JunkBlocks > 0 {
+		fmt.Printf("垃圾代码:   %d 个函数\n", stats.JunkBlocks)
+	}
 	if stats.SkippedFiles > 0 {
 		fmt.Printf("跳过文件:   %d\n", stats.SkippedFiles)
 	}
+	if stats.ParsedFiles > 0 {
+		fmt.Printf("解析文件:   %d（%d 个包，每个文件只解析一次）\n", stats.ParsedFiles, stats.Packages)
+	}
+	if len(stats.Phases) > 0 {
+		fmt.Println("阶段耗时:")
+		var total time.Duration
+		for _, phase := range stats.Phases {
+			fmt.Printf("   %-10v %s\n", phase.Duration.Round(time.Millisecond), phase.Name)
+			total += phase.Duration
+		}
+		fmt.Printf("   %-10v %s\n", total.Round(time.Millisecond), "合计")
+		fmt.Printf("内存峰值:   %.1f MB（累计分配 %.1f MB）\n",
+			float64(stats.PeakHeapBytes)/(1<<20), float64(stats.TotalAllocBytes)/(1<<20))
+	}
+}
+
+func printCoverage(cov *obfuscator.Coverage) {
+	if cov == nil || cov.IdentsTotal == 0 {
+		return
+	}
+	fmt.Println()
+	fmt.Println("========================================")
+	fmt.Println("   混淆覆盖率")
+	fmt.Println("========================================")
+	for _, k := range cov.Identifiers {
+		fmt.Printf("   %-8s %6d / %-6d %5.1f%%\n", k.Kind, k.Renamed, k.Total, k.Percent)
+	}
+	fmt.Printf("   %-6s %6d / %-6d %5.1f%%\n", "合计", cov.IdentsRenamed, cov.IdentsTotal, cov.IdentsPercent)
+	fmt.Printf("字符串加密: %d / %d 字节（%.1f%%）\n", cov.EncryptedBytes, cov.StringBytes, cov.StringsPercent)
+	if b := cov.Binary; b != nil {
+		fmt.Printf("二进制 %s:\n", b.Path)
+		fmt.Printf("   可读符号:   %d / %d", b.ReadableSymbols, b.Symbols)
+		if len(b.ReadableNames) > 0 {
+			names := b.ReadableNames
+			if len(names) > 10 {
+				names = append(names[:10:10], "...")
+			}
+			fmt.Printf("（%s）", strings.Join(names, ", "))
+		}
+		fmt.Println()
+		fmt.Printf("   明文字面量: %d / %d\n", b.ReadableLiterals, b.Literals)
+	}
+}
+
+// runScan 执行 scan 子命令，返回退出状态：0 无泄露（或只有 -allow 允许的类别），1 存在泄露，2 参数或读取错误
+func runScan(args []string) int {
+	fs := flag.NewFlagSet("scan", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "以 JSON 输出报告")
+	allow := fs.String("allow", "", "允许存在的泄露类别，不影响退出状态 (逗号分隔: identifier,package,file,string,comment)")
+	ignore := fs.String("ignore", "", "不报告的值 (逗号分隔)，例如有意保留的导出 API 名称")
+	minLength := fs.Int("min-length", 0, "参与匹配的最小字节数 (默认: 名称 4，字符串 6，注释 12)")
+	fs.Usage = func() {
+		fmt.Println("用法: cross-file-obfuscator scan [选项] <二进制> <源码目录>")
+		fmt.Println()
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	allowed := make(map[string]bool)
+	kinds := map[string]bool{
+		obfuscator.LeakIdentifier: true, obfuscator.LeakPackage: true, obfuscator.LeakFile: true,
+		obfuscator.LeakString: true, obfuscator.LeakComment: true,
+	}
+	for _, kind := range splitList(*allow) {
+		if !kinds[kind] {
+			fmt.Fprintf(os.Stderr, "错误: 未知的泄露类别 %q\n", kind)
+			return 2
+		}
+		allowed[kind] = true
+	}
+
+	report, err := obfuscator.ScanLeaks(fs.Arg(0), fs.Arg(1), &obfuscator.LeakOptions{
+		MinLength: *minLength,
+		Ignore:    splitList(*ignore),
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "错误: %v\n", err)
+		return 2
+	}
+
+	if *jsonOutput {
+		if err := report.WriteJSON(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "错误: %v\n", err)
+			return 2
+		}
+	} else {
+		printLeaks(report)
+	}
+
+	failed := 0
+	for kind, n := range report.Kinds() {
+		if !allowed[kind] {
+			failed += n
+		}
+	}
+	if failed > 0 {
+		if !*jsonOutput {
+			fmt.Printf("\n❌ 发现 %d 处源码泄露\n", failed)
+		}
+		return 1
+	}
+	if !*jsonOutput {
+		fmt.Println("\n✅ 未发现源码泄露")
+	}
+	return 0
+}
+
+// splitList 拆分逗号分隔的列表，忽略空项
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func printLeaks(report *obfuscator.LeakReport) {
+	fmt.Println("========================================")
+	fmt.Println("   源码泄露扫描")
+	fmt.Println("========================================")
+	fmt.Printf("二进制:   %s\n", report.Binary)
+	fmt.Printf("源码目录: %s\n", report.Source)
+	for _, region := range report.Regions {
+		fmt.Printf("\n[%s] %d 字节，%d 处泄露\n", region.Name, region.Size, len(region.Leaks))
+		for _, leak := range region.Leaks {
+			value := strconv.Quote(leak.Value)
+			if len(value) > 80 {
+				value = value[:77] + "..."
+			}
+			fmt.Printf("   %-10s %s", leak.Kind, value)
+			if leak.Origin != "" {
+				fmt.Printf("  (%s)", leak.Origin)
+			}
+			if leak.Count > 1 {
+				fmt.Printf(" ×%d", leak.Count)
+			}
+			fmt.Println()
+		}
+	}
+	if kinds := report.Kinds(); len(kinds) > 0 {
+		var parts []string
+		for _, kind := range []string{obfuscator.LeakIdentifier, obfuscator.LeakPackage, obfuscator.LeakFile, obfuscator.LeakString, obfuscator.LeakComment} {
+			if n := kinds[kind]; n > 0 {
+				parts = append(parts, fmt.Sprintf("%s %d", kind, n))
+			}
+		}
+		fmt.Printf("\n合计: %s\n", strings.Join(parts, "，"))
+	}
+}
+
+func printTagReport(tags []obfuscator.SynthesizedTag) {
+	if len(tags) == 0 {
+		return
+	}
+	fmt.Println()
+	fmt.Println("========================================")
+	fmt.Println("   结构体标签合成报告")
+	fmt.Println("========================================")
+	for _, t := range tags {
+		fmt.Printf("%s.%s -> %s (%s)\n", t.Type, t.Field, t.NewName, t.Position)
+		fmt.Printf("   新增标签: %s\n", strings.Join(t.Added, " "))
+	}
+}
+
+func printExplanation(name string, reasons []obfuscator.ProtectionReason, objects, locals int) {
+	fmt.Println()
+	fmt.Println("========================================")
+	fmt.Printf("   名称 %q 的保护原因\n", name)
+	fmt.Println("========================================")
+	if len(reasons) == 0 {
+		fmt.Println("没有任何保护原因，该名称会被混淆")
+		return
+	}
+	for _, r := range reasons {
+		fmt.Printf("  - %s\n", r)
+	}
+	if objects > 0 {
+		fmt.Printf("ℹ️  以上原因属于其他对象，不影响包级声明：%d 个同名包级函数、变量或常量按声明对象单独改名\n", objects)
+	}
+	if locals > 0 {
+		fmt.Printf("ℹ️  以上原因不影响局部变量：%d 个同名局部变量按声明对象单独改名\n", locals)
+	}
 }
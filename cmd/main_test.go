@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runMainEnv 非空时测试二进制直接执行 main，供测试以子进程方式运行命令行
+const runMainEnv = "CFO_TEST_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) != "" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runCLI 以子进程运行命令行，返回合并的输出和退出码
+func runCLI(t *testing.T, args ...string) (string, int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), runMainEnv+"=1")
+	output, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return string(output), 0
+	case errors.As(err, &exitErr):
+		return string(output), exitErr.ExitCode()
+	}
+	t.Fatalf("无法运行命令行: %v", err)
+	return "", -1
+}
+
+// copyProject 把 obfuscator/testdata 下的模块复制到临时目录
+func copyProject(t *testing.T, name string) string {
+	t.Helper()
+	src := filepath.Join("..", "obfuscator", "testdata", name)
+	dst := filepath.Join(t.TempDir(), name)
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(filepath.Join(dst, rel), 0755)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dst, rel), data, 0644)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dst
+}
+
+// TestThresholdsExitCode 未达到 -thresholds 时以非零状态退出，阈值格式错误时报错
+func TestThresholdsExitCode(t *testing.T) {
+	project := copyProject(t, "app")
+	tests := []struct {
+		name      string
+		threshold string
+		code      int
+		output    string
+	}{
+		{"met", "idents=0,strings=0", 0, "覆盖率达到全部阈值"},
+		{"breached", "strings=100", 1, "字符串加密比例"},
+		{"invalid", "idents", 1, "阈值格式错误"},
+		{"binary without scan", "symbols=0", 1, "需要扫描二进制"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "out")
+			output, code := runCLI(t, "-force", "-seed", "test-seed", "-thresholds", tc.threshold, "-o", out, project)
+			if code != tc.code || !strings.Contains(output, tc.output) {
+				t.Errorf("退出码 %d（期望 %d），输出中应包含 %q:\n%s", code, tc.code, tc.output, output)
+			}
+		})
+	}
+}
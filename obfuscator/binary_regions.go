@@ -0,0 +1,394 @@
+package obfuscator
+
+import (
+	"bytes"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// byteRange 表示二进制文件中的一段字节区间 [Start, End)（文件偏移）
+type byteRange struct {
+	Name  string
+	Start int
+	End   int
+}
+
+// sectionInfo 描述一个有文件内容的段
+type sectionInfo struct {
+	Name   string
+	Addr   uint64 // 虚拟地址
+	Offset int    // 文件偏移
+	Size   int    // 文件中的大小
+}
+
+// binaryLayout 通过解析节表得到的 Go 二进制布局
+type binaryLayout struct {
+	format    string
+	byteOrder binary.ByteOrder
+	ptrSize   int
+	sections  []sectionInfo
+
+	pclntabOffset int // pcHeader 的文件偏移，-1 表示未找到
+}
+
+// buildInfoMagic 是 .go.buildinfo 段的起始标记
+var buildInfoMagic = []byte("\xff Go buildinf:")
+
+// modinfo 字符串前后的哨兵字节（见 cmd/go/internal/modload），
+// runtime/debug.ReadBuildInfo 读取的 rodata 副本同样带有这些哨兵
+var (
+	modInfoStart = []byte("\x30\x77\xaf\x0c\x92\x74\x08\x02\x41\xe1\xc1\x07\xe6\xd6\x18\xe6")
+	modInfoEnd   = []byte("\xf9\x32\x43\x31\x86\x18\x20\x72\x00\x82\x42\x10\x41\x16\xd8\xf2")
+)
+
+// parseBinaryLayout 解析 ELF / PE / Mach-O 的节表
+func parseBinaryLayout(data []byte) (*binaryLayout, error) {
+	layout := &binaryLayout{
+		format:        detectBinaryFormat(data),
+		pclntabOffset: -1,
+	}
+
+	switch layout.format {
+	case "ELF":
+		f, err := elf.NewFile(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("解析 ELF 失败: %v", err)
+		}
+		defer f.Close()
+		layout.byteOrder = f.ByteOrder
+		for _, s := range f.Sections {
+			if s.Type == elf.SHT_NOBITS || s.Size == 0 {
+				continue
+			}
+			layout.sections = append(layout.sections, sectionInfo{
+				Name: s.Name, Addr: s.Addr, Offset: int(s.Offset), Size: int(s.Size),
+			})
+		}
+	case "PE":
+		f, err := pe.NewFile(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("解析 PE 失败: %v", err)
+		}
+		defer f.Close()
+		layout.byteOrder = binary.LittleEndian
+		var imageBase uint64
+		switch oh := f.OptionalHeader.(type) {
+		case *pe.OptionalHeader32:
+			imageBase = uint64(oh.ImageBase)
+		case *pe.OptionalHeader64:
+			imageBase = oh.ImageBase
+		}
+		for _, s := range f.Sections {
+			size := s.Size
+			if s.VirtualSize != 0 && s.VirtualSize < size {
+				size = s.VirtualSize
+			}
+			if size == 0 || s.Offset == 0 {
+				continue
+			}
+			layout.sections = append(layout.sections, sectionInfo{
+				Name: s.Name, Addr: imageBase + uint64(s.VirtualAddress), Offset: int(s.Offset), Size: int(size),
+			})
+		}
+	case "Mach-O":
+		f, err := macho.NewFile(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("解析 Mach-O 失败: %v", err)
+		}
+		defer f.Close()
+		layout.byteOrder = f.ByteOrder
+		for _, s := range f.Sections {
+			if s.Offset == 0 || s.Size == 0 {
+				continue
+			}
+			layout.sections = append(layout.sections, sectionInfo{
+				Name: s.Name, Addr: s.Addr, Offset: int(s.Offset), Size: int(s.Size),
+			})
+		}
+	default:
+		return nil, fmt.Errorf("不支持的二进制格式: %s", layout.format)
+	}
+
+	// 过滤掉超出文件范围的段
+	valid := layout.sections[:0]
+	for _, s := range layout.sections {
+		if s.Offset >= 0 && s.Offset+s.Size <= len(data) {
+			valid = append(valid, s)
+		}
+	}
+	layout.sections = valid
+
+	layout.pclntabOffset = layout.locatePclntab(data)
+	if layout.pclntabOffset >= 0 {
+		layout.ptrSize = int(data[layout.pclntabOffset+7])
+	}
+	return layout, nil
+}
+
+// locatePclntab 定位 pcHeader：优先使用专用段，否则在段内搜索并校验头部
+func (bl *binaryLayout) locatePclntab(data []byte) int {
+	for _, s := range bl.sections {
+		if s.Name == ".gopclntab" || s.Name == "__gopclntab" {
+			if isValidPcHeader(data, s.Offset) {
+				return s.Offset
+			}
+		}
+	}
+	for _, s := range bl.sections {
+		sectionData := data[s.Offset : s.Offset+s.Size]
+		for i := 0; i+8 <= len(sectionData); i += 4 {
+			if isValidPcHeader(data, s.Offset+i) {
+				return s.Offset + i
+			}
+		}
+	}
+	return -1
+}
+
+// isValidPcHeader 校验 magic 之后的固定字段：pad 为 0，minLC ∈ {1,2,4}，ptrSize ∈ {4,8}
+func isValidPcHeader(data []byte, off int) bool {
+	if off < 0 || off+8 > len(data) {
+		return false
+	}
+	magic := binary.LittleEndian.Uint32(data[off:])
+	if magic != go116magic && magic != go118magic && magic != go120magic {
+		magic = binary.BigEndian.Uint32(data[off:])
+		if magic != go116magic && magic != go118magic && magic != go120magic {
+			return false
+		}
+	}
+	if data[off+4] != 0 || data[off+5] != 0 {
+		return false
+	}
+	minLC := data[off+6]
+	ptrSize := data[off+7]
+	return (minLC == 1 || minLC == 2 || minLC == 4) && (ptrSize == 4 || ptrSize == 8)
+}
+
+// readWord 按指针宽度读取一个字
+func (bl *binaryLayout) readWord(data []byte, off int) (uint64, bool) {
+	if off < 0 || off+bl.ptrSize > len(data) {
+		return 0, false
+	}
+	if bl.ptrSize == 8 {
+		return bl.byteOrder.Uint64(data[off:]), true
+	}
+	return uint64(bl.byteOrder.Uint32(data[off:])), true
+}
+
+// offsetToAddr 将文件偏移转换为虚拟地址
+func (bl *binaryLayout) offsetToAddr(off int) (uint64, bool) {
+	for _, s := range bl.sections {
+		if off >= s.Offset && off < s.Offset+s.Size {
+			return s.Addr + uint64(off-s.Offset), true
+		}
+	}
+	return 0, false
+}
+
+// addrToOffset 将虚拟地址转换为文件偏移
+func (bl *binaryLayout) addrToOffset(addr uint64) (int, bool) {
+	for _, s := range bl.sections {
+		if addr >= s.Addr && addr < s.Addr+uint64(s.Size) {
+			return s.Offset + int(addr-s.Addr), true
+		}
+	}
+	return 0, false
+}
+
+// pclntabRegions 根据 pcHeader 计算 funcnametab（函数名）和 filetab（文件路径）区间
+func (bl *binaryLayout) pclntabRegions(data []byte) []byteRange {
+	if bl.pclntabOffset < 0 {
+		return nil
+	}
+	base := bl.pclntabOffset
+	magic := bl.byteOrder.Uint32(data[base:])
+
+	// Go 1.16/1.17 没有 textStart 字段，之后的版本在 nfiles 之后多一个字
+	firstOffsetField := base + 8 + 2*bl.ptrSize
+	if magic == go118magic || magic == go120magic {
+		firstOffsetField += bl.ptrSize
+	}
+
+	var fields [5]uint64 // funcnameOffset, cuOffset, filetabOffset, pctabOffset, pclnOffset
+	for i := range fields {
+		v, ok := bl.readWord(data, firstOffsetField+i*bl.ptrSize)
+		if !ok {
+			return nil
+		}
+		fields[i] = v
+	}
+
+	var regions []byteRange
+	add := func(name string, start, end uint64) {
+		s, e := base+int(start), base+int(end)
+		if start < end && s >= 0 && e <= len(data) {
+			regions = append(regions, byteRange{Name: name, Start: s, End: e})
+		}
+	}
+	add("pclntab.funcnametab", fields[0], fields[1])
+	add("pclntab.filetab", fields[2], fields[3])
+	return regions
+}
+
+// typesRegion 通过 moduledata 找到类型描述区 [types, etypes)
+// 类型名、包路径（pkgPath）以及接口/itab 使用的方法名都存放在此区间
+func (bl *binaryLayout) typesRegion(data []byte) (byteRange, bool) {
+	if bl.pclntabOffset < 0 {
+		return byteRange{}, false
+	}
+	pclntabAddr, ok := bl.offsetToAddr(bl.pclntabOffset)
+	if !ok {
+		return byteRange{}, false
+	}
+	pclnRegions := bl.pclntabRegions(data)
+	if len(pclnRegions) == 0 {
+		return byteRange{}, false
+	}
+	funcnametabAddr, ok := bl.offsetToAddr(pclnRegions[0].Start)
+	if !ok {
+		return byteRange{}, false
+	}
+
+	// moduledata 的第一个字段是 pcHeader 指针，第二个是 funcnametab 切片的数据指针
+	for _, s := range bl.sections {
+		for off := s.Offset; off+2*bl.ptrSize <= s.Offset+s.Size; off += bl.ptrSize {
+			if v, _ := bl.readWord(data, off); v != pclntabAddr {
+				continue
+			}
+			if v, _ := bl.readWord(data, off+bl.ptrSize); v != funcnametabAddr {
+				continue
+			}
+			if r, ok := bl.typesFromModuledata(data, off); ok {
+				return r, true
+			}
+		}
+	}
+	return byteRange{}, false
+}
+
+// typesFromModuledata 从 moduledata 中读取 types/etypes
+// 不同 Go 版本的布局不同：1.18/1.19 没有 covctrs，新版本在 types 之后插入了 typedesclen，
+// 因此按 pclntab magic 选择候选下标，并要求区间落在同一个段内
+func (bl *binaryLayout) typesFromModuledata(data []byte, moduledataOffset int) (byteRange, bool) {
+	candidates := []struct{ types, etypes int }{
+		{37, 38}, // Go 1.20 - 1.25
+		{37, 39}, // 带 typedesclen 字段的新版本
+	}
+	switch bl.byteOrder.Uint32(data[bl.pclntabOffset:]) {
+	case go118magic:
+		candidates = []struct{ types, etypes int }{{35, 36}} // Go 1.18 / 1.19
+	case go116magic, go12magic:
+		return byteRange{}, false
+	}
+	for _, c := range candidates {
+		types, ok1 := bl.readWord(data, moduledataOffset+c.types*bl.ptrSize)
+		etypes, ok2 := bl.readWord(data, moduledataOffset+c.etypes*bl.ptrSize)
+		if !ok1 || !ok2 || types == 0 || etypes <= types {
+			continue
+		}
+		start, ok1 := bl.addrToOffset(types)
+		end, ok2 := bl.addrToOffset(etypes - 1)
+		if !ok1 || !ok2 || bl.sectionIndexAt(start) != bl.sectionIndexAt(end) {
+			continue
+		}
+		return byteRange{Name: "types", Start: start, End: end + 1}, true
+	}
+	return byteRange{}, false
+}
+
+// sectionIndexAt 返回包含文件偏移的段下标
+func (bl *binaryLayout) sectionIndexAt(off int) int {
+	for i, s := range bl.sections {
+		if off >= s.Offset && off < s.Offset+s.Size {
+			return i
+		}
+	}
+	return -1
+}
+
+// buildInfoRegion 定位 buildinfo 头部以及紧随其后的版本字符串与模块信息
+func (bl *binaryLayout) buildInfoRegion(data []byte) (byteRange, bool) {
+	start := bytes.Index(data, buildInfoMagic)
+	if start < 0 || start+32 > len(data) {
+		return byteRange{}, false
+	}
+	flags := data[start+len(buildInfoMagic)+1]
+	if flags&0x2 == 0 {
+		// Go 1.18 之前的格式使用指针引用字符串，无法安全地就地修改
+		return byteRange{}, false
+	}
+	pos := start + 32
+	for i := 0; i < 2; i++ {
+		n, w := binary.Uvarint(data[pos:])
+		if w <= 0 || pos+w+int(n) > len(data) {
+			return byteRange{}, false
+		}
+		pos += w + int(n)
+	}
+	return byteRange{Name: "buildinfo", Start: start, End: pos}, true
+}
+
+// modInfoRegions 定位所有由哨兵字节包围的模块信息字符串
+func modInfoRegions(data []byte) []byteRange {
+	var regions []byteRange
+	for start := 0; start < len(data); {
+		idx := bytes.Index(data[start:], modInfoStart)
+		if idx < 0 {
+			break
+		}
+		begin := start + idx
+		limit := begin + 64*1024
+		if limit > len(data) {
+			limit = len(data)
+		}
+		end := bytes.Index(data[begin:limit], modInfoEnd)
+		if end < 0 {
+			start = begin + len(modInfoStart)
+			continue
+		}
+		regions = append(regions, byteRange{Name: "modinfo", Start: begin, End: begin + end + len(modInfoEnd)})
+		start = begin + end + len(modInfoEnd)
+	}
+	return regions
+}
+
+// stringRegions 返回所有已知的、承载包路径字符串的区间
+func (bl *binaryLayout) stringRegions(data []byte) []byteRange {
+	regions := bl.pclntabRegions(data)
+	if r, ok := bl.typesRegion(data); ok {
+		regions = append(regions, r)
+	}
+	if r, ok := bl.buildInfoRegion(data); ok {
+		regions = append(regions, r)
+	}
+	regions = append(regions, modInfoRegions(data)...)
+	sort.Slice(regions, func(i, j int) bool { return regions[i].Start < regions[j].Start })
+	return regions
+}
+
+// subtractRanges 从区间列表中剔除排除区间（例如 //go:embed 的数据）
+func subtractRanges(regions, excluded []byteRange) []byteRange {
+	result := regions
+	for _, ex := range excluded {
+		var next []byteRange
+		for _, r := range result {
+			if ex.End <= r.Start || ex.Start >= r.End {
+				next = append(next, r)
+				continue
+			}
+			if ex.Start > r.Start {
+				next = append(next, byteRange{Name: r.Name, Start: r.Start, End: ex.Start})
+			}
+			if ex.End < r.End {
+				next = append(next, byteRange{Name: r.Name, Start: ex.End, End: r.End})
+			}
+		}
+		result = next
+	}
+	return result
+}
@@ -0,0 +1,99 @@
+package obfuscator
+
+import (
+	"go/ast"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// isCgoFile 判断文件是否导入了伪包 "C"
+func isCgoFile(node *ast.File) bool {
+	for _, imp := range node.Imports {
+		if imp.Path != nil && imp.Path.Value == `"C"` {
+			return true
+		}
+	}
+	return false
+}
+
+// protectCgoNames 记录 cgo 文件，并保护 //export 导出给 C 的函数名。
+// C.xxx 选择器由 collectProtectedNames 按非项目包选择器保护
+func (o *Obfuscator) protectCgoNames(node *ast.File, path string) {
+	if !isCgoFile(node) {
+		return
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		o.cgoFiles[abs] = true
+	}
+	for _, group := range node.Comments {
+		for _, c := range group.List {
+			if strings.HasPrefix(c.Text, "//export ") {
+				name := strings.TrimSpace(strings.TrimPrefix(c.Text, "//export "))
+				o.protect(name, reasonCgoExport, c.Text, c.Pos())
+			}
+		}
+	}
+}
+
+// cgoPreambleGroups 返回 import "C" 之前的序言注释，序言是 C 代码，移除注释时必须原样保留
+func cgoPreambleGroups(node *ast.File) map[*ast.CommentGroup]bool {
+	groups := make(map[*ast.CommentGroup]bool)
+	for _, decl := range node.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			imp, ok := spec.(*ast.ImportSpec)
+			if !ok || imp.Path == nil || imp.Path.Value != `"C"` {
+				continue
+			}
+			// 与 cgo 相同的规则：优先取导入项的文档注释，单独的 import "C" 取声明的文档注释
+			if imp.Doc != nil {
+				groups[imp.Doc] = true
+			} else if gen.Doc != nil && len(gen.Specs) == 1 {
+				groups[gen.Doc] = true
+			}
+		}
+	}
+	return groups
+}
+
+// cgoSource 原始 cgo 文件的内容和每行起始偏移
+type cgoSource struct {
+	src   []byte
+	lines []int
+}
+
+// cgoSourceOffset 把 cgo 处理后文件中的位置（经 //line 映射得到的原文件行列）换算为原文件的字节偏移，
+// 并确认该位置确实是同名标识符；无法确认时返回 false
+func (o *Obfuscator) cgoSourceOffset(file string, line, column int, name string) (int, bool) {
+	cs, ok := o.cgoSources[file]
+	if !ok {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return 0, false
+		}
+		cs = &cgoSource{src: data, lines: []int{0}}
+		for i, b := range data {
+			if b == '\n' {
+				cs.lines = append(cs.lines, i+1)
+			}
+		}
+		o.cgoSources[file] = cs
+	}
+	if line < 1 || line > len(cs.lines) || column < 1 {
+		return 0, false
+	}
+
+	offset := cs.lines[line-1] + column - 1
+	end := offset + len(name)
+	if end > len(cs.src) || string(cs.src[offset:end]) != name {
+		return 0, false
+	}
+	if end < len(cs.src) && isIdentByte(cs.src[end]) {
+		return 0, false
+	}
+	return offset, true
+}
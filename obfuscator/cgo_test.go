@@ -0,0 +1,63 @@
+package obfuscator_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cross-file-obfuscator/obfuscator"
+)
+
+// TestObfuscateCgoModule 混淆 cgo 模块（含 //export）后用本机 C 编译器编译，运行结果与原项目一致
+func TestObfuscateCgoModule(t *testing.T) {
+	skipIfShort(t)
+	if out, err := exec.Command("go", "env", "CGO_ENABLED").Output(); err != nil || strings.TrimSpace(string(out)) != "1" {
+		t.Skip("cgo 未启用")
+	}
+	cc, err := exec.Command("go", "env", "CC").Output()
+	if err != nil || len(strings.Fields(string(cc))) == 0 {
+		t.Skip("无法确定 C 编译器")
+	}
+	if _, err := exec.LookPath(strings.Fields(string(cc))[0]); err != nil {
+		t.Skip("没有可用的 C 编译器")
+	}
+
+	project := copyTestdata(t, "cgoapp")
+	want := runGo(t, project, "run", ".")
+
+	config := obfuscator.DefaultConfig()
+	config.EncryptStrings = true
+	config.EncryptCgoStrings = true
+	_, result := obfuscate(t, project, config)
+
+	if len(result.SkippedFiles) != 0 {
+		t.Fatalf("cgo 文件不应被跳过: %v", result.SkippedFiles)
+	}
+	src, err := os.ReadFile(filepath.Join(result.OutputDir, "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"addInts", "secretLabel", `"cgo-sum"`} {
+		if strings.Contains(string(src), name) {
+			t.Errorf("输出中仍包含 %s", name)
+		}
+	}
+	for _, kept := range []string{"static int add(int a, int b)", "C.add(", "C.int("} {
+		if !strings.Contains(string(src), kept) {
+			t.Errorf("cgo 前导注释或 C 选择器被修改，缺少 %s", kept)
+		}
+	}
+	export, err := os.ReadFile(filepath.Join(result.OutputDir, "export.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(export), "//export goDouble\nfunc goDouble(") {
+		t.Errorf("//export 函数应保持原名:\n%s", export)
+	}
+
+	if got := runGo(t, result.OutputDir, "run", "."); got != want {
+		t.Errorf("混淆后输出 %q，期望 %q", got, want)
+	}
+}
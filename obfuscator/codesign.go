@@ -0,0 +1,170 @@
+package obfuscator
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"debug/macho"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+)
+
+// Mach-O 代码签名的常量（见 cmd/internal/codesign 和 xnu 的 cs_blobs.h）。
+// darwin/arm64 的链接器为二进制写入 ad-hoc 签名，内核按页校验 CodeDirectory 中的哈希，
+// 后处理修改任何字节后都必须重新计算，否则 macOS 在启动时直接终止进程
+const (
+	lcCodeSignature          = 0x1d
+	csMagicEmbeddedSignature = 0xfade0cc0
+	csMagicCodeDirectory     = 0xfade0c02
+	csSlotCodeDirectory      = 0
+	csSlotSignature          = 0x10000 // CMS 证书签名
+	csAdhoc                  = 0x2
+	csHashSHA1               = 1
+	csHashSHA256             = 2
+	csHashSHA256Truncated    = 3
+)
+
+// errNoCodeSignature Mach-O 二进制没有代码签名
+var errNoCodeSignature = errors.New("没有代码签名")
+
+// machoCodeDirectory 签名中可以就地更新的 CodeDirectory：各代码页哈希的位置和算法
+type machoCodeDirectory struct {
+	hashes    int // 第一个代码页哈希的文件偏移
+	hashSize  int
+	hashType  int
+	pageSize  int // 0 表示整个签名范围为一页
+	codeSlots int
+	codeLimit int
+}
+
+// parseMachOCodeDirectory 定位 LC_CODE_SIGNATURE 中的 CodeDirectory。
+// 只支持链接器生成的 ad-hoc 签名：带证书的签名还覆盖 CodeDirectory 本身，修改后只能由原签名者重新签名
+func parseMachOCodeDirectory(data []byte) (*machoCodeDirectory, error) {
+	f, err := macho.NewFile(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("解析 Mach-O 失败: %v", err)
+	}
+	defer f.Close()
+
+	start, end := -1, -1
+	for _, load := range f.Loads {
+		raw := load.Raw()
+		if len(raw) >= 16 && f.ByteOrder.Uint32(raw) == lcCodeSignature {
+			start = int(f.ByteOrder.Uint32(raw[8:]))
+			end = start + int(f.ByteOrder.Uint32(raw[12:]))
+			break
+		}
+	}
+	if start < 0 {
+		return nil, errNoCodeSignature
+	}
+	if end > len(data) || end-start < 12 {
+		return nil, fmt.Errorf("代码签名越出文件范围")
+	}
+
+	// 签名数据一律为大端序
+	sig := data[start:end]
+	if binary.BigEndian.Uint32(sig) != csMagicEmbeddedSignature {
+		return nil, fmt.Errorf("未知的代码签名格式 0x%08x", binary.BigEndian.Uint32(sig))
+	}
+	count := int(binary.BigEndian.Uint32(sig[8:]))
+	if 12+8*count > len(sig) {
+		return nil, fmt.Errorf("代码签名的索引越界")
+	}
+	cdOffset := -1
+	for i := 0; i < count; i++ {
+		slot := binary.BigEndian.Uint32(sig[12+8*i:])
+		offset := int(binary.BigEndian.Uint32(sig[16+8*i:]))
+		switch slot {
+		case csSlotCodeDirectory:
+			cdOffset = offset
+		case csSlotSignature:
+			// ad-hoc 签名可能带有空的 CMS 包装（只有 8 字节的头部）
+			if offset+8 <= len(sig) && binary.BigEndian.Uint32(sig[offset+4:]) > 8 {
+				return nil, fmt.Errorf("二进制带有证书签名，修改后需要重新签名")
+			}
+		}
+	}
+	if cdOffset < 0 || cdOffset+44 > len(sig) {
+		return nil, fmt.Errorf("代码签名中没有 CodeDirectory")
+	}
+
+	cd := sig[cdOffset:]
+	if binary.BigEndian.Uint32(cd) != csMagicCodeDirectory {
+		return nil, fmt.Errorf("CodeDirectory 格式错误")
+	}
+	if binary.BigEndian.Uint32(cd[12:])&csAdhoc == 0 {
+		return nil, fmt.Errorf("不是 ad-hoc 签名，修改后需要重新签名")
+	}
+	dir := &machoCodeDirectory{
+		hashes:    start + cdOffset + int(binary.BigEndian.Uint32(cd[16:])),
+		codeSlots: int(binary.BigEndian.Uint32(cd[28:])),
+		codeLimit: int(binary.BigEndian.Uint32(cd[32:])),
+		hashSize:  int(cd[36]),
+		hashType:  int(cd[37]),
+	}
+	if shift := cd[39]; shift > 0 {
+		dir.pageSize = 1 << shift
+	}
+	if dir.newHash() == nil {
+		return nil, fmt.Errorf("不支持的签名哈希算法 %d", dir.hashType)
+	}
+	if dir.codeLimit > start || dir.hashes+dir.codeSlots*dir.hashSize > end {
+		return nil, fmt.Errorf("CodeDirectory 的范围无效")
+	}
+	return dir, nil
+}
+
+func (d *machoCodeDirectory) newHash() hash.Hash {
+	switch d.hashType {
+	case csHashSHA1:
+		return sha1.New()
+	case csHashSHA256, csHashSHA256Truncated:
+		return sha256.New()
+	}
+	return nil
+}
+
+// pageHash 返回第 i 个代码页的哈希（截断到 hashSize）
+func (d *machoCodeDirectory) pageHash(data []byte, i int) []byte {
+	start, end := 0, d.codeLimit
+	if d.pageSize > 0 {
+		start = i * d.pageSize
+		if start+d.pageSize < end {
+			end = start + d.pageSize
+		}
+	}
+	h := d.newHash()
+	h.Write(data[start:end])
+	return h.Sum(nil)[:d.hashSize]
+}
+
+// resignMachO 按修改后的内容重新计算 ad-hoc 签名的代码页哈希。签名大小和覆盖范围不变，就地写入。
+// 没有签名时返回 errNoCodeSignature
+func resignMachO(data []byte) error {
+	dir, err := parseMachOCodeDirectory(data)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < dir.codeSlots; i++ {
+		copy(data[dir.hashes+i*dir.hashSize:], dir.pageHash(data, i))
+	}
+	return nil
+}
+
+// verifyMachOSignature 检查 ad-hoc 签名的代码页哈希与文件内容一致。没有签名时返回 errNoCodeSignature
+func verifyMachOSignature(data []byte) error {
+	dir, err := parseMachOCodeDirectory(data)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < dir.codeSlots; i++ {
+		at := dir.hashes + i*dir.hashSize
+		if !bytes.Equal(data[at:at+dir.hashSize], dir.pageHash(data, i)) {
+			return fmt.Errorf("第 %d 页的签名哈希与内容不符", i)
+		}
+	}
+	return nil
+}
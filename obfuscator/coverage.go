@@ -0,0 +1,406 @@
+package obfuscator
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 覆盖率统计的标识符类别，按输出顺序排列
+var coverageKinds = []string{"func", "method", "type", "var", "const", "field", "local"}
+
+// Coverage 混淆强度：各类标识符的改名比例、字符串字面量的加密比例，以及扫描二进制得到的可读名称
+type Coverage struct {
+	Identifiers    []KindCoverage  `json:"identifiers"`      // 按类别统计的声明（项目源码，不含依赖和跳过的文件）
+	IdentsTotal    int             `json:"idents_total"`     // 声明总数
+	IdentsRenamed  int             `json:"idents_renamed"`   // 改名的声明数
+	IdentsPercent  float64         `json:"idents_percent"`   // 改名的声明占比（%）
+	StringBytes    int             `json:"string_bytes"`     // 字符串字面量的总字节数（不含导入路径和结构体标签）
+	EncryptedBytes int             `json:"encrypted_bytes"`  // 被加密的字面量字节数
+	StringsPercent float64         `json:"strings_percent"`  // 被加密的字节占比（%）
+	Binary         *BinaryCoverage `json:"binary,omitempty"` // ScanBinary 的结果
+}
+
+// KindCoverage 一类标识符的改名情况
+type KindCoverage struct {
+	Kind    string  `json:"kind"`
+	Total   int     `json:"total"`
+	Renamed int     `json:"renamed"`
+	Percent float64 `json:"percent"`
+}
+
+// BinaryCoverage 最终二进制中仍然可读的名称
+type BinaryCoverage struct {
+	Path             string   `json:"path"`
+	Symbols          int      `json:"symbols"`           // pclntab 中不属于标准库的函数符号
+	ReadableSymbols  int      `json:"readable_symbols"`  // 其中包含源码中声明的名称的符号
+	ReadableNames    []string `json:"readable_names"`    // 这些符号中出现的源码名称
+	Literals         int      `json:"literals"`          // 项目中的字符串字面量（去重，至少 4 字节）
+	ReadableLiterals int      `json:"readable_literals"` // 在只读数据段中以明文出现的字面量
+}
+
+// percent 计算百分比，保留一位小数；total 为 0 时视为 100%
+func percent(part, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return float64(int(float64(part)*1000/float64(total))) / 10
+}
+
+// countedForCoverage 判断文件是否计入覆盖率：项目自身被混淆的源码
+func (o *Obfuscator) countedForCoverage(path string) bool {
+	if _, skipped := o.skippedFiles[path]; skipped {
+		return false
+	}
+	return !o.isVendoredPath(path) && !o.isIgnoredVendorPath(path)
+}
+
+// measureCoverage 在分析阶段结束时统计各类声明及其是否改名，以及字符串字面量的字节数
+func (o *Obfuscator) measureCoverage() {
+	renamed := make(map[identPos]bool)
+	for obj, to := range o.objectMapping {
+		if to == "" || to == obj.Name {
+			continue
+		}
+		p := o.fset.Position(obj.Pos)
+		if abs, err := filepath.Abs(p.Filename); err == nil {
+			renamed[identPos{file: abs, offset: p.Offset}] = true
+		}
+	}
+	for _, d := range o.renamedDecls {
+		renamed[d.pos] = true
+	}
+
+	kinds := make(map[string]*KindCoverage)
+	seen := make(map[identPos]bool)
+	add := func(kind string, pos identPos) {
+		if seen[pos] {
+			return
+		}
+		seen[pos] = true
+		k, ok := kinds[kind]
+		if !ok {
+			k = &KindCoverage{Kind: kind}
+			kinds[kind] = k
+		}
+		k.Total++
+		if renamed[pos] {
+			k.Renamed++
+		}
+	}
+
+	o.declaredNames = make(map[string]bool)
+	o.projectLiterals = make(map[string]bool)
+	o.stringBytes = 0
+	counted := make(map[string]bool)
+	for _, file := range o.sourceFiles {
+		if !o.countedForCoverage(file.path) {
+			continue
+		}
+		counted[file.abs] = true
+		at := func(id *ast.Ident) identPos {
+			return identPos{file: file.abs, offset: o.fset.Position(id.Pos()).Offset}
+		}
+		declare := func(kind string, id *ast.Ident) {
+			if id.Name == "_" {
+				return
+			}
+			add(kind, at(id))
+			if kind != "field" && len(id.Name) >= 4 {
+				o.declaredNames[id.Name] = true
+			}
+		}
+
+		for _, decl := range file.node.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				switch {
+				case d.Recv != nil:
+					declare("method", d.Name)
+				case d.Name.Name == "init" || d.Name.Name == "main" && file.node.Name.Name == "main":
+					// 入口函数不能改名，不计入
+				default:
+					declare("func", d.Name)
+				}
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					switch s := spec.(type) {
+					case *ast.TypeSpec:
+						declare("type", s.Name)
+						ast.Inspect(s.Type, func(n ast.Node) bool {
+							if st, ok := n.(*ast.StructType); ok {
+								for _, f := range st.Fields.List {
+									for _, name := range f.Names {
+										declare("field", name)
+									}
+								}
+							}
+							return true
+						})
+					case *ast.ValueSpec:
+						kind := "var"
+						if d.Tok == token.CONST {
+							kind = "const"
+						}
+						for _, name := range s.Names {
+							declare(kind, name)
+						}
+					}
+				}
+			}
+		}
+
+		skip := make(map[*ast.BasicLit]bool)
+		for _, imp := range file.node.Imports {
+			skip[imp.Path] = true
+		}
+		ast.Inspect(file.node, func(n ast.Node) bool {
+			switch x := n.(type) {
+			case *ast.Field:
+				if x.Tag != nil {
+					skip[x.Tag] = true
+				}
+			case *ast.BasicLit:
+				if x.Kind != token.STRING || skip[x] {
+					return true
+				}
+				s, err := strconv.Unquote(x.Value)
+				if err != nil {
+					return true
+				}
+				o.stringBytes += len(s)
+				if len(s) >= 4 {
+					o.projectLiterals[s] = true
+				}
+			}
+			return true
+		})
+	}
+
+	// 局部变量、常量和类型参数来自类型检查结果，类型检查失败时不统计
+	for _, pkg := range o.typedPkgs {
+		for _, obj := range pkg.TypesInfo.Defs {
+			if obj == nil || !isLocalObject(obj) {
+				continue
+			}
+			p := o.typesFset.PositionFor(obj.Pos(), false)
+			abs, err := filepath.Abs(p.Filename)
+			if err != nil || !counted[abs] {
+				continue
+			}
+			add("local", identPos{file: abs, offset: p.Offset})
+		}
+	}
+
+	o.identCoverage = nil
+	for _, kind := range coverageKinds {
+		if k, ok := kinds[kind]; ok {
+			k.Percent = percent(k.Renamed, k.Total)
+			o.identCoverage = append(o.identCoverage, *k)
+		}
+	}
+}
+
+// coverage 汇总覆盖率，转换阶段之后加密字节数才完整
+func (o *Obfuscator) coverage() *Coverage {
+	c := &Coverage{
+		Identifiers: append([]KindCoverage{}, o.identCoverage...),
+		StringBytes: o.stringBytes,
+		Binary:      o.binaryCoverage,
+	}
+	for _, k := range c.Identifiers {
+		c.IdentsTotal += k.Total
+		c.IdentsRenamed += k.Renamed
+	}
+	c.IdentsPercent = percent(c.IdentsRenamed, c.IdentsTotal)
+	for _, pkg := range o.transformed {
+		for _, f := range pkg.Files {
+			if _, ok := o.sourceByPath[f.original]; ok && o.countedForCoverage(f.original) {
+				c.EncryptedBytes += f.encryptedBytes
+			}
+		}
+	}
+	c.StringsPercent = percent(c.EncryptedBytes, c.StringBytes)
+	return c
+}
+
+// ScanBinary 扫描编译得到的二进制（ELF、PE 或 Mach-O），统计 pclntab 中仍包含源码名称的函数符号，
+// 以及只读数据（不含类型描述和构建信息）中以明文出现的字符串字面量，需在 Run 之后调用。结果同时写入之后的 GetStatistics
+func (o *Obfuscator) ScanBinary(path string) (*BinaryCoverage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取二进制 %s 失败: %v", path, err)
+	}
+	regions, err := splitBinaryRegions(data)
+	if err != nil {
+		return nil, fmt.Errorf("读取二进制 %s 失败: %v", path, err)
+	}
+
+	std := stdTopLevel()
+	names := make(map[string]bool)
+	bc := &BinaryCoverage{Path: path}
+	for _, sym := range bytes.Split(regions.chunk(RegionPclntab, "pclntab.funcnametab"), []byte{0}) {
+		s := string(sym)
+		if !isProjectSymbol(s, std) {
+			continue
+		}
+		bc.Symbols++
+		readable := false
+		for _, part := range symbolNameRe.FindAllString(s[strings.LastIndex(s, "/")+1:], -1) {
+			if o.declaredNames[part] {
+				readable = true
+				names[part] = true
+			}
+		}
+		if readable {
+			bc.ReadableSymbols++
+		}
+	}
+	for name := range names {
+		bc.ReadableNames = append(bc.ReadableNames, name)
+	}
+	sort.Strings(bc.ReadableNames)
+
+	for lit := range o.projectLiterals {
+		bc.Literals++
+		if regions.contains(RegionRodata, []byte(lit)) {
+			bc.ReadableLiterals++
+		}
+	}
+	o.binaryCoverage = bc
+	return bc, nil
+}
+
+var (
+	// funcSymbolRe pclntab 中的函数符号：导入路径、点和函数名（可带接收者、闭包和泛型实例化后缀）
+	funcSymbolRe = regexp.MustCompile(`^[A-Za-z][\w.\-~]*(?:/[\w.\-~]+)*\.[\w()*\[\].,{}:;/\-~$·]+$`)
+	// symbolNameRe 符号中的标识符部分
+	symbolNameRe = regexp.MustCompile(`[\pL_][\pL\pN_]*`)
+)
+
+// isProjectSymbol 判断 pclntab 中的字符串是否为非标准库的函数符号。
+// 链接器混淆替换包名后无法按模块路径识别项目包，因此只排除标准库和编译器生成的符号
+func isProjectSymbol(sym string, std map[string]bool) bool {
+	if !funcSymbolRe.MatchString(sym) {
+		return false
+	}
+	if strings.HasPrefix(sym, "type:") || strings.HasPrefix(sym, "go:") || strings.HasPrefix(sym, "gclocals") {
+		return false
+	}
+	first := sym[:strings.IndexAny(sym, "/.")]
+	return !std[first]
+}
+
+// stdTopLevel 标准库的顶层目录（GOROOT/src 下的目录名）
+func stdTopLevel() map[string]bool {
+	std := make(map[string]bool)
+	entries, err := os.ReadDir(filepath.Join(runtime.GOROOT(), "src"))
+	if err != nil {
+		return std
+	}
+	for _, e := range entries {
+		if e.IsDir() && e.Name() != "cmd" {
+			std[e.Name()] = true
+		}
+	}
+	return std
+}
+
+// Thresholds 覆盖率阈值。键为 idents、strings 或标识符类别（func、local 等）时为最低百分比；
+// 键为 symbols、literals 时为二进制中可读符号、明文字面量的最大数量（需要先 ScanBinary）
+type Thresholds map[string]float64
+
+// ParseThresholds 解析 "idents=80,strings=90,symbols=0" 形式的阈值
+func ParseThresholds(spec string) (Thresholds, error) {
+	t := make(Thresholds)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("阈值格式错误: %q（应为 key=value）", item)
+		}
+		key = strings.TrimSpace(key)
+		if !validThresholdKey(key) {
+			return nil, fmt.Errorf("未知的阈值: %s（可用: idents, strings, symbols, literals, %s）", key, strings.Join(coverageKinds, ", "))
+		}
+		v, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "%"), 64)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("阈值 %s 的值无效: %q", key, value)
+		}
+		t[key] = v
+	}
+	return t, nil
+}
+
+func validThresholdKey(key string) bool {
+	switch key {
+	case "idents", "strings", "symbols", "literals":
+		return true
+	}
+	for _, kind := range coverageKinds {
+		if key == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedsBinary 判断阈值是否包含需要扫描二进制的项
+func (t Thresholds) NeedsBinary() bool {
+	_, symbols := t["symbols"]
+	_, literals := t["literals"]
+	return symbols || literals
+}
+
+// Check 返回未达到阈值的项，全部满足时返回空切片。未扫描二进制时 symbols、literals 视为未满足
+func (c *Coverage) Check(t Thresholds) []string {
+	var failed []string
+	keys := make([]string, 0, len(t))
+	for key := range t {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		limit := t[key]
+		switch key {
+		case "idents":
+			if c.IdentsPercent < limit {
+				failed = append(failed, fmt.Sprintf("标识符改名比例 %.1f%% 低于 %.1f%%", c.IdentsPercent, limit))
+			}
+		case "strings":
+			if c.StringsPercent < limit {
+				failed = append(failed, fmt.Sprintf("字符串加密比例 %.1f%% 低于 %.1f%%", c.StringsPercent, limit))
+			}
+		case "symbols", "literals":
+			if c.Binary == nil {
+				failed = append(failed, fmt.Sprintf("阈值 %s 需要扫描二进制，但未扫描", key))
+				continue
+			}
+			n, what := c.Binary.ReadableSymbols, "二进制中可读的符号"
+			if key == "literals" {
+				n, what = c.Binary.ReadableLiterals, "二进制中明文的字符串字面量"
+			}
+			if float64(n) > limit {
+				failed = append(failed, fmt.Sprintf("%s %d 个，超过 %.0f", what, n, limit))
+			}
+		default:
+			for _, k := range c.Identifiers {
+				if k.Kind == key && k.Percent < limit {
+					failed = append(failed, fmt.Sprintf("%s 改名比例 %.1f%% 低于 %.1f%%", key, k.Percent, limit))
+				}
+			}
+		}
+	}
+	return failed
+}
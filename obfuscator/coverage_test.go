@@ -0,0 +1,93 @@
+package obfuscator_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"cross-file-obfuscator/obfuscator"
+)
+
+// TestParseThresholds 阈值的格式、键名和取值校验
+func TestParseThresholds(t *testing.T) {
+	tests := []struct {
+		spec string
+		want obfuscator.Thresholds
+		err  string
+	}{
+		{spec: "", want: obfuscator.Thresholds{}},
+		{spec: "idents=80, strings=90%,symbols=0", want: obfuscator.Thresholds{"idents": 80, "strings": 90, "symbols": 0}},
+		{spec: "func=100,local=50.5,", want: obfuscator.Thresholds{"func": 100, "local": 50.5}},
+		{spec: "idents", err: "格式错误"},
+		{spec: "coverage=10", err: "未知的阈值"},
+		{spec: "idents=abc", err: "值无效"},
+		{spec: "strings=-1", err: "值无效"},
+	}
+	for _, tc := range tests {
+		got, err := obfuscator.ParseThresholds(tc.spec)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("ParseThresholds(%q) 期望包含 %q 的错误，得到 %v", tc.spec, tc.err, err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ParseThresholds(%q) = %v, %v，期望 %v", tc.spec, got, err, tc.want)
+		}
+	}
+}
+
+// TestCoverageCheck 低于最低百分比或超过二进制可读数量上限的阈值被报告
+func TestCoverageCheck(t *testing.T) {
+	coverage := &obfuscator.Coverage{
+		Identifiers:    []obfuscator.KindCoverage{{Kind: "func", Total: 4, Renamed: 3, Percent: 75}},
+		IdentsPercent:  75,
+		StringsPercent: 50,
+	}
+	withBinary := *coverage
+	withBinary.Binary = &obfuscator.BinaryCoverage{ReadableSymbols: 2, ReadableLiterals: 3}
+
+	tests := []struct {
+		name     string
+		coverage *obfuscator.Coverage
+		spec     string
+		failed   []string // 期望失败项中包含的片段，按键名排序
+	}{
+		{"all met", coverage, "idents=75,strings=50,func=70", nil},
+		{"idents", coverage, "idents=80", []string{"标识符改名比例"}},
+		{"strings and kind", coverage, "strings=60,func=80", []string{"func 改名比例", "字符串加密比例"}},
+		{"unknown kind is ignored", coverage, "method=100", nil},
+		{"binary not scanned", coverage, "symbols=0", []string{"需要扫描二进制"}},
+		{"binary limits", &withBinary, "symbols=1,literals=0", []string{"明文的字符串字面量", "可读的符号"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			thresholds, err := obfuscator.ParseThresholds(tc.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			failed := tc.coverage.Check(thresholds)
+			if len(failed) != len(tc.failed) {
+				t.Fatalf("Check(%s) = %v，期望 %d 项", tc.spec, failed, len(tc.failed))
+			}
+			for i, want := range tc.failed {
+				if !strings.Contains(failed[i], want) {
+					t.Errorf("第 %d 项 %q 应包含 %q", i, failed[i], want)
+				}
+			}
+		})
+	}
+}
+
+// TestThresholdsNeedsBinary 只有 symbols、literals 需要扫描二进制
+func TestThresholdsNeedsBinary(t *testing.T) {
+	for spec, want := range map[string]bool{"idents=80,func=90": false, "symbols=0": true, "strings=10,literals=3": true} {
+		thresholds, err := obfuscator.ParseThresholds(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := thresholds.NeedsBinary(); got != want {
+			t.Errorf("%s: NeedsBinary() = %v", spec, got)
+		}
+	}
+}
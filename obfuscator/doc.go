@@ -0,0 +1,20 @@
+// Package obfuscator 提供 Go 源码级混淆和链接器级混淆。
+//
+// 在构建系统中使用时通过 New 创建混淆器，用 Run 执行并取得完整的名称映射和统计信息：
+//
+//	cfg := obfuscator.DefaultConfig()
+//	cfg.EncryptStrings = true
+//	obf := obfuscator.New("./myapp", "./myapp_obf", cfg,
+//		obfuscator.WithSeed("release-1.2"),
+//		obfuscator.WithLogger(log.Default()),
+//	)
+//	result, err := obf.Run(ctx)
+//	if err != nil {
+//		return err
+//	}
+//	_ = json.NewEncoder(mappingFile).Encode(result.Mapping)
+//
+// 库本身不向终端输出，也不会退出进程：进度和警告交给 WithLogger 注入的 Logger，
+// 结构化事件交给 WithEventHandler；所有错误都通过返回值传递。ctx 取消后 Run 尽快返回 ctx.Err()，
+// 已写出的文件保留在输出目录中。
+package obfuscator
@@ -0,0 +1,164 @@
+package obfuscator
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// minEmbedMatchLength 嵌入文件内容短于此长度时不做定位，避免误匹配
+const minEmbedMatchLength = 4
+
+// findEmbeddedFiles 扫描项目中的 //go:embed 指令，返回被嵌入文件的绝对路径
+func findEmbeddedFiles(root string) []string {
+	seen := make(map[string]bool)
+	var files []string
+
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			name := info.Name()
+			if path != root && (strings.HasPrefix(name, ".") || name == "vendor" || name == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return nil
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if !strings.HasPrefix(line, "//go:embed ") {
+				continue
+			}
+			for _, pattern := range parseEmbedPatterns(strings.TrimPrefix(line, "//go:embed ")) {
+				for _, file := range expandEmbedPattern(filepath.Dir(path), pattern) {
+					if !seen[file] {
+						seen[file] = true
+						files = append(files, file)
+					}
+				}
+			}
+		}
+		return nil
+	})
+
+	return files
+}
+
+// collectEmbeddedFiles 解析项目中的 //go:embed 指令，记录被嵌入的文件
+func (o *Obfuscator) collectEmbeddedFiles() {
+	o.embeddedFiles = make(map[string]bool)
+	for _, file := range findEmbeddedFiles(o.projectRoot) {
+		if abs, err := filepath.Abs(file); err == nil {
+			o.embeddedFiles[abs] = true
+		}
+	}
+	if len(o.embeddedFiles) > 0 {
+		o.logf("发现 %d 个 //go:embed 嵌入文件，输出中保持原名", len(o.embeddedFiles))
+	}
+}
+
+// isEmbeddedFile 判断文件是否被 //go:embed 嵌入
+func (o *Obfuscator) isEmbeddedFile(path string) bool {
+	abs, err := filepath.Abs(path)
+	return err == nil && o.embeddedFiles[abs]
+}
+
+// EmbeddedFiles 返回被 //go:embed 嵌入的文件（相对项目目录，使用 "/" 分隔），
+// 可传给 LinkConfig.EmbeddedFiles，使链接器阶段不修改这些数据
+func (o *Obfuscator) EmbeddedFiles() []string {
+	absRoot, err := filepath.Abs(o.projectRoot)
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for file := range o.embeddedFiles {
+		if rel, err := filepath.Rel(absRoot, file); err == nil {
+			files = append(files, filepath.ToSlash(rel))
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// parseEmbedPatterns 解析 //go:embed 指令的参数（支持双引号和反引号包裹的模式）
+func parseEmbedPatterns(args string) []string {
+	var patterns []string
+	for args = strings.TrimSpace(args); args != ""; args = strings.TrimSpace(args) {
+		switch args[0] {
+		case '"', '`':
+			end := strings.IndexByte(args[1:], args[0])
+			if end < 0 {
+				return patterns
+			}
+			quoted := args[:end+2]
+			if unquoted, err := strconv.Unquote(quoted); err == nil {
+				patterns = append(patterns, unquoted)
+			}
+			args = args[end+2:]
+		default:
+			end := strings.IndexAny(args, " \t")
+			if end < 0 {
+				end = len(args)
+			}
+			patterns = append(patterns, args[:end])
+			args = args[end:]
+		}
+	}
+	return patterns
+}
+
+// expandEmbedPattern 按 go:embed 规则展开模式：目录会递归包含其中的文件，
+// 未使用 all: 前缀时跳过以 "." 或 "_" 开头的文件
+func expandEmbedPattern(dir, pattern string) []string {
+	includeHidden := strings.HasPrefix(pattern, "all:")
+	pattern = strings.TrimPrefix(pattern, "all:")
+
+	matches, err := filepath.Glob(filepath.Join(dir, filepath.FromSlash(pattern)))
+	if err != nil {
+		return nil
+	}
+
+	var files []string
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil {
+			continue
+		}
+		if !info.IsDir() {
+			files = append(files, match)
+			continue
+		}
+		filepath.Walk(match, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			name := info.Name()
+			if path != match && !includeHidden && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !info.IsDir() {
+				files = append(files, path)
+			}
+			return nil
+		})
+	}
+	return files
+}
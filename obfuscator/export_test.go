@@ -0,0 +1,90 @@
+package obfuscator
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// 供外部测试包 obfuscator_test 使用的内部函数
+
+// EqualLengthReplacements 按 config.PackageReplacements 计算等长替换名
+func EqualLengthReplacements(config *LinkConfig) (map[string]string, error) {
+	lo := NewLinkerObfuscator(".", "", config)
+	return lo.equalLengthReplacements(NewNaturalNameGenerator())
+}
+
+// ReplaceProjectPaths 把整个 data 视为 buildinfo 区域替换项目包路径，//go:embed 数据区间除外。
+// 返回替换次数
+func ReplaceProjectPaths(projectDir string, config *LinkConfig, data []byte) (int, error) {
+	lo := NewLinkerObfuscator(projectDir, "", config)
+	lo.applied = make(map[string]appliedReplacement)
+	resolved, err := lo.equalLengthReplacements(NewNaturalNameGenerator())
+	if err != nil {
+		return 0, err
+	}
+	regions := subtractRanges([]byteRange{{Name: "buildinfo", Start: 0, End: len(data)}}, lo.collectEmbedDataRanges(data))
+	return lo.replaceProjectPackagePathsInRegions(data, regions, resolved), nil
+}
+
+// ToolexecStats 一个包在 -toolexec 模式下的改名统计
+type ToolexecStats = toolexecStats
+
+// ToolexecRewrite 按 -toolexec 模式改写 pkgPath 包的源文件（不能有导入），返回改写后的源码和统计
+func ToolexecRewrite(pkgPath, outDir string, files []string, seed string) ([]string, *ToolexecStats, error) {
+	importcfg := filepath.Join(outDir, "importcfg")
+	if err := os.WriteFile(importcfg, nil, 0644); err != nil {
+		return nil, nil, err
+	}
+	args := append([]string{"-p", pkgPath, "-importcfg", importcfg, "-o", filepath.Join(outDir, "_pkg_.a")}, files...)
+	inv := parseCompileArgs(args)
+	newArgs, stats, err := rewriteCompilePackage(args, inv, seed)
+	if err != nil {
+		return nil, nil, err
+	}
+	var sources []string
+	for _, idx := range inv.files {
+		data, err := os.ReadFile(newArgs[idx])
+		if err != nil {
+			return nil, nil, err
+		}
+		sources = append(sources, string(data))
+	}
+	return sources, stats, nil
+}
+
+// RunToolVersion 以 -toolexec 包装器身份执行 tool -V=full，返回退出码
+func RunToolVersion(tool, name, seed string) int {
+	return runToolVersion(tool, name, seed)
+}
+
+// PassOrder 返回按 config 实际执行的 Pass 名称
+func PassOrder(config *Config) ([]string, error) {
+	passes, err := New(".", "", config).passPipeline()
+	if err != nil {
+		return nil, err
+	}
+	return passNames(passes), nil
+}
+
+// UnregisterPass 撤销 RegisterPass，测试结束后恢复全局注册表
+func UnregisterPass(name string) {
+	passMu.Lock()
+	defer passMu.Unlock()
+	delete(passByName, name)
+	for i, n := range customPass {
+		if n == name {
+			customPass = append(customPass[:i], customPass[i+1:]...)
+			break
+		}
+	}
+}
+
+// ResignMachO 重新计算 Mach-O ad-hoc 签名的代码页哈希
+func ResignMachO(data []byte) error {
+	return resignMachO(data)
+}
+
+// VerifyMachOSignature 检查 Mach-O ad-hoc 签名与内容一致
+func VerifyMachOSignature(data []byte) error {
+	return verifyMachOSignature(data)
+}
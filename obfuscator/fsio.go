@@ -0,0 +1,210 @@
+package obfuscator
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// OutputSink 接收混淆后的项目文件。Run 成功后按路径顺序依次调用 WriteFile（不会并发），最后调用 Close
+type OutputSink interface {
+	WriteFile(name string, data []byte, perm fs.FileMode) error // name 为以 / 分隔的相对路径
+	Close() error
+}
+
+// archiveModTime 归档中所有文件的修改时间，相同输入和种子得到逐字节相同的归档
+var archiveModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// dirSink 写入目录。作为 WithOutput 的参数时混淆器直接在该目录中工作
+type dirSink struct {
+	dir string
+}
+
+// NewDirSink 返回写入目录的 OutputSink
+func NewDirSink(dir string) OutputSink {
+	return &dirSink{dir: dir}
+}
+
+func (d *dirSink) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	path := filepath.Join(d.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, perm)
+}
+
+func (d *dirSink) Close() error { return nil }
+
+// tarSink 写入 tar 流
+type tarSink struct {
+	tw *tar.Writer
+}
+
+// NewTarSink 返回写入 tar 流的 OutputSink。Close 写入 tar 结尾，但不关闭 w
+func NewTarSink(w io.Writer) OutputSink {
+	return &tarSink{tw: tar.NewWriter(w)}
+}
+
+func (t *tarSink) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	hdr := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     int64(perm.Perm()),
+		Size:     int64(len(data)),
+		ModTime:  archiveModTime,
+		Format:   tar.FormatPAX,
+	}
+	if err := t.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := t.tw.Write(data)
+	return err
+}
+
+func (t *tarSink) Close() error { return t.tw.Close() }
+
+// zipSink 写入 zip 流
+type zipSink struct {
+	zw *zip.Writer
+}
+
+// NewZipSink 返回写入 zip 流的 OutputSink。Close 写入中央目录，但不关闭 w
+func NewZipSink(w io.Writer) OutputSink {
+	return &zipSink{zw: zip.NewWriter(w)}
+}
+
+func (z *zipSink) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	hdr := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: archiveModTime}
+	hdr.SetMode(perm)
+	fw, err := z.zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	_, err = fw.Write(data)
+	return err
+}
+
+func (z *zipSink) Close() error { return z.zw.Close() }
+
+// MemorySink 把输出保存在内存中，Files 的键为以 / 分隔的相对路径
+type MemorySink struct {
+	Files map[string][]byte
+}
+
+// NewMemorySink 返回空的 MemorySink
+func NewMemorySink() *MemorySink {
+	return &MemorySink{Files: make(map[string][]byte)}
+}
+
+func (m *MemorySink) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	m.Files[name] = append([]byte(nil), data...)
+	return nil
+}
+
+func (m *MemorySink) Close() error { return nil }
+
+// prepareIO 准备输入和输出目录。类型检查需要 go list 读取真实目录，
+// 因此 WithSourceFS 的输入先写入临时目录；目录以外的 OutputSink 在临时目录中生成，Run 结束后写入 sink。
+// 临时目录由 cleanupIO 删除
+func (o *Obfuscator) prepareIO() error {
+	if o.ioPrepared {
+		return nil
+	}
+	o.ioPrepared = true
+
+	// overlay.json 引用项目目录和输出目录中的绝对路径，两者都必须是真实目录
+	if o.Config.Overlay {
+		if o.sourceFS != nil {
+			return fmt.Errorf("overlay 模式需要真实的项目目录，不支持 WithSourceFS")
+		}
+		if _, ok := o.output.(*dirSink); o.output != nil && !ok {
+			return fmt.Errorf("overlay 模式只能输出到目录")
+		}
+		if o.Config.ObfuscateVendor {
+			return fmt.Errorf("overlay 模式不支持 ObfuscateVendor（依赖的源码不在项目目录中）")
+		}
+	}
+
+	if o.sourceFS != nil {
+		dir, err := os.MkdirTemp("", "cfo-src-")
+		if err != nil {
+			return fmt.Errorf("创建临时输入目录失败: %v", err)
+		}
+		o.tempDirs = append(o.tempDirs, dir)
+		if err := writeFS(dir, o.sourceFS); err != nil {
+			return fmt.Errorf("读取输入文件系统失败: %v", err)
+		}
+		o.projectRoot = dir
+	}
+
+	switch sink := o.output.(type) {
+	case nil:
+	case *dirSink:
+		o.outputDir = sink.dir
+	default:
+		dir, err := os.MkdirTemp("", "cfo-out-")
+		if err != nil {
+			return fmt.Errorf("创建临时输出目录失败: %v", err)
+		}
+		o.tempDirs = append(o.tempDirs, dir)
+		o.outputDir = dir
+		o.stagedOutput = true
+	}
+	return nil
+}
+
+// cleanupIO 删除 prepareIO 创建的临时目录
+func (o *Obfuscator) cleanupIO() {
+	for _, dir := range o.tempDirs {
+		os.RemoveAll(dir)
+	}
+	o.tempDirs = nil
+}
+
+// flushOutput 把临时输出目录中的文件按路径顺序写入 sink
+func (o *Obfuscator) flushOutput() error {
+	return filepath.Walk(o.outputDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(o.outputDir, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return o.output.WriteFile(filepath.ToSlash(rel), data, info.Mode().Perm())
+	})
+}
+
+// writeFS 把 fsys 中的全部文件写入目录 dir
+func writeFS(dir string, fsys fs.FS) error {
+	return fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dir, filepath.FromSlash(path))
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+		perm := fs.FileMode(0644)
+		if info, err := d.Info(); err == nil {
+			perm = info.Mode().Perm() | 0600
+		}
+		return os.WriteFile(target, data, perm)
+	})
+}
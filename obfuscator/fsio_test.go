@@ -0,0 +1,110 @@
+package obfuscator_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cross-file-obfuscator/obfuscator"
+)
+
+// TestOutputSinks 经 tar、zip、内存 sink 以及 fs.FS 输入得到的文件与直接输出到目录的结果一致
+func TestOutputSinks(t *testing.T) {
+	project := copyTestdata(t, "app")
+	newConfig := func() *obfuscator.Config {
+		config := obfuscator.DefaultConfig()
+		config.EncryptStrings = true
+		config.Seed = "test-seed"
+		return config
+	}
+	_, result := obfuscate(t, project, newConfig())
+	want := readTree(t, result.OutputDir)
+	if len(want) == 0 {
+		t.Fatal("目录输出为空")
+	}
+
+	run := func(sink obfuscator.OutputSink, opts ...obfuscator.Option) {
+		t.Helper()
+		opts = append(opts, obfuscator.WithOutput(sink))
+		if _, err := obfuscator.New(project, "", newConfig(), opts...).Run(context.Background()); err != nil {
+			t.Fatalf("混淆失败: %v", err)
+		}
+	}
+	compare := func(t *testing.T, got map[string]string) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Errorf("得到 %d 个文件，期望 %d 个", len(got), len(want))
+		}
+		for name, content := range want {
+			if got[name] != content {
+				t.Errorf("%s 与目录输出不同", name)
+			}
+		}
+	}
+
+	t.Run("tar", func(t *testing.T) {
+		var buf bytes.Buffer
+		run(obfuscator.NewTarSink(&buf))
+		got := make(map[string]string)
+		tr := tar.NewReader(&buf)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got[hdr.Name] = string(data)
+		}
+		compare(t, got)
+	})
+
+	t.Run("zip", func(t *testing.T) {
+		var buf bytes.Buffer
+		run(obfuscator.NewZipSink(&buf))
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]string)
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got[f.Name] = string(data)
+		}
+		compare(t, got)
+	})
+
+	t.Run("memory", func(t *testing.T) {
+		sink := obfuscator.NewMemorySink()
+		run(sink)
+		got := make(map[string]string)
+		for name, data := range sink.Files {
+			got[name] = string(data)
+		}
+		compare(t, got)
+	})
+
+	t.Run("fs input", func(t *testing.T) {
+		out := filepath.Join(t.TempDir(), "out")
+		run(obfuscator.NewDirSink(out), obfuscator.WithSourceFS(os.DirFS(project)))
+		compare(t, readTree(t, out))
+	})
+}
@@ -0,0 +1,41 @@
+package obfuscator_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cross-file-obfuscator/obfuscator"
+)
+
+// TestGeneratedHeaderPolicy 按已解析文件包声明前的生成代码标记识别生成器并应用策略
+func TestGeneratedHeaderPolicy(t *testing.T) {
+	project := t.TempDir()
+	files := map[string]string{
+		"go.mod":     "module example.com/gen\n\ngo 1.22\n",
+		"kind.go":    "// Code generated by \"stringer -type=Kind\"; DO NOT EDIT.\r\n\r\npackage gen\r\n\r\nfunc kindName() string { return \"k\" }\r\n",
+		"query.go":   "// Code generated by sqlc. DO NOT EDIT.\n\npackage gen\n\nfunc query() string { return \"q\" }\n",
+		"doc.go":     "package gen\n\n// Code generated by hand. DO NOT EDIT.\nfunc docName() string { return \"d\" }\n",
+		"gen_use.go": "package gen\n\n// Names 返回所有名称\nfunc Names() []string { return []string{kindName(), query(), docName()} }\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(project, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := obfuscator.DefaultConfig()
+	config.GeneratedPolicies = map[string]string{"stringer": obfuscator.GeneratedFull}
+	_, result := obfuscate(t, project, config)
+
+	// 只有 sqlc 生成的文件按默认策略跳过；包声明之后的标记不算生成代码
+	if _, ok := result.SkippedFiles["query.go"]; !ok || len(result.SkippedFiles) != 1 {
+		t.Errorf("只应跳过 query.go: %v", result.SkippedFiles)
+	}
+	if _, ok := result.Mapping.Functions["kindName"]; !ok {
+		t.Errorf("stringer 生成的文件按 full 策略应被混淆: %v", result.Mapping.Functions)
+	}
+	if _, ok := result.Mapping.Functions["query"]; ok {
+		t.Error("跳过的生成文件中的函数不应改名")
+	}
+}
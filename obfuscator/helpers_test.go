@@ -0,0 +1,113 @@
+package obfuscator_test
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cross-file-obfuscator/obfuscator"
+)
+
+// TestMain 让测试二进制同时充当 -toolexec 包装器：buildBinaryToolexec 以 os.Executable() 作为包装器
+func TestMain(m *testing.M) {
+	if obfuscator.IsToolexecInvocation() {
+		os.Exit(obfuscator.RunToolexec(os.Args[1:]))
+	}
+	os.Exit(m.Run())
+}
+
+// copyTestdata 把 testdata 下的模块复制到临时目录，测试可以随意修改副本
+func copyTestdata(t *testing.T, name string) string {
+	t.Helper()
+	src := filepath.Join("testdata", name)
+	dst := filepath.Join(t.TempDir(), name)
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, 0644)
+	})
+	if err != nil {
+		t.Fatalf("复制 %s 失败: %v", name, err)
+	}
+	return dst
+}
+
+// runGo 在 dir 中执行 go 命令，失败时终止测试
+func runGo(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("go %s 失败: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return string(output)
+}
+
+// runBinary 运行编译出的二进制并返回标准输出
+func runBinary(t *testing.T, path string, args ...string) string {
+	t.Helper()
+	output, err := exec.Command(path, args...).Output()
+	if err != nil {
+		t.Fatalf("运行 %s 失败: %v", path, err)
+	}
+	return string(output)
+}
+
+// obfuscate 混淆 project 并返回结果，输出目录位于临时目录中
+func obfuscate(t *testing.T, project string, config *obfuscator.Config) (*obfuscator.Obfuscator, *obfuscator.Result) {
+	t.Helper()
+	if config.Seed == "" {
+		config.Seed = "test-seed"
+	}
+	obf := obfuscator.New(project, filepath.Join(t.TempDir(), "out"), config)
+	result, err := obf.Run(context.Background())
+	if err != nil {
+		t.Fatalf("混淆失败: %v", err)
+	}
+	return obf, result
+}
+
+// thirdpartyOutput testdata/thirdparty/app 的预期输出
+const thirdpartyOutput = "[a,b]\n[owner,balance]\n{\"Owner\":\"x\",\"Balance\":0} {\"Theme\":\"dark\"}\n"
+
+// obfuscateThirdparty 混淆 testdata/thirdparty 中的 app，返回混淆器和输出目录。
+// 输出目录与 app 同级，go.mod 中指向 ../orm 的 replace 仍然有效
+func obfuscateThirdparty(t *testing.T, config *obfuscator.Config) (*obfuscator.Obfuscator, string) {
+	t.Helper()
+	if config.Seed == "" {
+		config.Seed = "test-seed"
+	}
+	root := copyTestdata(t, "thirdparty")
+	out := filepath.Join(root, "out")
+	obf := obfuscator.New(filepath.Join(root, "app"), out, config)
+	if _, err := obf.Run(context.Background()); err != nil {
+		t.Fatalf("混淆失败: %v", err)
+	}
+	return obf, out
+}
+
+// skipIfShort 端到端测试需要多次调用 go 工具链，-short 时跳过
+func skipIfShort(t *testing.T) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("端到端测试，-short 时跳过")
+	}
+}
@@ -0,0 +1,156 @@
+package obfuscator
+
+import (
+	"go/ast"
+	"path/filepath"
+)
+
+// 沿用名称的类别
+const (
+	stableFunc   = "func"   // 包级函数
+	stableVar    = "var"    // 包级变量和常量
+	stableAlias  = "alias"  // 标准库导入路径 -> 别名
+	stableFile   = "file"   // 文件名 -> 混淆后的文件名
+	stableField  = "field"  // 包路径.类型名.字段名 -> 混淆后的字段名
+	stableObject = "object" // 包路径.原名 -> 按对象改名的包级函数、变量和常量
+)
+
+// stableNames 上一次运行分配的名称（WithPreviousResult）。同一原名再次出现时沿用原来的混淆名，
+// 未改动的输出文件因此仍与新生成的文件一致
+type stableNames struct {
+	tables map[string]map[string]string
+}
+
+// newStableNames 从上一次运行的结果中提取名称
+func newStableNames(prev *Result) *stableNames {
+	s := &stableNames{tables: make(map[string]map[string]string)}
+	for _, kind := range []string{stableFunc, stableVar, stableAlias, stableFile, stableField, stableObject} {
+		s.tables[kind] = make(map[string]string)
+	}
+	if prev.Mapping != nil {
+		for name, obf := range prev.Mapping.Functions {
+			s.tables[stableFunc][name] = obf
+		}
+		for name, obf := range prev.Mapping.Variables {
+			s.tables[stableVar][name] = obf
+		}
+		for key, obf := range prev.Mapping.Objects {
+			s.tables[stableObject][key] = obf
+		}
+		for path, alias := range prev.Mapping.ImportAliases {
+			s.tables[stableAlias][path] = alias
+		}
+		// 文件名按基本名映射（与 obfuscateFileName 一致）
+		for from, to := range prev.Mapping.Files {
+			if filepath.Dir(from) == filepath.Dir(to) {
+				s.tables[stableFile][filepath.Base(from)] = filepath.Base(to)
+			}
+		}
+	}
+	for _, tag := range prev.SynthesizedTags {
+		s.tables[stableField][tag.Type+"."+tag.Field] = tag.NewName
+	}
+	return s
+}
+
+// get 返回上一次运行为 key 分配的名称，未使用 WithPreviousResult 时总是返回 false
+func (s *stableNames) get(kind, key string) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+	name, ok := s.tables[kind][key]
+	return name, ok
+}
+
+// reserveStableNames 预先登记上一次运行分配的全部名称，新生成的名称不会与之冲突
+func (o *Obfuscator) reserveStableNames() {
+	for kind, table := range o.stable.tables {
+		if kind == stableField {
+			continue // 字段名只需在所属类型内唯一
+		}
+		for _, name := range table {
+			o.usedNames[name] = true
+		}
+	}
+}
+
+// relDir 返回文件所在目录相对项目目录的路径
+func (o *Obfuscator) relDir(path string) string {
+	rel, err := filepath.Rel(o.projectRoot, filepath.Dir(path))
+	if err != nil {
+		return filepath.Dir(path)
+	}
+	return rel
+}
+
+// rewritesDir 增量运行时只重写 onlyDirs 中的包，否则重写全部
+func (o *Obfuscator) rewritesDir(path string) bool {
+	return o.onlyDirs == nil || o.onlyDirs[o.relDir(path)]
+}
+
+// expandIncremental 增量运行时补全需要重写的包：名称映射是按名称跨文件共享的，
+// 映射发生变化（新增、删除、改为受保护）的名称所在的包都要重写；序列化字段的改名变化时重写全部
+func (o *Obfuscator) expandIncremental() {
+	if o.onlyDirs == nil || o.stable == nil {
+		return
+	}
+	fields := o.stable.tables[stableField]
+	if len(fields) != len(o.fieldRenamePlan) {
+		o.onlyDirs = nil
+		return
+	}
+	for _, r := range o.fieldRenamePlan {
+		if fields[r.Type+"."+r.Field] != r.NewName {
+			o.onlyDirs = nil
+			return
+		}
+	}
+
+	// 按对象改名的包级声明只通过位置改名，变化时同样重写全部
+	objects := o.stable.tables[stableObject]
+	if len(objects) != len(o.objectRenames) {
+		o.onlyDirs = nil
+		return
+	}
+	for key, obf := range o.objectRenames {
+		if objects[key] != obf {
+			o.onlyDirs = nil
+			return
+		}
+	}
+
+	changed := make(map[string]bool)
+	diff := func(prev, cur map[string]string) {
+		for name, obf := range cur {
+			if prev[name] != obf {
+				changed[name] = true
+			}
+		}
+		for name := range prev {
+			if _, ok := cur[name]; !ok {
+				changed[name] = true
+			}
+		}
+	}
+	diff(o.stable.tables[stableFunc], o.funcMapping)
+	diff(o.stable.tables[stableVar], o.varMapping)
+
+	if len(changed) > 0 {
+		for _, file := range o.sourceFiles {
+			dir := o.relDir(file.path)
+			if o.onlyDirs[dir] {
+				continue
+			}
+			ast.Inspect(file.node, func(n ast.Node) bool {
+				if id, ok := n.(*ast.Ident); ok && changed[id.Name] {
+					o.onlyDirs[dir] = true
+				}
+				return !o.onlyDirs[dir]
+			})
+		}
+	}
+	// 解密包每次都重新生成，需要和其他包一样经过转换
+	if o.Config.EncryptStrings {
+		o.onlyDirs[o.decryptPkgName] = true
+	}
+}
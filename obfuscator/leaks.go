@@ -0,0 +1,419 @@
+package obfuscator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"index/suffixarray"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 二进制中的区域
+const (
+	RegionPclntab   = "pclntab"   // 函数名表和源文件路径表
+	RegionRodata    = "rodata"    // 只读数据（不含类型描述和构建信息）：字符串字面量等
+	RegionTypeNames = "typenames" // 类型描述区：类型名、包路径、字段名和方法名
+	RegionBuildInfo = "buildinfo" // 构建信息和模块信息：模块路径、依赖和构建参数
+)
+
+// 泄露的类别
+const (
+	LeakIdentifier = "identifier" // 源码中声明的名称
+	LeakPackage    = "package"    // 模块路径和包的导入路径
+	LeakFile       = "file"       // 源文件路径和项目目录
+	LeakString     = "string"     // 字符串字面量
+	LeakComment    = "comment"    // 注释片段
+)
+
+// leakKinds 泄露类别的输出顺序
+var leakKinds = []string{LeakIdentifier, LeakPackage, LeakFile, LeakString, LeakComment}
+
+// LeakOptions ScanLeaks 的可选参数
+type LeakOptions struct {
+	MinLength int      // 参与匹配的名称和字面量的最小字节数，0 表示默认值（名称 4，字符串 6，注释 12）
+	Ignore    []string // 不报告的值（名称、路径或字面量），例如有意保留的导出 API
+}
+
+// LeakReport 二进制中仍然出现的源码信息，按区域分组
+type LeakReport struct {
+	Binary  string       `json:"binary"`
+	Source  string       `json:"source"`
+	Regions []LeakRegion `json:"regions"`
+	Total   int          `json:"total"` // 全部区域的泄露条数
+}
+
+// LeakRegion 一个区域中的泄露
+type LeakRegion struct {
+	Name  string `json:"name"` // Region* 常量
+	Size  int    `json:"size"` // 区域的字节数
+	Leaks []Leak `json:"leaks"`
+}
+
+// Leak 一条泄露：某个源码中的值在该区域中出现的次数
+type Leak struct {
+	Kind   string `json:"kind"` // Leak* 常量
+	Value  string `json:"value"`
+	Origin string `json:"origin,omitempty"` // 值在源码中首次出现的位置 file:line
+	Count  int    `json:"count"`
+}
+
+// Kinds 返回报告中出现的泄露类别及条数
+func (r *LeakReport) Kinds() map[string]int {
+	kinds := make(map[string]int)
+	for _, region := range r.Regions {
+		for _, leak := range region.Leaks {
+			kinds[leak.Kind]++
+		}
+	}
+	return kinds
+}
+
+// WriteJSON 以缩进的 JSON 写出报告
+func (r *LeakReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// leakCandidate 源码中需要在二进制里查找的一个值
+type leakCandidate struct {
+	kind   string
+	value  string
+	origin string
+}
+
+// ScanLeaks 在二进制（ELF、PE 或 Mach-O）中查找原始源码树中的标识符、包路径、文件路径、字符串字面量和注释片段，
+// 按区域（pclntab、rodata、typenames、buildinfo）报告。用于发布前确认混淆后的二进制没有泄露不该出现的信息
+func ScanLeaks(binaryPath, sourceDir string, opts *LeakOptions) (*LeakReport, error) {
+	if opts == nil {
+		opts = &LeakOptions{}
+	}
+	data, err := os.ReadFile(binaryPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取二进制失败: %v", err)
+	}
+	regions, err := splitBinaryRegions(data)
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := collectLeakCandidates(sourceDir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	std := stdTopLevel()
+	report := &LeakReport{Binary: binaryPath, Source: sourceDir}
+	for _, region := range regions {
+		lr := LeakRegion{Name: region.name, Leaks: []Leak{}}
+		var indexes []*suffixarray.Index
+		for _, c := range region.chunks {
+			lr.Size += len(c.data)
+			indexes = append(indexes, suffixarray.New(c.data))
+		}
+		for _, cand := range candidates {
+			count := 0
+			for i, idx := range indexes {
+				chunk := region.chunks[i]
+				for _, off := range idx.Lookup([]byte(cand.value), -1) {
+					if cand.kind != LeakIdentifier {
+						count++
+						continue
+					}
+					if !isWholeIdent(chunk.data, off, len(cand.value)) {
+						continue
+					}
+					// 函数名表中只统计非标准库的符号，标准库同名函数不算泄露
+					if chunk.name == "pclntab.funcnametab" && !isProjectSymbol(symbolAt(chunk.data, off), std) {
+						continue
+					}
+					count++
+				}
+			}
+			if count > 0 {
+				lr.Leaks = append(lr.Leaks, Leak{Kind: cand.kind, Value: cand.value, Origin: cand.origin, Count: count})
+			}
+		}
+		report.Total += len(lr.Leaks)
+		report.Regions = append(report.Regions, lr)
+	}
+	return report, nil
+}
+
+// isWholeIdent 判断 data[off:off+n] 前后都不是标识符字符（避免 Name 匹配到 FileName）
+func isWholeIdent(data []byte, off, n int) bool {
+	isIdent := func(b byte) bool {
+		return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+	}
+	if off > 0 && isIdent(data[off-1]) {
+		return false
+	}
+	return off+n >= len(data) || !isIdent(data[off+n])
+}
+
+// symbolAt 返回函数名表中包含偏移 off 的以 NUL 结尾的符号
+func symbolAt(data []byte, off int) string {
+	start := bytes.LastIndexByte(data[:off], 0) + 1
+	end := bytes.IndexByte(data[off:], 0)
+	if end < 0 {
+		return string(data[start:])
+	}
+	return string(data[start : off+end])
+}
+
+// collectLeakCandidates 遍历源码树（不含 vendor、testdata 和测试文件），收集需要查找的值，
+// 同一个值只保留第一次出现的位置，按类别和值排序
+func collectLeakCandidates(sourceDir string, opts *LeakOptions) ([]leakCandidate, error) {
+	root, err := filepath.Abs(sourceDir)
+	if err != nil {
+		return nil, err
+	}
+	minLen := func(def int) int {
+		if opts.MinLength > 0 {
+			return opts.MinLength
+		}
+		return def
+	}
+	ignore := make(map[string]bool)
+	for _, v := range opts.Ignore {
+		ignore[v] = true
+	}
+
+	seen := make(map[string]bool)
+	var candidates []leakCandidate
+	add := func(kind, value, origin string) {
+		key := kind + "\x00" + value
+		if value == "" || ignore[value] || seen[key] {
+			return
+		}
+		seen[key] = true
+		candidates = append(candidates, leakCandidate{kind: kind, value: value, origin: origin})
+	}
+
+	module, _ := readModulePath(root)
+	if module != "" {
+		add(LeakPackage, module, "go.mod")
+	}
+	add(LeakFile, root, "")
+
+	fset := token.NewFileSet()
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		name := info.Name()
+		if info.IsDir() {
+			if path != root && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".go") || isTestFile(name) {
+			return nil
+		}
+		node, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		rel = filepath.ToSlash(rel)
+		if dir := filepath.ToSlash(filepath.Dir(rel)); module != "" && dir != "." {
+			add(LeakPackage, module+"/"+dir, rel)
+		}
+		// 文件路径按 /rel 查找，匹配 pclntab 中的绝对路径和 -trimpath 后的模块路径
+		add(LeakFile, "/"+rel, rel)
+
+		origin := func(pos token.Pos) string {
+			return fmt.Sprintf("%s:%d", rel, fset.Position(pos).Line)
+		}
+		ident := func(id *ast.Ident) {
+			if id.Name != "_" && id.Name != "main" && id.Name != "init" && len(id.Name) >= minLen(4) {
+				add(LeakIdentifier, id.Name, origin(id.Pos()))
+			}
+		}
+		skip := make(map[*ast.BasicLit]bool)
+		for _, imp := range node.Imports {
+			skip[imp.Path] = true
+		}
+		// 名称只收集可能进入二进制的：包级声明、类型、结构体字段和接口方法（局部变量只出现在调试信息中）
+		for _, decl := range node.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				ident(d.Name)
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					switch s := spec.(type) {
+					case *ast.TypeSpec:
+						ident(s.Name)
+					case *ast.ValueSpec:
+						for _, id := range s.Names {
+							ident(id)
+						}
+					}
+				}
+			}
+		}
+		ast.Inspect(node, func(n ast.Node) bool {
+			switch x := n.(type) {
+			case *ast.StructType:
+				for _, f := range x.Fields.List {
+					for _, id := range f.Names {
+						ident(id)
+					}
+				}
+			case *ast.InterfaceType:
+				for _, m := range x.Methods.List {
+					for _, id := range m.Names {
+						ident(id)
+					}
+				}
+			case *ast.Field:
+				if x.Tag != nil {
+					skip[x.Tag] = true
+				}
+			case *ast.BasicLit:
+				if x.Kind != token.STRING || skip[x] {
+					return true
+				}
+				if s, err := strconv.Unquote(x.Value); err == nil && len(s) >= minLen(6) && strings.IndexFunc(s, isLetter) >= 0 {
+					add(LeakString, s, origin(x.Pos()))
+				}
+			}
+			return true
+		})
+
+		for _, group := range node.Comments {
+			for _, c := range group.List {
+				for _, line := range strings.Split(commentText(c.Text), "\n") {
+					line = strings.TrimSpace(line)
+					if len(line) < minLen(12) || strings.HasPrefix(line, "go:") || strings.HasPrefix(line, "+build") || strings.HasPrefix(line, "line ") {
+						continue
+					}
+					add(LeakComment, line, origin(c.Pos()))
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	order := make(map[string]int)
+	for i, kind := range leakKinds {
+		order[kind] = i
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].kind != candidates[j].kind {
+			return order[candidates[i].kind] < order[candidates[j].kind]
+		}
+		return candidates[i].value < candidates[j].value
+	})
+	return candidates, nil
+}
+
+// commentText 去掉注释的 // 或 /* */ 标记
+func commentText(text string) string {
+	if strings.HasPrefix(text, "//") {
+		return text[2:]
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(text, "/*"), "*/")
+}
+
+func isLetter(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r > 0x7f
+}
+
+// regionChunk 区域中的一段连续数据
+type regionChunk struct {
+	name string // binary_regions 中的区间名，例如 pclntab.funcnametab
+	data []byte
+}
+
+// binaryRegion 二进制中的一个区域
+type binaryRegion struct {
+	name   string
+	chunks []regionChunk
+}
+
+// binaryRegions 按 Region* 顺序排列的区域
+type binaryRegions []binaryRegion
+
+// chunk 返回区域中指定名称的数据段
+func (rs binaryRegions) chunk(region, name string) []byte {
+	for _, r := range rs {
+		if r.name != region {
+			continue
+		}
+		for _, c := range r.chunks {
+			if c.name == name {
+				return c.data
+			}
+		}
+	}
+	return nil
+}
+
+// contains 判断区域中是否出现 value
+func (rs binaryRegions) contains(region string, value []byte) bool {
+	for _, r := range rs {
+		if r.name != region {
+			continue
+		}
+		for _, c := range r.chunks {
+			if bytes.Contains(c.data, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// splitBinaryRegions 用 parseBinaryLayout 把二进制切分为 pclntab、rodata、typenames 和 buildinfo 四个区域。
+// 类型描述区和模块信息位于只读数据段中，从 rodata 中剔除，避免重复报告
+func splitBinaryRegions(data []byte) (binaryRegions, error) {
+	layout, err := parseBinaryLayout(data)
+	if err != nil {
+		return nil, err
+	}
+	regions := binaryRegions{{name: RegionPclntab}, {name: RegionRodata}, {name: RegionTypeNames}, {name: RegionBuildInfo}}
+	add := func(i int, r byteRange) {
+		regions[i].chunks = append(regions[i].chunks, regionChunk{name: r.Name, data: data[r.Start:r.End]})
+	}
+
+	excluded := layout.pclntabRegions(data)
+	for _, r := range excluded {
+		add(0, r)
+	}
+	if r, ok := layout.typesRegion(data); ok {
+		add(2, r)
+		excluded = append(excluded, r)
+	}
+	var info []byteRange
+	if r, ok := layout.buildInfoRegion(data); ok {
+		info = append(info, r)
+	}
+	info = append(info, modInfoRegions(data)...)
+	for _, r := range info {
+		add(3, r)
+	}
+	excluded = append(excluded, info...)
+
+	for _, s := range layout.sections {
+		switch s.Name {
+		case ".rodata", "__rodata", ".rdata":
+			whole := []byteRange{{Name: s.Name, Start: s.Offset, End: s.Offset + s.Size}}
+			for _, r := range subtractRanges(whole, excluded) {
+				add(1, r)
+			}
+		}
+	}
+	return regions, nil
+}
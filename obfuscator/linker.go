@@ -6,12 +6,14 @@ import (
 	"debug/macho"
 	"debug/pe"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"sort"
 	"strings"
 )
@@ -29,44 +31,66 @@ type LinkerObfuscator struct {
 	config     *LinkConfig
 	projectDir string
 	outputBin  string
+	goos       string                        // 目标操作系统（为空时继承环境变量 GOOS）
+	goarch     string                        // 目标架构（为空时继承环境变量 GOARCH）
+	format     string                        // 最近一次后处理检测到的二进制格式
+	applied    map[string]appliedReplacement // 最近一次后处理实际生效的替换
+	signature  string                        // 最近一次后处理对 Mach-O 代码签名的处理（signature* 常量，其他格式为空）
+	backupPath string                        // 本次构建后处理时写出的未修改二进制（未修改时为空）
+	logger     Logger                        // 进度输出（WithLogger，默认丢弃）
+}
+
+// appliedReplacement 记录一条实际写入二进制的替换及其次数
+type appliedReplacement struct {
+	Replacement string `json:"replacement"`
+	Count       int    `json:"count"`
 }
 
 // NewLinkerObfuscator 创建新的链接器混淆器
-func NewLinkerObfuscator(projectDir, outputBin string, config *LinkConfig) *LinkerObfuscator {
+func NewLinkerObfuscator(projectDir, outputBin string, config *LinkConfig, opts ...Option) *LinkerObfuscator {
 	if config == nil {
 		config = &LinkConfig{
 			RemoveFuncNames: true, // 默认混淆函数名
 			EntryPackage:    ".",  // 默认当前目录
 		}
 	}
+	// 复制一份再修改：选项和后处理（自动发现的包名、回退配置）不应写回调用方的配置，
+	// 否则同一配置再用于其他构建时会沿用本次的种子和替换
+	copied := *config
+	config = &copied
 	// 如果没有指定入口包，默认使用当前目录
 	if config.EntryPackage == "" {
 		config.EntryPackage = "."
 	}
+	opt := applyOptions(opts)
+	if opt.seed != nil {
+		config.ToolexecSeed = *opt.seed
+	}
 	return &LinkerObfuscator{
 		config:     config,
 		projectDir: projectDir,
 		outputBin:  outputBin,
+		logger:     opt.logger,
 	}
 }
 
 // BuildWithLinkerObfuscation 使用链接器混淆构建项目
 func (lo *LinkerObfuscator) BuildWithLinkerObfuscation() error {
-	fmt.Println("=== 链接器级别混淆 ===")
-	fmt.Printf("项目目录: %s\n", lo.projectDir)
-	fmt.Printf("入口包: %s\n", lo.config.EntryPackage)
-	
+	lo.logf("=== 链接器级别混淆 ===")
+	lo.logf("项目目录: %s\n", lo.projectDir)
+	lo.logf("入口包: %s\n", lo.config.EntryPackage)
+
 	// 如果启用了自动包名发现且没有手动指定包名替换
 	if lo.config.AutoDiscoverPackages && len(lo.config.PackageReplacements) == 0 {
-		fmt.Println("第 0 步: 自动发现项目包名...")
+		lo.logf("第 0 步: 自动发现项目包名...")
 		if err := lo.discoverAndGeneratePackageReplacements(); err != nil {
-			fmt.Printf("⚠️  警告: 自动发现包名失败: %v\n", err)
-			fmt.Println("   将继续使用默认包名替换模式")
+			lo.logf("⚠️  警告: 自动发现包名失败: %v\n", err)
+			lo.logf("   将继续使用默认包名替换模式")
 		}
 	}
-	
-	fmt.Println("第 1 步: 标准编译...")
-	
+
+	lo.logf("第 1 步: 标准编译...")
+
 	// 确保输出路径是绝对路径
 	outputPath := lo.outputBin
 	if !filepath.IsAbs(outputPath) {
@@ -76,61 +100,109 @@ func (lo *LinkerObfuscator) BuildWithLinkerObfuscation() error {
 		}
 		outputPath = filepath.Join(cwd, outputPath)
 	}
-	
+
+	if err := lo.buildBinary(outputPath); err != nil {
+		return err
+	}
+
+	lo.logf("✅ 标准编译完成")
+
+	// 更新 outputBin 为绝对路径
+	lo.outputBin = outputPath
+
+	// 第二步：后处理二进制文件
+	lo.logf("第 2 步: 后处理二进制文件...")
+	if err := lo.postProcessBinary(); err != nil {
+		return fmt.Errorf("后处理失败: %v", err)
+	}
+	lo.logf("✅ 后处理完成")
+
+	// 第三步：自检（可选）
+	if lo.config.SelfCheck {
+		lo.logf("第 3 步: 自检混淆后的二进制...")
+		if err := lo.selfCheckWithFallback(); err != nil {
+			return fmt.Errorf("自检失败: %v", err)
+		}
+	}
+
+	// 注意：由于编译时已使用 -ldflags="-s -w"，无需再执行 strip
+	lo.logf("✅ 符号表已在编译时移除（-ldflags=\"-s -w\"）")
+
+	return nil
+}
+
+// buildBinary 执行 go build，输出到 outputPath
+// 指定了目标平台时设置 GOOS/GOARCH 并禁用 CGO，以便交叉编译
+func (lo *LinkerObfuscator) buildBinary(outputPath string) error {
+	if lo.config.UseToolexec {
+		return lo.buildBinaryToolexec(outputPath)
+	}
+
 	// 构建 go build 命令
 	// 使用 -ldflags="-s -w" 移除符号表和调试信息
 	// -s: 禁用符号表
 	// -w: 禁用 DWARF 调试信息
 	buildArgs := []string{"build", "-ldflags=-s -w", "-trimpath", "-o", outputPath}
-	
+	if lo.config.Overlay != "" {
+		buildArgs = append(buildArgs, "-overlay", lo.config.Overlay)
+	}
+
 	// 添加入口包路径
 	buildArgs = append(buildArgs, lo.config.EntryPackage)
-	
+
 	buildCmd := exec.Command("go", buildArgs...)
 	buildCmd.Dir = lo.projectDir
-	buildCmd.Stdout = os.Stdout
-	buildCmd.Stderr = os.Stderr
 	buildCmd.Env = os.Environ() // 继承当前环境变量（包括 CGO_ENABLED 等）
-	
+
+	envPrefix := ""
+	if lo.goos != "" && lo.goarch != "" {
+		buildCmd.Env = append(buildCmd.Env, "GOOS="+lo.goos, "GOARCH="+lo.goarch, "CGO_ENABLED=0")
+		envPrefix = fmt.Sprintf("GOOS=%s GOARCH=%s CGO_ENABLED=0 ", lo.goos, lo.goarch)
+	}
+
 	// 打印实际执行的命令（调试用）
-	fmt.Printf("   执行命令: cd %s && go %s\n", lo.projectDir, strings.Join(buildArgs, " "))
-	
-	if err := buildCmd.Run(); err != nil {
-		return fmt.Errorf("编译失败: %v\n请尝试在项目目录手动执行: cd %s && go %s", err, lo.projectDir, strings.Join(buildArgs, " "))
-	}
-	
-	fmt.Println("✅ 标准编译完成")
-	
-	// 更新 outputBin 为绝对路径
-	lo.outputBin = outputPath
-	
-	// 第二步：后处理二进制文件
-	fmt.Println("第 2 步: 后处理二进制文件...")
-	if err := lo.postProcessBinary(); err != nil {
-		return fmt.Errorf("后处理失败: %v", err)
+	lo.logf("   执行命令: cd %s && %sgo %s\n", lo.projectDir, envPrefix, strings.Join(buildArgs, " "))
+
+	if output, err := buildCmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("编译失败: %v\n%s请尝试在项目目录手动执行: cd %s && %sgo %s", err, output, lo.projectDir, envPrefix, strings.Join(buildArgs, " "))
 	}
-	fmt.Println("✅ 后处理完成")
-	
-	// 注意：由于编译时已使用 -ldflags="-s -w"，无需再执行 strip
-	fmt.Println("✅ 符号表已在编译时移除（-ldflags=\"-s -w\"）")
-	
 	return nil
 }
 
+// targetPlatform 返回本次构建的目标平台
+func (lo *LinkerObfuscator) targetPlatform() (string, string) {
+	goos, goarch := lo.goos, lo.goarch
+	if goos == "" {
+		goos = os.Getenv("GOOS")
+	}
+	if goarch == "" {
+		goarch = os.Getenv("GOARCH")
+	}
+	if goos == "" {
+		goos = runtime.GOOS
+	}
+	if goarch == "" {
+		goarch = runtime.GOARCH
+	}
+	return goos, goarch
+}
+
 // postProcessBinary 后处理二进制文件
 func (lo *LinkerObfuscator) postProcessBinary() error {
 	data, err := os.ReadFile(lo.outputBin)
 	if err != nil {
 		return err
 	}
-	
+
 	// 检测二进制格式
 	format := detectBinaryFormat(data)
-	fmt.Printf("   检测到二进制格式: %s\n", format)
-	
+	lo.logf("   检测到二进制格式: %s\n", format)
+	lo.format = format
+	lo.applied = make(map[string]appliedReplacement)
+
 	var modified bool
 	var newData []byte
-	
+
 	switch format {
 	case "ELF":
 		newData, modified, err = lo.processELF(data)
@@ -141,49 +213,79 @@ func (lo *LinkerObfuscator) postProcessBinary() error {
 	default:
 		return fmt.Errorf("不支持的二进制格式: %s", format)
 	}
-	
+
 	if err != nil {
 		return err
 	}
-	
+
+	lo.signature = ""
+	if modified && format == "Mach-O" {
+		modified = lo.resignMachO(newData)
+	}
+
 	if modified {
 		// 备份原文件
 		backupPath := lo.outputBin + ".backup"
 		if err := os.WriteFile(backupPath, data, 0755); err != nil {
 			return fmt.Errorf("备份失败: %v", err)
 		}
-		
+		lo.backupPath = backupPath
+
 		// 写入修改后的文件
 		if err := os.WriteFile(lo.outputBin, newData, 0755); err != nil {
 			return fmt.Errorf("写入失败: %v", err)
 		}
-		
-		fmt.Printf("   ✅ 已修改 pclntab\n")
-		fmt.Printf("   ✅ 原文件已备份到: %s\n", backupPath)
+
+		lo.logf("   ✅ 已修改 pclntab\n")
+		lo.logf("   ✅ 原文件已备份到: %s\n", backupPath)
 	} else {
-		fmt.Println("   ⚠️  未找到 pclntab 或无需修改")
+		lo.logf("   ⚠️  未找到 pclntab 或无需修改")
 	}
-	
+
 	return nil
 }
 
+// Mach-O 代码签名的处理结果
+const (
+	signatureNone     = "无签名"
+	signatureResigned = "ad-hoc 签名已重新计算"
+	signatureSkipped  = "签名无法更新，未修改二进制"
+)
+
+// resignMachO 修改 Mach-O 后重新计算 ad-hoc 签名。签名无法就地更新时放弃本次修改：
+// 签名失效的二进制在 macOS 上启动即被终止，不如保留未混淆的二进制。返回修改是否保留
+func (lo *LinkerObfuscator) resignMachO(data []byte) bool {
+	switch err := resignMachO(data); {
+	case err == nil:
+		lo.signature = signatureResigned
+		lo.logf("   ✅ 已重新计算 ad-hoc 代码签名")
+	case errors.Is(err, errNoCodeSignature):
+		lo.signature = signatureNone
+	default:
+		lo.signature = signatureSkipped
+		lo.applied = make(map[string]appliedReplacement)
+		lo.logf("   ⚠️  %v，放弃对该 Mach-O 二进制的修改（签名失效的二进制无法在 macOS 上启动）\n", err)
+		return false
+	}
+	return true
+}
 
 // detectBinaryFormat 检测二进制文件格式
 func detectBinaryFormat(data []byte) string {
 	if len(data) < 4 {
 		return "Unknown"
 	}
-	
+
 	// ELF magic: 0x7F 'E' 'L' 'F'
 	if data[0] == 0x7F && data[1] == 'E' && data[2] == 'L' && data[3] == 'F' {
 		return "ELF"
 	}
-	
+
 	// PE magic: 'M' 'Z'
 	if data[0] == 'M' && data[1] == 'Z' {
 		return "PE"
 	}
-	
+
 	// Mach-O magic (multiple variants)
 	if len(data) >= 4 {
 		magic := binary.LittleEndian.Uint32(data[0:4])
@@ -192,7 +294,7 @@ func detectBinaryFormat(data []byte) string {
 			return "Mach-O"
 		}
 	}
-	
+
 	return "Unknown"
 }
 
@@ -204,29 +306,29 @@ func (lo *LinkerObfuscator) processELF(data []byte) ([]byte, bool, error) {
 		return data, false, fmt.Errorf("解析 ELF 失败: %v", err)
 	}
 	defer elfFile.Close()
-	
+
 	// 查找 .gopclntab 或 .data.rel.ro 段
 	var pclntabData []byte
 	var pclntabOffset int64
-	
+
 	for _, section := range elfFile.Sections {
 		if section.Name == ".gopclntab" || section.Name == ".data.rel.ro" {
 			sectionData, err := section.Data()
 			if err != nil {
 				continue
 			}
-			
+
 			// 在段中搜索 pclntab magic
 			offset := findPclntabMagic(sectionData)
 			if offset >= 0 {
 				pclntabData = sectionData
 				pclntabOffset = int64(section.Offset) + int64(offset)
-				fmt.Printf("   找到 pclntab 在段 %s，偏移: 0x%x\n", section.Name, pclntabOffset)
+				lo.logf("   找到 pclntab 在段 %s，偏移: 0x%x\n", section.Name, pclntabOffset)
 				break
 			}
 		}
 	}
-	
+
 	if pclntabData == nil {
 		// 在整个文件中搜索
 		offset := findPclntabMagic(data)
@@ -234,9 +336,9 @@ func (lo *LinkerObfuscator) processELF(data []byte) ([]byte, bool, error) {
 			return data, false, nil
 		}
 		pclntabOffset = int64(offset)
-		fmt.Printf("   找到 pclntab 在文件偏移: 0x%x\n", pclntabOffset)
+		lo.logf("   找到 pclntab 在文件偏移: 0x%x\n", pclntabOffset)
 	}
-	
+
 	// 修改二进制数据
 	return lo.modifyPclntab(data, pclntabOffset)
 }
@@ -248,26 +350,26 @@ func (lo *LinkerObfuscator) processPE(data []byte) ([]byte, bool, error) {
 		return data, false, fmt.Errorf("解析 PE 失败: %v", err)
 	}
 	defer peFile.Close()
-	
+
 	// 在 .rdata 或 .data 段中查找 pclntab
 	var pclntabOffset int64 = -1
-	
+
 	for _, section := range peFile.Sections {
 		if section.Name == ".rdata" || section.Name == ".data" {
 			sectionData, err := section.Data()
 			if err != nil {
 				continue
 			}
-			
+
 			offset := findPclntabMagic(sectionData)
 			if offset >= 0 {
 				pclntabOffset = int64(section.Offset) + int64(offset)
-				fmt.Printf("   找到 pclntab 在段 %s，偏移: 0x%x\n", section.Name, pclntabOffset)
+				lo.logf("   找到 pclntab 在段 %s，偏移: 0x%x\n", section.Name, pclntabOffset)
 				break
 			}
 		}
 	}
-	
+
 	if pclntabOffset < 0 {
 		// 在整个文件中搜索
 		offset := findPclntabMagic(data)
@@ -275,9 +377,9 @@ func (lo *LinkerObfuscator) processPE(data []byte) ([]byte, bool, error) {
 			return data, false, nil
 		}
 		pclntabOffset = int64(offset)
-		fmt.Printf("   找到 pclntab 在文件偏移: 0x%x\n", pclntabOffset)
+		lo.logf("   找到 pclntab 在文件偏移: 0x%x\n", pclntabOffset)
 	}
-	
+
 	return lo.modifyPclntab(data, pclntabOffset)
 }
 
@@ -288,26 +390,26 @@ func (lo *LinkerObfuscator) processMachO(data []byte) ([]byte, bool, error) {
 		return data, false, fmt.Errorf("解析 Mach-O 失败: %v", err)
 	}
 	defer machoFile.Close()
-	
+
 	// 在 __gopclntab 或 __data 段中查找
 	var pclntabOffset int64 = -1
-	
+
 	for _, section := range machoFile.Sections {
 		if section.Name == "__gopclntab" || section.Name == "__data" {
 			sectionData, err := section.Data()
 			if err != nil {
 				continue
 			}
-			
+
 			offset := findPclntabMagic(sectionData)
 			if offset >= 0 {
 				pclntabOffset = int64(section.Offset) + int64(offset)
-				fmt.Printf("   找到 pclntab 在段 %s，偏移: 0x%x\n", section.Name, pclntabOffset)
+				lo.logf("   找到 pclntab 在段 %s，偏移: 0x%x\n", section.Name, pclntabOffset)
 				break
 			}
 		}
 	}
-	
+
 	if pclntabOffset < 0 {
 		// 在整个文件中搜索
 		offset := findPclntabMagic(data)
@@ -315,16 +417,16 @@ func (lo *LinkerObfuscator) processMachO(data []byte) ([]byte, bool, error) {
 			return data, false, nil
 		}
 		pclntabOffset = int64(offset)
-		fmt.Printf("   找到 pclntab 在文件偏移: 0x%x\n", pclntabOffset)
+		lo.logf("   找到 pclntab 在文件偏移: 0x%x\n", pclntabOffset)
 	}
-	
+
 	return lo.modifyPclntab(data, pclntabOffset)
 }
 
 // findPclntabMagic 在数据中查找 pclntab magic value
 func findPclntabMagic(data []byte) int {
 	magics := []uint32{go12magic, go116magic, go118magic, go120magic}
-	
+
 	for i := 0; i <= len(data)-4; i++ {
 		value := binary.LittleEndian.Uint32(data[i : i+4])
 		for _, magic := range magics {
@@ -333,7 +435,7 @@ func findPclntabMagic(data []byte) int {
 			}
 		}
 	}
-	
+
 	return -1
 }
 
@@ -342,41 +444,46 @@ func (lo *LinkerObfuscator) modifyPclntab(data []byte, offset int64) ([]byte, bo
 	if offset < 0 || offset+4 > int64(len(data)) {
 		return data, false, fmt.Errorf("无效的 pclntab 偏移")
 	}
-	
+
 	// 复制数据以避免修改原始数据
 	newData := make([]byte, len(data))
 	copy(newData, data)
-	
+
 	// 读取原始 magic value（仅用于显示）
 	originalMagic := binary.LittleEndian.Uint32(newData[offset : offset+4])
-	fmt.Printf("   原始 magic value: 0x%08x\n", originalMagic)
-	
+	lo.logf("   原始 magic value: 0x%08x\n", originalMagic)
+
 	// 检查是否完全禁用 pclntab 修改
 	if lo.config.DisablePclntab {
-		fmt.Printf("   ⚠️  pclntab 修改已禁用（避免杀软误报）\n")
+		lo.logf("   ⚠️  pclntab 修改已禁用（避免杀软误报）\n")
 		return data, false, nil
 	}
-	
+
 	// 混淆函数名
 	if lo.config.RemoveFuncNames {
-		if err := lo.obfuscateFunctionNames(newData, offset); err != nil {
+		if err := lo.obfuscateFunctionNames(newData); err != nil {
 			return data, false, fmt.Errorf("函数名混淆失败: %v", err)
 		}
-		fmt.Printf("   ✅ 已混淆函数名\n")
+		lo.logf("   ✅ 已混淆函数名\n")
 		return newData, true, nil
 	}
-	
+
 	return data, false, nil
 }
 
 // obfuscateFunctionNames 混淆二进制中的函数名（使用等长自然混淆）
-func (lo *LinkerObfuscator) obfuscateFunctionNames(data []byte, pclntabOffset int64) error {
+func (lo *LinkerObfuscator) obfuscateFunctionNames(data []byte) error {
 	var patterns []string
 	var replacements []string
-	
+
 	// 创建自然名称生成器
 	nameGen := NewNaturalNameGenerator()
-	
+	// 每个包只确定一次等长替换名，函数名前缀和包路径使用同一个名称
+	resolved, err := lo.equalLengthReplacements(nameGen)
+	if err != nil {
+		return err
+	}
+
 	// 标准库包列表
 	standardLibs := map[string]bool{
 		"main": false, "runtime": true, "sync": true, "fmt": true,
@@ -387,69 +494,61 @@ func (lo *LinkerObfuscator) obfuscateFunctionNames(data []byte, pclntabOffset in
 		"hex": true, "unicode": true, "regexp": true, "log": true,
 		"sort": true, "path": true, "filepath": true, "syscall": true,
 	}
-	
+
 	// 如果用户提供了自定义包名替换映射，使用它
 	if len(lo.config.PackageReplacements) > 0 {
 		if lo.config.OnlyObfuscateProject {
-			fmt.Println("   ⚠️  最小化混淆模式：只混淆项目包，保留标准库")
+			lo.logf("   ⚠️  最小化混淆模式：只混淆项目包，保留标准库")
 		} else {
-			fmt.Println("   使用自定义包名替换映射（等长模式）:")
+			lo.logf("   使用自定义包名替换映射（等长模式）:")
 		}
-		
-		for original, replacement := range lo.config.PackageReplacements {
+
+		for original := range lo.config.PackageReplacements {
 			// 检查是否是标准库
 			pkgName := strings.TrimSuffix(original, ".")
 			isStdLib := standardLibs[pkgName]
-			
+
 			// 如果启用了 OnlyObfuscateProject，跳过标准库
 			if lo.config.OnlyObfuscateProject && isStdLib {
 				continue
 			}
-			
+
 			// 确保包名以 "." 结尾（用于匹配函数名）
 			originalPattern := original
 			if !strings.HasSuffix(originalPattern, ".") {
 				originalPattern += "."
 			}
-			
-			// 确保替换后的名称与原始名称等长
-			replacementPattern := replacement
-			if !strings.HasSuffix(replacementPattern, ".") {
-				replacementPattern += "."
-			}
-			
-			// 如果长度不同，重新生成等长的名称
-			if len(replacementPattern) != len(originalPattern) {
-				replacementPattern = nameGen.GeneratePackageName(originalPattern, len(originalPattern))
-			}
-			
+
+			// 替换后的名称与原始名称等长
+			replacementPattern := resolved[pkgName] + "."
+
 			patterns = append(patterns, originalPattern)
 			replacements = append(replacements, replacementPattern)
-			
+
 			if !lo.config.OnlyObfuscateProject || (lo.config.OnlyObfuscateProject && !isStdLib) {
-				fmt.Printf("     %s -> %s (均为 %d 字节)\n", originalPattern, replacementPattern, len(originalPattern))
+				lo.logf("     %s -> %s (均为 %d 字节)\n", originalPattern, replacementPattern, len(originalPattern))
 			}
 		}
-		
+
 		if lo.config.OnlyObfuscateProject {
-			fmt.Printf("   ✅ 已过滤标准库，只混淆项目包（共 %d 个）\n", len(patterns))
+			lo.logf("   ✅ 已过滤标准库，只混淆项目包（共 %d 个）\n", len(patterns))
 		}
 	} else {
 		// 使用默认的包名替换模式（等长自然混淆）
 		if lo.config.OnlyObfuscateProject {
-			fmt.Println("   ⚠️  最小化混淆模式：只混淆项目包，保留标准库（减少杀软误报）")
+			lo.logf("   ⚠️  最小化混淆模式：只混淆项目包，保留标准库（减少杀软误报）")
 			// 只混淆 main 包，保留所有标准库
 			defaultPatterns := []string{
 				"main.",
 			}
-			
+
 			for _, pattern := range defaultPatterns {
 				replacement := nameGen.GeneratePackageName(pattern, len(pattern))
 				patterns = append(patterns, pattern)
 				replacements = append(replacements, replacement)
 			}
 		} else {
-			fmt.Println("   使用等长自然混淆模式（标准）")
+			lo.logf("   使用等长自然混淆模式（标准）")
 			defaultPatterns := []string{
 				"main.",
 				"runtime.",
@@ -460,7 +559,7 @@ func (lo *LinkerObfuscator) obfuscateFunctionNames(data []byte, pclntabOffset in
 				"net.",
 				"http.",
 			}
-			
+
 			for _, pattern := range defaultPatterns {
 				// 生成等长的自然名称
 				replacement := nameGen.GeneratePackageName(pattern, len(pattern))
@@ -468,96 +567,109 @@ func (lo *LinkerObfuscator) obfuscateFunctionNames(data []byte, pclntabOffset in
 				replacements = append(replacements, replacement)
 			}
 		}
-		
-		fmt.Println("   等长替换映射:")
+
+		lo.logf("   等长替换映射:")
 		for i, pattern := range patterns {
-			fmt.Printf("     %s -> %s\n", pattern, replacements[i])
+			lo.logf("     %s -> %s\n", pattern, replacements[i])
 		}
 	}
-	
+
+	// 解析二进制布局并收集 //go:embed 数据所在区间，这些区间永远不会被修改
+	layout, err := parseBinaryLayout(data)
+	if err != nil {
+		return err
+	}
+	embedRanges := lo.collectEmbedDataRanges(data)
+	if len(embedRanges) > 0 {
+		lo.logf("   已排除 %d 个 //go:embed 数据区间\n", len(embedRanges))
+	}
+
 	count := 0
 	replacedPatterns := make(map[string]int)
-	
+
 	// 第一阶段：替换 "包名." 模式（函数名）
-	// 策略：只在 pclntab 区域内替换，避免破坏 embed 文件内容
-	// pclntab 区域通常在文件的特定位置，我们需要更精确的定位
-	
-	// 估算 pclntab 的大小（通常不超过几MB）
-	// 为了安全，我们只在 pclntabOffset 后的合理范围内搜索
-	pclntabSearchEnd := int(pclntabOffset) + 10*1024*1024 // 10MB 应该足够大
-	if pclntabSearchEnd > len(data) {
-		pclntabSearchEnd = len(data)
-	}
-	
-	for i, pattern := range patterns {
-		if i >= len(replacements) {
-			break
+	// 只在 pclntab 的函数名表内替换，不会触及只读数据中的字符串常量和 embed 文件内容
+	var funcnameRegions []byteRange
+	for _, r := range layout.pclntabRegions(data) {
+		if r.Name == "pclntab.funcnametab" {
+			funcnameRegions = append(funcnameRegions, r)
 		}
-		
+	}
+	funcnameRegions = subtractRanges(funcnameRegions, embedRanges)
+	if len(funcnameRegions) == 0 {
+		lo.logf("   ⚠️  未能定位 pclntab 函数名表，跳过函数名前缀替换")
+	}
+
+	for i, pattern := range patterns {
 		patternBytes := []byte(pattern)
 		replacement := []byte(replacements[i])
+		if len(replacement) != len(patternBytes) {
+			continue // 只做等长替换
+		}
 		patternCount := 0
-		
-		// 只在 pclntab 区域内查找并替换
-		for j := int(pclntabOffset); j < pclntabSearchEnd-len(patternBytes); j++ {
-			if bytes.Equal(data[j:j+len(patternBytes)], patternBytes) {
+
+		for _, r := range funcnameRegions {
+			for j := r.Start; j+len(patternBytes) <= r.End; {
+				idx := bytes.Index(data[j:r.End], patternBytes)
+				if idx < 0 {
+					break
+				}
+				pos := j + idx
+				j = pos + 1
 				// 更严格的上下文检查
-				if !lo.isSafeFunctionNamePrefix(data, j, patternBytes) {
+				if !lo.isSafeFunctionNamePrefix(data, pos, patternBytes) {
 					continue
 				}
-				
-				// ⭐ 等长替换：确保替换字符串与原字符串长度完全相同
-				if len(replacement) == len(patternBytes) {
-					// 直接替换，无需填充
-					copy(data[j:j+len(replacement)], replacement)
-					count++
-					patternCount++
-				} else if len(replacement) < len(patternBytes) {
-					// 如果替换字符串较短（不应该发生，但作为保护）
-					// 使用原始字符串填充方式而不是 0x00
-					copy(data[j:j+len(replacement)], replacement)
-					// 不填充，保持原有字符（更安全）
-					count++
-					patternCount++
-				}
-				// 忽略替换字符串过长的情况
+				copy(data[pos:pos+len(replacement)], replacement)
+				count++
+				patternCount++
+				j = pos + len(patternBytes)
 			}
 		}
-		
+
 		if patternCount > 0 {
 			replacedPatterns[pattern] = patternCount
+			lo.applied[pattern] = appliedReplacement{Replacement: replacements[i], Count: patternCount}
 		}
 	}
-	
-	// 替换项目包路径（在整个二进制文件中替换，但有严格的安全检查）
-	fmt.Println("   替换项目包路径...")
-	pathCount := lo.replaceProjectPackagePathsGlobal(data)
-	
+
+	// 替换项目包路径（只在 pclntab、类型名、buildinfo 等已知字符串区域内等长替换）
+	lo.logf("   替换项目包路径...")
+	pathCount := lo.replaceProjectPackagePathsInRegions(data, subtractRanges(layout.stringRegions(data), embedRanges), resolved)
+
 	if count > 0 {
-		fmt.Printf("   ✅ 替换了 %d 个函数名前缀:\n", count)
+		lo.logf("   ✅ 替换了 %d 个函数名前缀:\n", count)
 		for pattern, cnt := range replacedPatterns {
-			fmt.Printf("      %s: %d 次\n", pattern, cnt)
+			lo.logf("      %s: %d 次\n", pattern, cnt)
 		}
 	} else {
-		fmt.Println("   ⚠️  未找到匹配的包名前缀")
+		lo.logf("   ⚠️  未找到匹配的包名前缀")
 	}
-	
+
 	if pathCount > 0 {
-		fmt.Printf("   ✅ 替换了 %d 个项目包路径引用\n", pathCount)
+		lo.logf("   ✅ 替换了 %d 个项目包路径引用\n", pathCount)
 	}
-	
+
 	return nil
 }
 
-// replaceProjectPackagePathsGlobal 在整个二进制文件中替换项目包路径（全局版本，带严格安全检查）
-func (lo *LinkerObfuscator) replaceProjectPackagePathsGlobal(data []byte) int {
+// replaceProjectPackagePathsInRegions 在已知的字符串区域内等长替换项目包路径
+// 区域由节表解析得到：pclntab 的函数名表与文件表、类型描述区、buildinfo，
+// 调用方已剔除 //go:embed 数据所在区间，因此不会触及嵌入文件和普通字符串常量
+// resolved 为 equalLengthReplacements 确定的等长替换名
+func (lo *LinkerObfuscator) replaceProjectPackagePathsInRegions(data []byte, regions []byteRange, resolved map[string]string) int {
 	if len(lo.config.PackageReplacements) == 0 {
 		return 0
 	}
-	
-	count := 0
-	replacedPaths := make(map[string]int)
-	
+
+	if len(regions) == 0 {
+		lo.logf("   ⚠️  未能解析出字符串区域，跳过包路径替换")
+		return 0
+	}
+	for _, r := range regions {
+		lo.logf("   字符串区域 %s: 0x%x - 0x%x\n", r.Name, r.Start, r.End)
+	}
+
 	// 标准库包名列表（这些不进行路径替换，只替换函数名前缀）
 	standardLibs := map[string]bool{
 		"main": true, "runtime": true, "sync": true, "fmt": true,
@@ -568,113 +680,216 @@ func (lo *LinkerObfuscator) replaceProjectPackagePathsGlobal(data []byte) int {
 		"hex": true, "unicode": true, "regexp": true, "log": true,
 		"sort": true, "path": true, "filepath": true, "syscall": true,
 	}
-	
-	// 对每个包路径进行替换
-	for original, replacement := range lo.config.PackageReplacements {
-		// 移除尾部的 "." 如果有的话
+
+	// 只替换包含 "/" 的路径（项目包路径），按长度降序处理，确保子包先被替换
+	var paths []string
+	for original := range lo.config.PackageReplacements {
 		originalPath := strings.TrimSuffix(original, ".")
-		
-		// 跳过标准库包（标准库包名太短，可能误替换系统符号）
-		if standardLibs[originalPath] {
+		if standardLibs[originalPath] || !strings.Contains(originalPath, "/") {
 			continue
 		}
-		
-		// 只替换包含 "/" 的路径（项目包路径）
-		// 这样可以避免替换单个单词，减少误替换风险
-		if !strings.Contains(originalPath, "/") {
-			continue
+		paths = append(paths, originalPath)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		if len(paths[i]) != len(paths[j]) {
+			return len(paths[i]) > len(paths[j])
 		}
-		
-		replacementPath := strings.TrimSuffix(replacement, ".")
+		return paths[i] < paths[j]
+	})
+
+	count := 0
+	replacedPaths := make(map[string]int)
+
+	for _, originalPath := range paths {
 		patternBytes := []byte(originalPath)
-		replacementBytes := []byte(replacementPath)
-		
-		// 在整个二进制文件中搜索并替换，但要进行严格的安全检查
-		for j := 0; j < len(data)-len(patternBytes); j++ {
-			if bytes.Equal(data[j:j+len(patternBytes)], patternBytes) {
-				// 严格的安全检查
-				if !lo.isSafePackagePathReplacement(data, j, len(patternBytes)) {
-					continue
+		replacementBytes := []byte(resolved[originalPath])
+
+		for _, r := range regions {
+			for j := r.Start; j+len(patternBytes) <= r.End; {
+				idx := bytes.Index(data[j:r.End], patternBytes)
+				if idx < 0 {
+					break
 				}
-				
-				// 只有当替换字符串不长于原字符串时才替换
-				if len(replacementBytes) <= len(patternBytes) {
-					copy(data[j:j+len(replacementBytes)], replacementBytes)
-					// 用空字节填充剩余部分
-					for k := j + len(replacementBytes); k < j + len(patternBytes); k++ {
-						data[k] = 0
-					}
+				pos := j + idx
+				if isPackagePathBoundary(data, pos, len(patternBytes), r.Name == "types") {
+					copy(data[pos:pos+len(replacementBytes)], replacementBytes)
 					count++
 					replacedPaths[originalPath]++
-					// 跳过已替换的部分
-					j += len(patternBytes) - 1
+					j = pos + len(patternBytes)
+				} else {
+					j = pos + 1
 				}
 			}
 		}
+		if cnt := replacedPaths[originalPath]; cnt > 0 {
+			lo.applied[originalPath] = appliedReplacement{Replacement: string(replacementBytes), Count: cnt}
+		}
 	}
-	
+
 	if count > 0 {
-		fmt.Printf("   ✅ 替换了 %d 个包路径:\n", count)
-		for path, cnt := range replacedPaths {
-			fmt.Printf("      %s: %d 次\n", path, cnt)
+		lo.logf("   ✅ 替换了 %d 个包路径:\n", count)
+		for _, path := range paths {
+			if cnt := replacedPaths[path]; cnt > 0 {
+				lo.logf("      %s: %d 次\n", path, cnt)
+			}
 		}
 	}
-	
+
 	return count
 }
 
-// isSafePackagePathReplacement 检查是否可以安全地替换包路径
-// 这个检查比 isSafeToReplace 更宽松，因为包路径可能出现在多种上下文中
-func (lo *LinkerObfuscator) isSafePackagePathReplacement(data []byte, pos int, length int) bool {
-	// 不检查系统路径（因为包路径通常包含特殊域名如 github.com）
-	// 只检查是否在合理的上下文中
-	
-	// 检查前面是否是合理的分隔符或开始
+// equalLengthReplacements 把 PackageReplacements 中的每个替换名调整为与原包名等长：
+// 过长时截断，过短时补充字母，与其他包的替换名冲突时重新补充，多次冲突后按顺序取未使用的字母组合。
+// 键为去掉末尾 "." 的原包名；该长度的名称全部用尽时返回错误
+func (lo *LinkerObfuscator) equalLengthReplacements(nameGen *NaturalNameGenerator) (map[string]string, error) {
+	originals := make([]string, 0, len(lo.config.PackageReplacements))
+	for original := range lo.config.PackageReplacements {
+		originals = append(originals, original)
+	}
+	sort.Strings(originals)
+
+	resolved := make(map[string]string, len(originals))
+	used := make(map[string]bool)
+	for _, original := range originals {
+		name := strings.TrimSuffix(original, ".")
+		base := strings.TrimSuffix(lo.config.PackageReplacements[original], ".")
+		if len(base) > len(name) {
+			base = base[:len(name)]
+		}
+		candidate := nameGen.padWithLetters(base, len(name))
+		for attempt := 0; used[candidate] && attempt < 100; attempt++ {
+			// 截断后冲突时去掉末尾一个字符，留出补充字母的位置
+			if len(base) == len(name) && len(base) > 0 {
+				base = base[:len(base)-1]
+			}
+			candidate = nameGen.padWithLetters(base, len(name))
+		}
+		if used[candidate] {
+			var ok bool
+			if candidate, ok = unusedLetters(len(name), used); !ok {
+				return nil, fmt.Errorf("包 %s 没有可用的等长替换名（长度 %d 的名称已全部使用）", name, len(name))
+			}
+		}
+		used[candidate] = true
+		resolved[name] = candidate
+	}
+	return resolved, nil
+}
+
+// unusedLetters 按字典序返回第一个未使用的、长度为 length 的小写字母组合
+func unusedLetters(length int, used map[string]bool) (string, bool) {
+	buf := make([]byte, length)
+	// 前 len(used)+1 个组合中必有一个未使用
+	for i := 0; i <= len(used); i++ {
+		n := i
+		for j := length - 1; j >= 0; j-- {
+			buf[j] = 'a' + byte(n%26)
+			n /= 26
+		}
+		if n > 0 {
+			return "", false // 该长度的组合已全部枚举
+		}
+		if !used[string(buf)] {
+			return string(buf), true
+		}
+	}
+	return "", false
+}
+
+// collectEmbedDataRanges 定位 //go:embed 嵌入文件的内容在二进制中的区间
+func (lo *LinkerObfuscator) collectEmbedDataRanges(data []byte) []byteRange {
+	// 源码混淆阶段传入的嵌入文件和构建目录中扫描到的嵌入文件合并去重
+	files := findEmbeddedFiles(lo.projectDir)
+	for _, rel := range lo.config.EmbeddedFiles {
+		files = append(files, filepath.Join(lo.projectDir, filepath.FromSlash(rel)))
+	}
+	seen := make(map[string]bool)
+
+	var ranges []byteRange
+	for _, file := range files {
+		if abs, err := filepath.Abs(file); err == nil {
+			if seen[abs] {
+				continue
+			}
+			seen[abs] = true
+		}
+		content, err := os.ReadFile(file)
+		if err != nil || len(content) < minEmbedMatchLength {
+			continue
+		}
+		name := file
+		if rel, err := filepath.Rel(lo.projectDir, file); err == nil {
+			name = filepath.ToSlash(rel)
+		}
+		for start := 0; start < len(data); {
+			idx := bytes.Index(data[start:], content)
+			if idx < 0 {
+				break
+			}
+			ranges = append(ranges, byteRange{Name: "embed:" + name, Start: start + idx, End: start + idx + len(content)})
+			start += idx + len(content)
+		}
+	}
+	return ranges
+}
+
+// isPackagePathBoundary 检查匹配位置两侧是否是包路径的边界
+// 前一个字符不能是路径字符（否则是更长路径的一部分），
+// 后一个字符不能是标识符字符（否则是同名前缀的其它包，如 project2）
+// 类型描述区中的名称以 "标志字节 + varint 长度" 开头，长度字节可能恰好是可打印字符，需单独识别
+func isPackagePathBoundary(data []byte, pos int, length int, lengthPrefixed bool) bool {
 	if pos > 0 {
-		prevChar := data[pos-1]
-		// 放宽检查：只拒绝明确是标识符一部分的字符（字母、下划线）
-		// 数字前缀是允许的（如 "0github.com/..."），这些是 Go 编译器添加的标记
-		// 拒绝的字符：字母、下划线（说明是某个标识符的一部分）
-		if (prevChar >= 'a' && prevChar <= 'z') ||
-		   (prevChar >= 'A' && prevChar <= 'Z') ||
-		   prevChar == '_' {
-			return false
+		prev := data[pos-1]
+		if isIdentByte(prev) || prev == '.' || prev == '/' || prev == '-' {
+			if !lengthPrefixed || !hasNameLengthPrefix(data, pos, length) {
+				return false
+			}
 		}
-		// 数字、分隔符和不可打印字符都允许
 	}
-	
-	// 检查后面的字符
 	if pos+length < len(data) {
-		nextChar := data[pos+length]
-		// 放宽检查：只拒绝明确是标识符一部分的字符
-		// 拒绝的字符：字母、数字、下划线（说明是某个标识符的一部分）
-		// 连字符不拒绝，因为包路径后面可能跟版本号
-		if (nextChar >= 'a' && nextChar <= 'z') ||
-		   (nextChar >= 'A' && nextChar <= 'Z') ||
-		   (nextChar >= '0' && nextChar <= '9') ||
-		   nextChar == '_' {
+		next := data[pos+length]
+		if isIdentByte(next) || next == '-' {
 			return false
 		}
-		// 其他字符都允许（包括 /、.、-、空格、null、引号等）
 	}
-	
 	return true
 }
 
+// hasNameLengthPrefix 检查 pos 处是否是一个 runtime name 的开头：
+// 前面是 1~2 字节的 varint 长度（不小于匹配长度），再往前是取值不超过 0x0f 的标志字节
+func hasNameLengthPrefix(data []byte, pos int, length int) bool {
+	for width := 1; width <= 2; width++ {
+		if pos-width-1 < 0 {
+			return false
+		}
+		n, w := binary.Uvarint(data[pos-width : pos])
+		if w != width || int(n) < length || pos+int(n) > len(data) {
+			continue
+		}
+		if data[pos-width-1] <= 0x0f {
+			return true
+		}
+	}
+	return false
+}
 
+// isIdentByte 判断字节是否是标识符字符
+func isIdentByte(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9') || b == '_'
+}
 
 // replaceBytesStrict 严格的字节替换，用于包路径替换
 func (lo *LinkerObfuscator) replaceBytesStrict(data []byte, pattern []byte, replacement []byte) int {
 	if len(replacement) > len(pattern) {
 		return 0
 	}
-	
+
 	// 额外检查：pattern 必须足够长（至少 10 个字符）
 	// 避免替换短字符串
 	if len(pattern) < 10 {
 		return 0
 	}
-	
+
 	count := 0
 	for i := 0; i < len(data)-len(pattern); i++ {
 		if bytes.Equal(data[i:i+len(pattern)], pattern) {
@@ -682,7 +897,7 @@ func (lo *LinkerObfuscator) replaceBytesStrict(data []byte, pattern []byte, repl
 			if !lo.isSafeToReplaceStrict(data, i, len(pattern)) {
 				continue
 			}
-			
+
 			// 执行替换
 			copy(data[i:i+len(replacement)], replacement)
 			for j := i + len(replacement); j < i+len(pattern); j++ {
@@ -692,7 +907,7 @@ func (lo *LinkerObfuscator) replaceBytesStrict(data []byte, pattern []byte, repl
 			i += len(pattern) - 1
 		}
 	}
-	
+
 	return count
 }
 
@@ -702,7 +917,7 @@ func (lo *LinkerObfuscator) isSafeToReplaceStrict(data []byte, pos int, length i
 	if !lo.isSafeToReplace(data, pos, length) {
 		return false
 	}
-	
+
 	// 额外检查：确保前后都是合理的字符
 	// 检查前一个字符
 	if pos > 0 {
@@ -715,7 +930,7 @@ func (lo *LinkerObfuscator) isSafeToReplaceStrict(data []byte, pos int, length i
 			return false
 		}
 	}
-	
+
 	// 检查后一个字符
 	if pos+length < len(data) {
 		nextChar := data[pos+length]
@@ -727,7 +942,7 @@ func (lo *LinkerObfuscator) isSafeToReplaceStrict(data []byte, pos int, length i
 			return false
 		}
 	}
-	
+
 	return true
 }
 
@@ -739,9 +954,9 @@ func (lo *LinkerObfuscator) isSafeToReplace(data []byte, pos int, length int) bo
 	if contextStart < 0 {
 		contextStart = 0
 	}
-	
+
 	contextBefore := data[contextStart:pos]
-	
+
 	// 危险模式：系统路径
 	dangerousPatterns := [][]byte{
 		[]byte("/System/Library/"),
@@ -752,34 +967,34 @@ func (lo *LinkerObfuscator) isSafeToReplace(data []byte, pos int, length int) bo
 		[]byte(".dylib"),
 		[]byte("/Cryptexes/"),
 	}
-	
+
 	// 检查前面的上下文是否包含危险模式
 	for _, dangerous := range dangerousPatterns {
 		if bytes.Contains(contextBefore, dangerous) {
 			return false
 		}
 	}
-	
+
 	// 检查后面的上下文（最多往后看 50 字节）
 	contextEnd := pos + length + 50
 	if contextEnd > len(data) {
 		contextEnd = len(data)
 	}
-	
+
 	contextAfter := data[pos+length : contextEnd]
-	
+
 	// 检查后面是否紧跟着系统路径特征
 	afterDangerousPatterns := [][]byte{
 		[]byte(".framework"),
 		[]byte(".dylib"),
 	}
-	
+
 	for _, dangerous := range afterDangerousPatterns {
 		if bytes.HasPrefix(contextAfter, dangerous) {
 			return false
 		}
 	}
-	
+
 	return true
 }
 
@@ -790,72 +1005,72 @@ func (lo *LinkerObfuscator) discoverAndGeneratePackageReplacements() error {
 	if err != nil {
 		return fmt.Errorf("无法读取模块名: %v", err)
 	}
-	
+
 	if moduleName == "" {
 		return fmt.Errorf("go.mod 中未找到模块名")
 	}
-	
-	fmt.Printf("   发现模块名: %s\n", moduleName)
-	
+
+	lo.logf("   发现模块名: %s\n", moduleName)
+
 	// 2. 扫描项目目录，查找所有子包
 	packages, err := lo.discoverProjectPackages(moduleName)
 	if err != nil {
 		return fmt.Errorf("扫描项目包失败: %v", err)
 	}
-	
+
 	if len(packages) == 0 {
 		return fmt.Errorf("未发现任何项目包")
 	}
-	
-	fmt.Printf("   发现 %d 个项目包:\n", len(packages))
+
+	lo.logf("   发现 %d 个项目包:\n", len(packages))
 	for _, pkg := range packages {
-		fmt.Printf("     - %s\n", pkg)
+		lo.logf("     - %s\n", pkg)
 	}
-	
+
 	// 3. 添加常见的标准库包名
 	standardPackages := lo.getStandardPackages()
-	fmt.Printf("   添加 %d 个标准库包\n", len(standardPackages))
-	
+	lo.logf("   添加 %d 个标准库包\n", len(standardPackages))
+
 	// 4. 合并项目包和标准库包（项目包优先，确保子包在前）
 	allPackages := append(packages, standardPackages...)
-	
+
 	// 5. 如果启用第三方包混淆，发现并添加第三方包
 	var thirdPartyPackages []string
 	if lo.config.ObfuscateThirdParty {
 		thirdPartyPackages, err = lo.discoverThirdPartyPackages(moduleName)
 		if err != nil {
-			fmt.Printf("   ⚠️  发现第三方包失败: %v\n", err)
+			lo.logf("   ⚠️  发现第三方包失败: %v\n", err)
 		} else {
-			fmt.Printf("   发现 %d 个第三方包（包括子包）:\n", len(thirdPartyPackages))
+			lo.logf("   发现 %d 个第三方包（包括子包）:\n", len(thirdPartyPackages))
 			// 显示前 10 个包
 			displayCount := 10
 			if len(thirdPartyPackages) < displayCount {
 				displayCount = len(thirdPartyPackages)
 			}
 			for i := 0; i < displayCount; i++ {
-				fmt.Printf("     - %s\n", thirdPartyPackages[i])
+				lo.logf("     - %s\n", thirdPartyPackages[i])
 			}
 			if len(thirdPartyPackages) > displayCount {
-				fmt.Printf("     - ... 还有 %d 个\n", len(thirdPartyPackages)-displayCount)
+				lo.logf("     - ... 还有 %d 个\n", len(thirdPartyPackages)-displayCount)
 			}
 			allPackages = append(allPackages, thirdPartyPackages...)
 		}
 	}
-	
+
 	// 6. 生成替换映射
 	replacements := lo.generateReplacements(allPackages)
-	
+
 	// 7. 应用替换映射
 	lo.config.PackageReplacements = replacements
-	
+
 	if lo.config.ObfuscateThirdParty {
-		fmt.Printf("   ✅ 生成了 %d 个包名替换映射 (项目包: %d, 标准库: %d, 第三方: %d)\n", 
+		lo.logf("   ✅ 生成了 %d 个包名替换映射 (项目包: %d, 标准库: %d, 第三方: %d)\n",
 			len(replacements), len(packages), len(standardPackages), len(thirdPartyPackages))
 	} else {
-		fmt.Printf("   ✅ 生成了 %d 个包名替换映射 (项目包: %d, 标准库: %d)\n", 
+		lo.logf("   ✅ 生成了 %d 个包名替换映射 (项目包: %d, 标准库: %d)\n",
 			len(replacements), len(packages), len(standardPackages))
 	}
-	
+
 	return nil
 }
 
@@ -868,64 +1083,64 @@ func (lo *LinkerObfuscator) getStandardPackages() []string {
 		"main",
 		"runtime",
 		"sync",
-		"syscall",  // 系统调用，函数名前缀可以安全替换
-		
+		"syscall", // 系统调用，函数名前缀可以安全替换
+
 		// I/O 和格式化（安全）
 		"fmt",
 		"io",
 		"bufio",
 		"os",
 		"log",
-		
+
 		// 网络相关（安全）
 		"net",
-		"http",  // 实际是 net/http，但在符号表中可能显示为 http
-		
+		"http", // 实际是 net/http，但在符号表中可能显示为 http
+
 		// 字符串和数据处理（安全）
 		"strings",
 		"bytes",
 		"strconv",
 		"unicode",
 		"regexp",
-		
+
 		// 编码（安全）
 		"encoding",
-		"json",    // encoding/json
-		"xml",     // encoding/xml
-		"base64",  // encoding/base64
-		"hex",     // encoding/hex
-		
+		"json",   // encoding/json
+		"xml",    // encoding/xml
+		"base64", // encoding/base64
+		"hex",    // encoding/hex
+
 		// 时间和数学（安全）
 		"time",
 		"math",
-		
+
 		// 容器和算法（安全）
 		"sort",
 		"container",
 		"list",
 		"heap",
-		
+
 		// 路径处理（安全）
 		"path",
 		"filepath",
-		
+
 		// 错误处理（安全）
 		"errors",
-		
+
 		// 上下文（安全）
 		"context",
-		
+
 		// 压缩（安全）
 		"compress",
 		"gzip",
 		"zlib",
-		
+
 		// 哈希（安全）
 		"hash",
 		"crc32",
 		"crc64",
 		"fnv",
-		
+
 		// 注意：以下包不包含，因为可能影响程序
 		// - reflect: 反射包，可能依赖包名
 		// - unsafe: 不安全操作
@@ -937,85 +1152,101 @@ func (lo *LinkerObfuscator) getStandardPackages() []string {
 
 // getModuleName 从 go.mod 文件中读取模块名
 func (lo *LinkerObfuscator) getModuleName() (string, error) {
-	goModPath := filepath.Join(lo.projectDir, "go.mod")
-	
-	data, err := ioutil.ReadFile(goModPath)
+	return readModulePath(lo.projectDir)
+}
+
+// readModulePath 从目录下的 go.mod 文件中读取模块路径
+func readModulePath(dir string) (string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
 	if err != nil {
 		return "", err
 	}
-	
+
 	// 使用正则表达式匹配 module 行
 	re := regexp.MustCompile(`(?m)^module\s+([^\s]+)`)
 	matches := re.FindSubmatch(data)
-	
+
 	if len(matches) < 2 {
 		return "", fmt.Errorf("go.mod 中未找到 module 声明")
 	}
-	
+
 	return string(matches[1]), nil
 }
 
 // discoverProjectPackages 扫描项目目录，发现所有子包
 func (lo *LinkerObfuscator) discoverProjectPackages(moduleName string) ([]string, error) {
 	packages := make(map[string]bool)
-	
+
 	// 添加主模块
 	packages[moduleName] = true
-	
+
 	// 遍历项目目录
 	err := filepath.Walk(lo.projectDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		
+
 		// 跳过非目录
 		if !info.IsDir() {
 			return nil
 		}
-		
+
 		// 跳过隐藏目录、vendor、测试数据等
 		dirName := info.Name()
-		if strings.HasPrefix(dirName, ".") || 
-		   dirName == "vendor" || 
-		   dirName == "testdata" ||
-		   dirName == "node_modules" {
+		if strings.HasPrefix(dirName, ".") ||
+			dirName == "vendor" ||
+			dirName == "testdata" ||
+			dirName == "node_modules" {
 			return filepath.SkipDir
 		}
-		
+
 		// 检查目录中是否有 .go 文件
 		hasGoFiles, err := lo.hasGoFiles(path)
 		if err != nil || !hasGoFiles {
 			return nil
 		}
-		
+
 		// 计算相对路径
 		relPath, err := filepath.Rel(lo.projectDir, path)
 		if err != nil {
 			return nil
 		}
-		
+
 		// 跳过根目录（已经添加了主模块）
 		if relPath == "." {
 			return nil
 		}
-		
+
 		// 构建完整包路径
 		pkgPath := moduleName + "/" + filepath.ToSlash(relPath)
 		packages[pkgPath] = true
-		
+
 		return nil
 	})
-	
+
 	if err != nil {
 		return nil, err
 	}
-	
+
+	// overlay 中新增的包（例如解密包）不在项目目录中
+	if lo.config.Overlay != "" {
+		dirs, err := overlayDirs(lo.config.Overlay, lo.projectDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, dir := range dirs {
+			if dir != "." {
+				packages[moduleName+"/"+filepath.ToSlash(dir)] = true
+			}
+		}
+	}
+
 	// 转换为切片并排序（长的包名在前，避免替换冲突）
 	result := make([]string, 0, len(packages))
 	for pkg := range packages {
 		result = append(result, pkg)
 	}
-	
+
 	// 按长度降序排序，确保子包先被替换
 	for i := 0; i < len(result); i++ {
 		for j := i + 1; j < len(result); j++ {
@@ -1024,7 +1255,7 @@ func (lo *LinkerObfuscator) discoverProjectPackages(moduleName string) ([]string
 			}
 		}
 	}
-	
+
 	return result, nil
 }
 
@@ -1034,7 +1265,7 @@ func (lo *LinkerObfuscator) hasGoFiles(dir string) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	
+
 	for _, file := range files {
 		if !file.IsDir() && strings.HasSuffix(file.Name(), ".go") {
 			// 排除测试文件
@@ -1043,14 +1274,14 @@ func (lo *LinkerObfuscator) hasGoFiles(dir string) (bool, error) {
 			}
 		}
 	}
-	
+
 	return false, nil
 }
 
 // generateReplacements 为包名生成短替换名
 func (lo *LinkerObfuscator) generateReplacements(packages []string) map[string]string {
 	replacements := make(map[string]string)
-	
+
 	// 生成简短的替换名
 	counter := 0
 	for _, pkg := range packages {
@@ -1059,7 +1290,7 @@ func (lo *LinkerObfuscator) generateReplacements(packages []string) map[string]s
 		replacements[pkg] = replacement
 		counter++
 	}
-	
+
 	return replacements
 }
 
@@ -1068,9 +1299,9 @@ func (lo *LinkerObfuscator) generateShortName(index int) string {
 	if index < 26 {
 		return string(rune('a' + index))
 	}
-	
+
 	// 对于超过26的，使用两个字母
-	first := index / 26 - 1
+	first := index/26 - 1
 	second := index % 26
 	return string(rune('a'+first)) + string(rune('a'+second))
 }
@@ -1084,101 +1315,101 @@ func (lo *LinkerObfuscator) isSafeFunctionNamePrefix(data []byte, pos int, patte
 	// 检查前一个字符
 	if pos > 0 {
 		prevChar := data[pos-1]
-		
+
 		// 允许的前置字符：
 		// - 空字节 (0x00)
 		// - 不可打印字符 (< 0x20，除了空格)
 		// - 路径分隔符 (/)
-		// 
+		//
 		// 不允许的前置字符：
 		// - 字母、数字（说明是某个标识符的一部分）
 		// - 点号（说明是包路径的一部分，如 commons.io.）
 		// - 其他可打印字符（说明可能是文本内容）
-		
+
 		if prevChar == 0 {
 			// 空字节，安全
 			return true
 		}
-		
+
 		if prevChar < 0x20 && prevChar != ' ' {
 			// 不可打印字符（除了空格），安全
 			return true
 		}
-		
+
 		// 如果是字母、数字、点号、斜杠、下划线、连字符，不安全
 		if (prevChar >= 'a' && prevChar <= 'z') ||
-		   (prevChar >= 'A' && prevChar <= 'Z') ||
-		   (prevChar >= '0' && prevChar <= '9') ||
-		   prevChar == '.' ||
-		   prevChar == '/' ||
-		   prevChar == '_' ||
-		   prevChar == '-' {
+			(prevChar >= 'A' && prevChar <= 'Z') ||
+			(prevChar >= '0' && prevChar <= '9') ||
+			prevChar == '.' ||
+			prevChar == '/' ||
+			prevChar == '_' ||
+			prevChar == '-' {
 			return false
 		}
 	}
-	
+
 	// 检查后一个字符（在点号之后）
 	// 函数名前缀后面应该是大写字母（导出函数）或小写字母（未导出函数）
 	dotPos := bytes.IndexByte(pattern, '.')
 	if dotPos >= 0 && pos+len(pattern) < len(data) {
 		nextChar := data[pos+len(pattern)]
-		
+
 		// 函数名后面通常是：
 		// - 大写或小写字母（函数名开始）
 		// - 空字节（字符串结束）
 		if nextChar == 0 {
 			return true
 		}
-		
+
 		if (nextChar >= 'a' && nextChar <= 'z') ||
-		   (nextChar >= 'A' && nextChar <= 'Z') {
+			(nextChar >= 'A' && nextChar <= 'Z') {
 			return true
 		}
-		
+
 		// 其他字符，可能不是函数名
 		return false
 	}
-	
+
 	return true
 }
 
 // discoverThirdPartyPackages 发现第三方依赖包
 func (lo *LinkerObfuscator) discoverThirdPartyPackages(moduleName string) ([]string, error) {
 	packages := make(map[string]bool)
-	
+
 	// 读取 go.mod 文件
 	goModPath := filepath.Join(lo.projectDir, "go.mod")
 	data, err := ioutil.ReadFile(goModPath)
 	if err != nil {
 		return nil, err
 	}
-	
+
 	// 改进的正则表达式：匹配所有 require 行（包括 indirect 注释）
 	// 匹配格式: github.com/xxx/yyy v1.2.3 或 github.com/xxx/yyy v1.2.3 // indirect
 	requireRe := regexp.MustCompile(`(?m)^\s*([a-zA-Z0-9\-_\.]+/[a-zA-Z0-9\-_\./]+?)\s+v[^\s]+`)
 	requireMatches := requireRe.FindAllSubmatch(data, -1)
-	
+
 	// 同时匹配 replace 指令，格式: replace github.com/xxx/yyy => github.com/aaa/bbb v1.2.3
 	replaceRe := regexp.MustCompile(`(?m)^replace\s+([a-zA-Z0-9\-_\.]+/[a-zA-Z0-9\-_\./]+)\s+[^\n]*=>\s+([a-zA-Z0-9\-_\.]+/[a-zA-Z0-9\-_\./]+)\s+v`)
 	replaceMatches := replaceRe.FindAllSubmatch(data, -1)
-	
+
 	// 处理 require 的包
 	for _, match := range requireMatches {
 		if len(match) >= 2 {
 			pkgPath := string(match[1])
-			
+
 			// 排除标准库（不包含域名）
 			if !strings.Contains(pkgPath, ".") {
 				continue
 			}
-			
+
 			// 排除项目自身
 			if strings.HasPrefix(pkgPath, moduleName) {
 				continue
 			}
-			
+
 			packages[pkgPath] = true
-			
+
 			// 对于有子包的路径（如 github.com/antlr/antlr4/runtime/Go/antlr）
 			// 也添加其父级路径，以便能匹配更多变体
 			parts := strings.Split(pkgPath, "/")
@@ -1186,26 +1417,26 @@ func (lo *LinkerObfuscator) discoverThirdPartyPackages(moduleName string) ([]str
 				// 添加顶层包路径，例如 github.com/antlr/antlr4
 				topLevel := strings.Join(parts[:3], "/")
 				packages[topLevel] = true
-				
+
 				// 添加中间路径，例如 github.com/antlr/antlr4/runtime
 				for i := 3; i < len(parts); i++ {
 					midLevel := strings.Join(parts[:i+1], "/")
 					packages[midLevel] = true
 				}
 			}
-			
+
 			// 添加可能的 internal 子包路径
 			// 例如: github.com/xxx/yyy/internal, github.com/xxx/yyy/internal/pkg
 			lo.addCommonSubPackages(pkgPath, packages)
 		}
 	}
-	
+
 	// 处理 replace 指令中的包（原始包和替换后的包都添加）
 	for _, match := range replaceMatches {
 		if len(match) >= 3 {
 			originalPkg := string(match[1])
 			replacementPkg := string(match[2])
-			
+
 			// 处理原始包
 			if strings.Contains(originalPkg, ".") && !strings.HasPrefix(originalPkg, moduleName) {
 				packages[originalPkg] = true
@@ -1217,7 +1448,7 @@ func (lo *LinkerObfuscator) discoverThirdPartyPackages(moduleName string) ([]str
 				}
 				lo.addCommonSubPackages(originalPkg, packages)
 			}
-			
+
 			// 处理替换后的包
 			if strings.Contains(replacementPkg, ".") && !strings.HasPrefix(replacementPkg, moduleName) {
 				packages[replacementPkg] = true
@@ -1231,18 +1462,18 @@ func (lo *LinkerObfuscator) discoverThirdPartyPackages(moduleName string) ([]str
 			}
 		}
 	}
-	
+
 	// 转换为切片并排序
 	result := make([]string, 0, len(packages))
 	for pkg := range packages {
 		result = append(result, pkg)
 	}
-	
+
 	// 按长度降序排序（确保子包在前，避免替换冲突）
 	sort.Slice(result, func(i, j int) bool {
 		return len(result[i]) > len(result[j])
 	})
-	
+
 	return result, nil
 }
 
@@ -1262,12 +1493,12 @@ func (lo *LinkerObfuscator) addCommonSubPackages(basePath string, packages map[s
 		"proto",
 		"protobuf",
 	}
-	
+
 	// 为基础路径添加常见子目录
 	for _, subDir := range commonSubDirs {
 		subPath := basePath + "/" + subDir
 		packages[subPath] = true
-		
+
 		// 对于 internal，还要添加一些常见的更深层路径
 		if subDir == "internal" {
 			internalCommon := []string{
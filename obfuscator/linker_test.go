@@ -0,0 +1,104 @@
+package obfuscator_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cross-file-obfuscator/obfuscator"
+)
+
+// TestLinkerRewritesProjectPaths 后处理二进制：项目包路径从函数名表中消失，自检通过，运行结果不变
+func TestLinkerRewritesProjectPaths(t *testing.T) {
+	skipIfShort(t)
+	project := copyTestdata(t, "app")
+	bin := filepath.Join(t.TempDir(), "app")
+
+	lo := obfuscator.NewLinkerObfuscator(project, bin, &obfuscator.LinkConfig{
+		RemoveFuncNames:      true,
+		EntryPackage:         ".",
+		AutoDiscoverPackages: true,
+		SelfCheck:            true,
+	})
+	if err := lo.BuildWithLinkerObfuscation(); err != nil {
+		t.Fatalf("链接器混淆失败: %v", err)
+	}
+
+	data, err := os.ReadFile(bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("example.com/app")) {
+		t.Error("二进制中仍包含项目包路径 example.com/app")
+	}
+	if got := runBinary(t, bin, "gopher"); got != "[hello, GOPHER]\n" {
+		t.Errorf("混淆后的二进制输出 %q", got)
+	}
+}
+
+// TestNewLinkerObfuscatorCopiesConfig 选项只作用于本次构建，不写回调用方的 LinkConfig
+func TestNewLinkerObfuscatorCopiesConfig(t *testing.T) {
+	config := &obfuscator.LinkConfig{UseToolexec: true}
+	obfuscator.NewLinkerObfuscator(".", "app", config, obfuscator.WithSeed("first"))
+	if config.ToolexecSeed != "" || config.EntryPackage != "" {
+		t.Errorf("NewLinkerObfuscator 修改了调用方的配置: %+v", config)
+	}
+}
+
+// TestEqualLengthReplacementsUnique 替换名与原包名等长且互不相同，名称用尽时报错而不是复用
+func TestEqualLengthReplacementsUnique(t *testing.T) {
+	// 全部建议同一个替换名，迫使逐个解决冲突
+	replacements := map[string]string{"example.com/app.": "q"}
+	for c := 'a'; c <= 'z'; c++ {
+		replacements[string(c)+"."] = "q"
+	}
+	resolved, err := obfuscator.EqualLengthReplacements(&obfuscator.LinkConfig{PackageReplacements: replacements})
+	if err != nil {
+		t.Fatalf("26 个单字母包应都能分到替换名: %v", err)
+	}
+	seen := make(map[string]string)
+	for original, replacement := range resolved {
+		if len(replacement) != len(original) {
+			t.Errorf("%s -> %s 长度不同", original, replacement)
+		}
+		if other, ok := seen[replacement]; ok {
+			t.Errorf("%s 和 %s 使用了同一个替换名 %s", original, other, replacement)
+		}
+		seen[replacement] = original
+	}
+
+	// 第 27 个单字符包名已没有可用的等长名称
+	replacements["_."] = "q"
+	if _, err := obfuscator.EqualLengthReplacements(&obfuscator.LinkConfig{PackageReplacements: replacements}); err == nil {
+		t.Error("单字符替换名用尽时应返回错误")
+	}
+}
+
+// TestReplaceProjectPathsSkipsEmbedData //go:embed 文件的内容即使包含项目包路径也不会被替换
+func TestReplaceProjectPathsSkipsEmbedData(t *testing.T) {
+	project := t.TempDir()
+	banner := "served by example.com/app/greet\n"
+	files := map[string]string{
+		"main.go":    "package main\n\nimport _ \"embed\"\n\n//go:embed banner.txt\nvar banner string\n",
+		"banner.txt": banner,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(project, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data := []byte("path example.com/app/greet\x00" + banner + "\x00dep example.com/app/greet\x00")
+	config := &obfuscator.LinkConfig{PackageReplacements: map[string]string{"example.com/app/greet.": "x"}}
+	count, err := obfuscator.ReplaceProjectPaths(project, config, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("嵌入数据之外的 2 处包路径应被替换，实际 %d 处: %q", count, data)
+	}
+	if !bytes.Contains(data, []byte(banner)) {
+		t.Errorf("嵌入文件的内容被修改: %q", data)
+	}
+}
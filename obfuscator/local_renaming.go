@@ -0,0 +1,233 @@
+package obfuscator
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"math/rand/v2"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// localObject 一个按对象单独改名的局部变量或常量
+type localObject struct {
+	name    string
+	newName string
+	decl    identPos
+	uses    []identPos
+	broken  bool // 有引用无法定位到原文件（cgo 文件），整个对象保持原名
+}
+
+// planLocalRenames 按类型检查得到的声明对象为函数内的变量、常量和参数生成独立的混淆名。
+// 保护名单按名称全局生效（结构体字段、方法、外部选择器），而局部对象只能通过自身的标识符引用，
+// 因此即使与受保护的名称同名也可以安全改名
+func (o *Obfuscator) planLocalRenames() {
+	if len(o.typedPkgs) == 0 {
+		return
+	}
+	position := o.sourcePositions()
+	if position == nil {
+		return
+	}
+
+	// 声明位置 -> 局部对象；测试变体和类型 switch 的隐式对象共享同一声明位置
+	locals := make(map[identPos]*localObject)
+	lookup := func(obj types.Object) *localObject {
+		if !isLocalObject(obj) {
+			return nil
+		}
+		decl, ok, mapped := position(obj.Pos(), obj.Name())
+		if !ok || !mapped {
+			return nil
+		}
+		if l, ok := locals[decl]; ok {
+			return l
+		}
+		l := &localObject{name: obj.Name(), decl: decl}
+		locals[decl] = l
+		return l
+	}
+
+	for _, pkg := range o.typedPkgs {
+		info := pkg.TypesInfo
+		bodyless := bodylessParams(pkg.Syntax, info)
+
+		for _, obj := range info.Defs {
+			if obj != nil && !bodyless[obj] {
+				lookup(obj)
+			}
+		}
+		for _, obj := range info.Implicits {
+			lookup(obj)
+		}
+		for id, obj := range info.Uses {
+			if bodyless[obj] {
+				continue
+			}
+			l := lookup(obj)
+			if l == nil {
+				continue
+			}
+			use, ok, mapped := position(id.Pos(), id.Name)
+			if !ok {
+				continue
+			}
+			if !mapped {
+				l.broken = true
+				continue
+			}
+			l.uses = append(l.uses, use)
+		}
+	}
+
+	if o.localRenames == nil {
+		o.localRenames = make(map[string]int)
+	}
+	// 按声明位置排序后分配名称，给定种子时结果可以复现
+	ordered := make([]*localObject, 0, len(locals))
+	for _, l := range locals {
+		ordered = append(ordered, l)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		if ordered[i].decl.file != ordered[j].decl.file {
+			return ordered[i].decl.file < ordered[j].decl.file
+		}
+		return ordered[i].decl.offset < ordered[j].decl.offset
+	})
+
+	renamed, shadowed, broken := 0, 0, 0
+	var r *rand.Rand
+	file := ""
+	for _, l := range ordered {
+		if l.broken {
+			broken++
+			continue
+		}
+		if r == nil || l.decl.file != file {
+			file = l.decl.file
+			r = newRand(o.seed, "local\x00"+o.displayPath(file))
+		}
+		l.newName = o.newLocalName(r)
+		for _, use := range append(l.uses, l.decl) {
+			o.addIdentRename(use, l.name, l.newName)
+		}
+		o.recordRenamedDecl(l.decl, "local", l.name, l.newName)
+		o.localRenames[l.name]++
+		renamed++
+		if o.shouldProtect(l.name) {
+			shadowed++
+		}
+	}
+	o.logf("局部变量改名: %d 个局部对象按声明单独改名，其中 %d 个与受保护名称同名", renamed, shadowed)
+	if broken > 0 {
+		o.warnf("%d 个 cgo 文件中的局部对象无法定位全部引用，保持原名", broken)
+	}
+}
+
+// sourcePositions 返回把类型检查中的位置换算为原文件位置的函数，供按对象改名使用。
+// ok 为 false 表示不属于会被重写的项目文件；cgo 文件经过 cgo 处理后位于构建缓存中，
+// 按 //line 映射的行列换算，mapped 为 false 表示无法确认
+func (o *Obfuscator) sourcePositions() func(pos token.Pos, name string) (p identPos, ok, mapped bool) {
+	absRoot, err := filepath.Abs(o.projectRoot)
+	if err != nil {
+		return nil
+	}
+	skipped := make(map[string]bool)
+	for path := range o.skippedFiles {
+		if abs, err := filepath.Abs(path); err == nil {
+			skipped[abs] = true
+		}
+	}
+
+	// 被 vendor 的依赖位于输出目录，同样按位置改名
+	absVendor := ""
+	if o.vendorRoot != "" {
+		if absVendor, err = filepath.Abs(o.vendorRoot); err != nil {
+			return nil
+		}
+	}
+	inProject := func(abs string) bool {
+		if skipped[abs] {
+			return false
+		}
+		if absVendor != "" && strings.HasPrefix(abs, absVendor+string(filepath.Separator)) {
+			return true
+		}
+		return strings.HasPrefix(abs, absRoot+string(filepath.Separator)) && !o.isIgnoredVendorPath(abs)
+	}
+	return func(pos token.Pos, name string) (p identPos, ok, mapped bool) {
+		raw := o.typesFset.PositionFor(pos, false)
+		if abs, err := filepath.Abs(raw.Filename); err == nil && inProject(abs) {
+			return identPos{file: abs, offset: raw.Offset}, true, true
+		}
+		adjusted := o.typesFset.PositionFor(pos, true)
+		abs, err := filepath.Abs(adjusted.Filename)
+		if err != nil || !o.cgoFiles[abs] || !inProject(abs) {
+			return identPos{}, false, false
+		}
+		offset, mapped := o.cgoSourceOffset(abs, adjusted.Line, adjusted.Column, name)
+		return identPos{file: abs, offset: offset}, true, mapped
+	}
+}
+
+// isLocalObject 判断对象是否为函数作用域内声明的变量或常量（不含结构体字段），
+// 或者泛型函数、泛型类型及其方法的类型参数
+func isLocalObject(obj types.Object) bool {
+	switch v := obj.(type) {
+	case *types.Var:
+		if v.IsField() {
+			return false
+		}
+	case *types.Const:
+	case *types.TypeName:
+		if _, ok := v.Type().(*types.TypeParam); !ok {
+			return false
+		}
+	default:
+		return false
+	}
+	if obj.Name() == "_" || obj.Pkg() == nil {
+		return false
+	}
+	parent := obj.Parent()
+	return parent != nil && parent != obj.Pkg().Scope() && parent != types.Universe
+}
+
+// bodylessParams 收集没有函数体的函数（由汇编或 linkname 实现）的参数，这些名称保持不变
+func bodylessParams(files []*ast.File, info *types.Info) map[types.Object]bool {
+	params := make(map[types.Object]bool)
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body != nil {
+				continue
+			}
+			for _, list := range []*ast.FieldList{fn.Recv, fn.Type.Params, fn.Type.Results} {
+				if list == nil {
+					continue
+				}
+				for _, field := range list.List {
+					for _, name := range field.Names {
+						if obj := info.Defs[name]; obj != nil {
+							params[obj] = true
+						}
+					}
+				}
+			}
+		}
+	}
+	return params
+}
+
+// newLocalName 生成不与已分配的混淆名（包级名称、导入别名、其他局部变量）冲突的局部变量名。
+// 随机源按文件派生，文件内容不变时其局部变量名也不变，与其他文件新增或删除的名称无关
+func (o *Obfuscator) newLocalName(r *rand.Rand) string {
+	for {
+		name := fmt.Sprintf("l%s", randomString(r, 12))
+		if o.reserveName(name) {
+			return name
+		}
+	}
+}
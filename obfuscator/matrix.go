@@ -0,0 +1,278 @@
+package obfuscator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// BuildTarget 交叉编译目标平台
+type BuildTarget struct {
+	GOOS   string
+	GOARCH string
+}
+
+// String 返回 "goos/goarch" 形式
+func (t BuildTarget) String() string {
+	return t.GOOS + "/" + t.GOARCH
+}
+
+// MatrixResult 单个目标平台的构建结果
+type MatrixResult struct {
+	Target       BuildTarget
+	Binary       string // 混淆后的二进制路径
+	MappingFile  string // 该目标的替换映射文件路径
+	Format       string // 检测到的二进制格式（ELF/PE/Mach-O）
+	Size         int64
+	Replacements int    // 实际写入二进制的替换次数
+	Signature    string // Mach-O 代码签名的处理情况（其他格式为空）
+	Err          error
+}
+
+// targetMapping 写入映射文件的内容
+type targetMapping struct {
+	Target       string                        `json:"target"`
+	Binary       string                        `json:"binary"`
+	Format       string                        `json:"format"`
+	OnlyProject  bool                          `json:"only_project"`
+	NoPclntab    bool                          `json:"disable_pclntab"`
+	Signature    string                        `json:"signature,omitempty"`
+	Replacements map[string]appliedReplacement `json:"replacements"`
+}
+
+// ParseBuildTargets 解析目标平台列表，格式: "linux/amd64,windows/amd64,darwin/arm64"
+func ParseBuildTargets(spec string) ([]BuildTarget, error) {
+	var targets []BuildTarget
+	seen := make(map[BuildTarget]bool)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.Split(item, "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("无效的目标平台 %q，格式应为 GOOS/GOARCH", item)
+		}
+		t := BuildTarget{GOOS: parts[0], GOARCH: parts[1]}
+		if !seen[t] {
+			seen[t] = true
+			targets = append(targets, t)
+		}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("未指定任何目标平台")
+	}
+	return targets, nil
+}
+
+// BuildMatrix 依次为每个目标平台交叉编译（禁用 CGO）并执行链接器混淆，
+// 产物、每个目标的映射文件和汇总表写入 outDir
+// outputBin 作为产物的基础文件名，例如 "app" -> "app_linux_amd64"、"app_windows_amd64.exe"
+func (lo *LinkerObfuscator) BuildMatrix(targets []BuildTarget, outDir string) ([]MatrixResult, error) {
+	lo.logf("=== 交叉编译矩阵 + 链接器混淆 ===")
+	lo.logf("项目目录: %s\n", lo.projectDir)
+	lo.logf("入口包: %s\n", lo.config.EntryPackage)
+	lo.logf("输出目录: %s\n", outDir)
+
+	absOut, err := filepath.Abs(outDir)
+	if err != nil {
+		return nil, fmt.Errorf("解析输出目录失败: %v", err)
+	}
+	if err := os.MkdirAll(absOut, 0755); err != nil {
+		return nil, fmt.Errorf("创建输出目录失败: %v", err)
+	}
+
+	// 包名只需发现一次，所有目标共享同一组待替换的包
+	if lo.config.AutoDiscoverPackages && len(lo.config.PackageReplacements) == 0 {
+		lo.logf("第 0 步: 自动发现项目包名...")
+		if err := lo.discoverAndGeneratePackageReplacements(); err != nil {
+			lo.logf("⚠️  警告: 自动发现包名失败: %v\n", err)
+			lo.logf("   将继续使用默认包名替换模式")
+		}
+	}
+
+	// 所有目标共用同一个命名种子，保证各平台产物的混淆名称一致
+	if lo.config.UseToolexec && lo.config.ToolexecSeed == "" {
+		lo.config.ToolexecSeed = newToolexecSeed()
+	}
+
+	baseName := strings.TrimSuffix(filepath.Base(lo.outputBin), ".exe")
+	results := make([]MatrixResult, 0, len(targets))
+	failed := 0
+
+	for i, target := range targets {
+		lo.logf("\n━━━ [%d/%d] %s ━━━\n", i+1, len(targets), target)
+		result := lo.buildMatrixTarget(target, absOut, baseName)
+		if result.Err != nil {
+			lo.logf("   ❌ %s 失败: %v\n", target, result.Err)
+			failed++
+		}
+		results = append(results, result)
+	}
+
+	summaryPath := filepath.Join(absOut, "summary.txt")
+	summary := formatMatrixSummary(results, absOut)
+	lo.logf("")
+	lo.logf("%s", summary)
+	if err := os.WriteFile(summaryPath, []byte(summary), 0644); err != nil {
+		return results, fmt.Errorf("写入汇总表失败: %v", err)
+	}
+	lo.logf("\n汇总表已保存到: %s\n", summaryPath)
+
+	if failed > 0 {
+		return results, fmt.Errorf("%d/%d 个目标平台构建失败", failed, len(targets))
+	}
+	return results, nil
+}
+
+// buildMatrixTarget 构建并后处理单个目标平台
+func (lo *LinkerObfuscator) buildMatrixTarget(target BuildTarget, outDir, baseName string) MatrixResult {
+	result := MatrixResult{Target: target}
+
+	config := *lo.config
+	if config.WindowsOnlyProject && target.GOOS == "windows" {
+		lo.logf("   ⚠️  Windows 目标：为避免杀软误报，只混淆项目包")
+		config.OnlyObfuscateProject = true
+	}
+
+	name := fmt.Sprintf("%s_%s_%s", baseName, target.GOOS, target.GOARCH)
+	binary := filepath.Join(outDir, name)
+	if target.GOOS == "windows" {
+		binary += ".exe"
+	}
+
+	tlo := &LinkerObfuscator{
+		config:     &config,
+		projectDir: lo.projectDir,
+		outputBin:  binary,
+		goos:       target.GOOS,
+		goarch:     target.GOARCH,
+		logger:     lo.logger,
+	}
+
+	if err := tlo.buildBinary(binary); err != nil {
+		result.Err = err
+		return result
+	}
+	if err := tlo.postProcessBinary(); err != nil {
+		result.Err = fmt.Errorf("后处理失败: %v", err)
+		return result
+	}
+	if config.SelfCheck {
+		if err := tlo.selfCheckWithFallback(); err != nil {
+			result.Err = fmt.Errorf("自检失败: %v", err)
+			return result
+		}
+	}
+	// 交叉编译的产物无法在本机自检，至少确认 Mach-O 的签名与修改后的内容一致
+	if tlo.signature == signatureResigned {
+		data, err := os.ReadFile(binary)
+		if err == nil {
+			err = verifyMachOSignature(data)
+		}
+		if err != nil {
+			result.Err = fmt.Errorf("代码签名校验失败: %v", err)
+			return result
+		}
+	}
+
+	// 产物目录只保留混淆后的二进制，未混淆的备份不应随产物一起分发
+	os.Remove(binary + ".backup")
+
+	result.Binary = binary
+	result.Format = tlo.format
+	result.Signature = tlo.signature
+	if info, err := os.Stat(binary); err == nil {
+		result.Size = info.Size()
+	}
+	for _, r := range tlo.applied {
+		result.Replacements += r.Count
+	}
+
+	mapping := targetMapping{
+		Target:       target.String(),
+		Binary:       filepath.Base(binary),
+		Format:       tlo.format,
+		OnlyProject:  tlo.config.OnlyObfuscateProject,
+		NoPclntab:    tlo.config.DisablePclntab,
+		Signature:    tlo.signature,
+		Replacements: tlo.applied,
+	}
+	if mapping.Replacements == nil {
+		mapping.Replacements = map[string]appliedReplacement{}
+	}
+	mappingData, err := json.MarshalIndent(mapping, "", "  ")
+	if err != nil {
+		result.Err = fmt.Errorf("生成映射文件失败: %v", err)
+		return result
+	}
+	result.MappingFile = filepath.Join(outDir, name+".mapping.json")
+	if err := os.WriteFile(result.MappingFile, mappingData, 0644); err != nil {
+		result.Err = fmt.Errorf("写入映射文件失败: %v", err)
+		return result
+	}
+
+	lo.logf("   ✅ %s -> %s\n", target, filepath.Base(binary))
+	return result
+}
+
+// formatMatrixSummary 生成矩阵构建的汇总表
+func formatMatrixSummary(results []MatrixResult, outDir string) string {
+	sorted := make([]MatrixResult, len(results))
+	copy(sorted, results)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Target.String() < sorted[j].Target.String()
+	})
+
+	var b strings.Builder
+	fmt.Fprintln(&b, "构建汇总:")
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "目标\t格式\t大小\t替换次数\t签名\t二进制\t映射文件\t状态")
+	for _, r := range sorted {
+		status := "✅ 成功"
+		if r.Err != nil {
+			status = "❌ " + r.Err.Error()
+			if idx := strings.IndexByte(status, '\n'); idx >= 0 {
+				status = status[:idx]
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\n",
+			r.Target, orDash(r.Format), formatSize(r.Size), r.Replacements, orDash(r.Signature),
+			orDash(relativeTo(outDir, r.Binary)), orDash(relativeTo(outDir, r.MappingFile)), status)
+	}
+	w.Flush()
+	return b.String()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func relativeTo(dir, path string) string {
+	if path == "" {
+		return ""
+	}
+	if rel, err := filepath.Rel(dir, path); err == nil {
+		return rel
+	}
+	return path
+}
+
+// formatSize 以 KB/MB 格式化文件大小
+func formatSize(size int64) string {
+	switch {
+	case size <= 0:
+		return "-"
+	case size < 1024*1024:
+		return fmt.Sprintf("%.1f KB", float64(size)/1024)
+	default:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1024*1024))
+	}
+}
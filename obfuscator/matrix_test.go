@@ -0,0 +1,162 @@
+package obfuscator_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cross-file-obfuscator/obfuscator"
+)
+
+// TestParseBuildTargets 目标列表去重并保持顺序，格式错误时报错
+func TestParseBuildTargets(t *testing.T) {
+	got, err := obfuscator.ParseBuildTargets(" linux/amd64, darwin/arm64,linux/amd64,,windows/386")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []obfuscator.BuildTarget{{GOOS: "linux", GOARCH: "amd64"}, {GOOS: "darwin", GOARCH: "arm64"}, {GOOS: "windows", GOARCH: "386"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseBuildTargets = %v，期望 %v", got, want)
+	}
+	for _, spec := range []string{"", " , ", "linux", "linux/", "/amd64", "linux/amd64/v2"} {
+		if _, err := obfuscator.ParseBuildTargets(spec); err == nil {
+			t.Errorf("ParseBuildTargets(%q) 应返回错误", spec)
+		}
+	}
+}
+
+// TestBuildMatrix 交叉编译三个平台：产物、映射文件和汇总表齐全，darwin/arm64 的 ad-hoc 签名在修改后重新计算
+func TestBuildMatrix(t *testing.T) {
+	skipIfShort(t)
+	project := copyTestdata(t, "app")
+	dist := filepath.Join(t.TempDir(), "dist")
+	targets, err := obfuscator.ParseBuildTargets("linux/amd64,windows/amd64,darwin/arm64")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lo := obfuscator.NewLinkerObfuscator(project, "app", &obfuscator.LinkConfig{
+		RemoveFuncNames:      true,
+		AutoDiscoverPackages: true,
+	})
+	results, err := lo.BuildMatrix(targets, dist)
+	if err != nil {
+		t.Fatalf("矩阵构建失败: %v", err)
+	}
+
+	wantBinaries := map[string]string{
+		"linux/amd64":   "app_linux_amd64",
+		"windows/amd64": "app_windows_amd64.exe",
+		"darwin/arm64":  "app_darwin_arm64",
+	}
+	wantFormats := map[string]string{"linux/amd64": "ELF", "windows/amd64": "PE", "darwin/arm64": "Mach-O"}
+	if len(results) != len(targets) {
+		t.Fatalf("得到 %d 个结果，期望 %d 个", len(results), len(targets))
+	}
+	for _, r := range results {
+		target := r.Target.String()
+		if r.Err != nil {
+			t.Errorf("%s 失败: %v", target, r.Err)
+			continue
+		}
+		if filepath.Base(r.Binary) != wantBinaries[target] || r.Format != wantFormats[target] || r.Replacements == 0 {
+			t.Errorf("%s: 二进制 %s，格式 %s，替换 %d 次", target, r.Binary, r.Format, r.Replacements)
+		}
+		if _, err := os.Stat(r.Binary + ".backup"); !os.IsNotExist(err) {
+			t.Errorf("%s 的产物目录不应保留未混淆的备份", target)
+		}
+
+		var mapping struct {
+			Target       string                     `json:"target"`
+			Binary       string                     `json:"binary"`
+			Format       string                     `json:"format"`
+			Signature    string                     `json:"signature"`
+			Replacements map[string]json.RawMessage `json:"replacements"`
+		}
+		data, err := os.ReadFile(r.MappingFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(data, &mapping); err != nil {
+			t.Fatalf("%s 的映射文件无法解析: %v", target, err)
+		}
+		if mapping.Target != target || mapping.Binary != wantBinaries[target] || mapping.Format != r.Format || len(mapping.Replacements) == 0 {
+			t.Errorf("%s 的映射文件内容不正确: %s", target, data)
+		}
+		if _, ok := mapping.Replacements["example.com/app/greet"]; !ok {
+			t.Errorf("%s 的映射文件缺少项目包的替换: %s", target, data)
+		}
+
+		if r.Format == "Mach-O" {
+			if r.Signature != mapping.Signature || !strings.Contains(r.Signature, "重新计算") {
+				t.Errorf("darwin 产物的签名应被重新计算: %q / %q", r.Signature, mapping.Signature)
+			}
+			bin, err := os.ReadFile(r.Binary)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := obfuscator.VerifyMachOSignature(bin); err != nil {
+				t.Errorf("darwin 产物的签名无效: %v", err)
+			}
+		} else if r.Signature != "" {
+			t.Errorf("%s 不是 Mach-O，不应有签名信息: %q", target, r.Signature)
+		}
+	}
+
+	summary, err := os.ReadFile(filepath.Join(dist, "summary.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"签名", "ad-hoc 签名已重新计算", "app_windows_amd64.exe", "app_darwin_arm64.mapping.json", "linux/amd64"} {
+		if !strings.Contains(string(summary), want) {
+			t.Errorf("汇总表中缺少 %q:\n%s", want, summary)
+		}
+	}
+}
+
+// TestMachOSignature 修改后重新计算 ad-hoc 签名使其恢复有效；非 ad-hoc 签名无法就地更新，返回错误
+func TestMachOSignature(t *testing.T) {
+	skipIfShort(t)
+	project := copyTestdata(t, "app")
+	bin := filepath.Join(t.TempDir(), "app")
+	cmd := exec.Command("go", "build", "-o", bin, ".")
+	cmd.Dir = project
+	cmd.Env = append(os.Environ(), "CGO_ENABLED=0", "GOOS=darwin", "GOARCH=arm64")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("交叉编译失败: %v\n%s", err, output)
+	}
+	data, err := os.ReadFile(bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := obfuscator.VerifyMachOSignature(data); err != nil {
+		t.Fatalf("链接器生成的签名应有效: %v", err)
+	}
+
+	patched := append([]byte(nil), data...)
+	patched[len(patched)/4] ^= 0xff
+	if err := obfuscator.VerifyMachOSignature(patched); err == nil {
+		t.Fatal("修改内容后签名校验应失败")
+	}
+	if err := obfuscator.ResignMachO(patched); err != nil {
+		t.Fatal(err)
+	}
+	if err := obfuscator.VerifyMachOSignature(patched); err != nil {
+		t.Errorf("重新计算后签名应有效: %v", err)
+	}
+
+	// 清除 CodeDirectory 的 ad-hoc 标志，模拟带证书的签名
+	cd := bytes.LastIndex(patched, []byte{0xfa, 0xde, 0x0c, 0x02})
+	if cd < 0 {
+		t.Fatal("找不到 CodeDirectory")
+	}
+	patched[cd+15] &^= 0x2
+	if err := obfuscator.ResignMachO(patched); err == nil || !strings.Contains(err.Error(), "ad-hoc") {
+		t.Errorf("非 ad-hoc 签名应拒绝更新，得到 %v", err)
+	}
+}
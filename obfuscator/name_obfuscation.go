@@ -17,6 +17,14 @@ func (o *Obfuscator) obfuscateName(name string, isFunc bool) string {
 	if obf, exists := mapping[name]; exists {
 		return obf
 	}
+	kind := stableVar
+	if isFunc {
+		kind = stableFunc
+	}
+	if obf, ok := o.stable.get(kind, name); ok {
+		mapping[name] = obf
+		return obf
+	}
 
 	// 检查是否为导出名称（首字母大写）
 	isExported := len(name) > 0 && name[0] >= 'A' && name[0] <= 'Z'
@@ -25,7 +33,7 @@ func (o *Obfuscator) obfuscateName(name string, isFunc bool) string {
 	// 使用循环确保在所有映射中的唯一性
 	maxAttempts := 100
 	for attempt := 0; attempt < maxAttempts; attempt++ {
-		randomPart := generateRandomString(12)
+		randomPart := o.randomString(12)
 		var obf string
 		
 		if isExported {
@@ -40,8 +48,8 @@ func (o *Obfuscator) obfuscateName(name string, isFunc bool) string {
 			obf = fmt.Sprintf("%s%s", prefix, randomPart)
 		}
 
-		// 检查此名称是否已在任何映射中使用
-		if !o.isNameUsed(obf) {
+		// 检查此名称是否已分配（O(1) 查找已登记的混淆名）
+		if o.reserveName(obf) {
 			mapping[name] = obf
 			return obf
 		}
@@ -52,45 +60,69 @@ func (o *Obfuscator) obfuscateName(name string, isFunc bool) string {
 	var obf string
 	if isExported {
 		if isFunc {
-			obf = fmt.Sprintf("Fn%d_%s", o.namingCounter, generateRandomString(8))
+			obf = fmt.Sprintf("Fn%d_%s", o.namingCounter, o.randomString(8))
 		} else {
-			obf = fmt.Sprintf("V%d_%s", o.namingCounter, generateRandomString(8))
+			obf = fmt.Sprintf("V%d_%s", o.namingCounter, o.randomString(8))
 		}
 	} else {
-		obf = fmt.Sprintf("%s%d_%s", prefix, o.namingCounter, generateRandomString(8))
+		obf = fmt.Sprintf("%s%d_%s", prefix, o.namingCounter, o.randomString(8))
 	}
+	o.reserveName(obf)
 	mapping[name] = obf
 	return obf
 }
 
-// isNameUsed 检查名称是否已在任何映射中使用
-func (o *Obfuscator) isNameUsed(name string) bool {
-	// 检查变量映射
-	for _, obfName := range o.varMapping {
-		if obfName == name {
-			return true
-		}
-	}
-
-	// 检查函数映射
-	for _, obfName := range o.funcMapping {
-		if obfName == name {
-			return true
-		}
-	}
-
-	// 检查导入别名映射
-	for _, obfName := range o.importAliasMapping {
-		if obfName == name {
-			return true
-		}
-	}
-
-	return false
+// protectedIdentifiers 可能导致问题的常见 Go 标识符（预声明类型、函数和常量）
+var protectedIdentifiers = map[string]bool{
+	"error":      true, // 内置错误接口
+	"string":     true, // 内置类型
+	"int":        true,
+	"bool":       true,
+	"byte":       true,
+	"rune":       true,
+	"float32":    true,
+	"float64":    true,
+	"complex64":  true,
+	"complex128": true,
+	"uint":       true,
+	"int8":       true,
+	"int16":      true,
+	"int32":      true,
+	"int64":      true,
+	"uint8":      true,
+	"uint16":     true,
+	"uint32":     true,
+	"uint64":     true,
+	"uintptr":    true,
+	"len":        true, // 内置函数
+	"cap":        true,
+	"make":       true,
+	"new":        true,
+	"append":     true,
+	"copy":       true,
+	"delete":     true,
+	"panic":      true,
+	"recover":    true,
+	"print":      true,
+	"println":    true,
+	"close":      true,
+	"min":        true, // Go 1.21+ 内置函数
+	"max":        true,
+	"clear":      true,
+	"nil":        true, // 内置常量
+	"true":       true,
+	"false":      true,
+	"iota":       true,
 }
 
 // shouldProtect 检查名称是否应受保护而不被混淆
 func (o *Obfuscator) shouldProtect(name string) bool {
+	// 保护受保护列表中的名称（字段、方法、选择器）
+	return o.protectedByRule(name) || o.protectedNames[name]
+}
+
+// protectedByRule 检查名称是否受不依赖声明对象的全局规则保护
+func (o *Obfuscator) protectedByRule(name string) bool {
 	// 保护特殊名称
 	if name == "_" || name == "main" || name == "init" {
 		return true
@@ -99,56 +131,10 @@ func (o *Obfuscator) shouldProtect(name string) bool {
 	if !o.Config.ObfuscateExported && isExported(name) {
 		return true
 	}
-	// 保护受保护列表中的名称（字段、方法、选择器）
-	if o.protectedNames[name] {
-		return true
-	}
 	// 保护包名称（来自导入）
 	if o.packageNames[name] {
 		return true
 	}
-	// 保护可能导致问题的常见 Go 标识符
-	protectedIdentifiers := map[string]bool{
-		"error":      true, // 内置错误接口
-		"string":     true, // 内置类型
-		"int":        true,
-		"bool":       true,
-		"byte":       true,
-		"rune":       true,
-		"float32":    true,
-		"float64":    true,
-		"complex64":  true,
-		"complex128": true,
-		"uint":       true,
-		"int8":       true,
-		"int16":      true,
-		"int32":      true,
-		"int64":      true,
-		"uint8":      true,
-		"uint16":     true,
-		"uint32":     true,
-		"uint64":     true,
-		"uintptr":    true,
-		"len":        true, // 内置函数
-		"cap":        true,
-		"make":       true,
-		"new":        true,
-		"append":     true,
-		"copy":       true,
-		"delete":     true,
-		"panic":      true,
-		"recover":    true,
-		"print":      true,
-		"println":    true,
-		"close":      true,
-		"min":        true, // Go 1.21+ 内置函数
-		"max":        true,
-		"clear":      true,
-		"nil":        true, // 内置常量
-		"true":       true,
-		"false":      true,
-		"iota":       true,
-	}
 	if protectedIdentifiers[name] {
 		return true
 	}
@@ -171,6 +157,10 @@ func (o *Obfuscator) obfuscateFileName(fileName string) string {
 	if obfuscated, exists := o.fileNameMapping[fileName]; exists {
 		return obfuscated
 	}
+	if obfuscated, ok := o.stable.get(stableFile, fileName); ok {
+		o.fileNameMapping[fileName] = obfuscated
+		return obfuscated
+	}
 
 	// ✅ 提取平台特定后缀（如 _linux, _windows, _darwin 等）
 	// 这些后缀用于build标签，必须保留以避免同名函数冲突
@@ -185,18 +175,25 @@ func (o *Obfuscator) obfuscateFileName(fileName string) string {
 	suffix := ""
 	if strings.HasSuffix(fileName, ".go") {
 		nameWithoutExt := strings.TrimSuffix(fileName, ".go")
+		// _test 后缀决定文件是否为测试文件，位于平台后缀之后
+		testSuffix := ""
+		if strings.HasSuffix(nameWithoutExt, "_test") {
+			testSuffix = "_test"
+			nameWithoutExt = strings.TrimSuffix(nameWithoutExt, "_test")
+		}
 		for _, ps := range platformSuffixes {
 			if strings.HasSuffix(nameWithoutExt, ps) {
 				suffix = ps
 				break
 			}
 		}
+		suffix += testSuffix
 	}
 
 	// 生成随机文件名
 	maxAttempts := 100
 	for attempt := 0; attempt < maxAttempts; attempt++ {
-		randomPart := generateRandomString(10)
+		randomPart := o.randomString(10)
 		var obfuscatedName string
 		if suffix != "" {
 			// 保留平台后缀
@@ -206,15 +203,7 @@ func (o *Obfuscator) obfuscateFileName(fileName string) string {
 		}
 
 		// 检查此名称是否已被使用
-		nameUsed := false
-		for _, existingName := range o.fileNameMapping {
-			if existingName == obfuscatedName {
-				nameUsed = true
-				break
-			}
-		}
-
-		if !nameUsed {
+		if o.reserveName(obfuscatedName) {
 			// 存储映射并返回
 			o.fileNameMapping[fileName] = obfuscatedName
 			return obfuscatedName
@@ -224,9 +213,9 @@ func (o *Obfuscator) obfuscateFileName(fileName string) string {
 	// 回退：如果随机生成失败，使用基于计数器的方法
 	var obfuscatedName string
 	if suffix != "" {
-		obfuscatedName = fmt.Sprintf("f%d_%s%s.go", len(o.fileNameMapping), generateRandomString(6), suffix)
+		obfuscatedName = fmt.Sprintf("f%d_%s%s.go", len(o.fileNameMapping), o.randomString(6), suffix)
 	} else {
-		obfuscatedName = fmt.Sprintf("f%d_%s.go", len(o.fileNameMapping), generateRandomString(6))
+		obfuscatedName = fmt.Sprintf("f%d_%s.go", len(o.fileNameMapping), o.randomString(6))
 	}
 	o.fileNameMapping[fileName] = obfuscatedName
 	return obfuscatedName
@@ -239,6 +228,13 @@ func (o *Obfuscator) generateUniqueObfuscatedNameForObject(obj *Object) string {
 	if existingName, exists := o.objectMapping[obj]; exists && existingName != "" {
 		return existingName
 	}
+	kind := stableVar
+	if obj.Kind == ObjFunc {
+		kind = stableFunc
+	}
+	if obfName, ok := o.stable.get(kind, obj.Name); ok {
+		return obfName
+	}
 
 	// 检查是否为导出名称
 	isExported := len(obj.Name) > 0 && obj.Name[0] >= 'A' && obj.Name[0] <= 'Z'
@@ -246,7 +242,7 @@ func (o *Obfuscator) generateUniqueObfuscatedNameForObject(obj *Object) string {
 	// 尝试生成唯一的混淆名称
 	maxAttempts := 100
 	for attempt := 0; attempt < maxAttempts; attempt++ {
-		randomPart := generateRandomString(12)
+		randomPart := o.randomString(12)
 		var obfName string
 		
 		if obj.Kind == ObjFunc {
@@ -263,8 +259,8 @@ func (o *Obfuscator) generateUniqueObfuscatedNameForObject(obj *Object) string {
 			}
 		}
 
-		// 检查此名称是否在整个项目中已被使用
-		if !o.isObfuscatedNameUsedInProject(obfName) {
+		// 检查此名称是否在整个项目中已被使用（O(1) 查找已登记的混淆名）
+		if o.reserveName(obfName) {
 			return obfName
 		}
 	}
@@ -274,48 +270,18 @@ func (o *Obfuscator) generateUniqueObfuscatedNameForObject(obj *Object) string {
 	var obfName string
 	if obj.Kind == ObjFunc {
 		if isExported {
-			obfName = fmt.Sprintf("Fn%d_%s", o.namingCounter, generateRandomString(8))
+			obfName = fmt.Sprintf("Fn%d_%s", o.namingCounter, o.randomString(8))
 		} else {
-			obfName = fmt.Sprintf("fn%d_%s", o.namingCounter, generateRandomString(8))
+			obfName = fmt.Sprintf("fn%d_%s", o.namingCounter, o.randomString(8))
 		}
 	} else {
 		if isExported {
-			obfName = fmt.Sprintf("V%d_%s", o.namingCounter, generateRandomString(8))
+			obfName = fmt.Sprintf("V%d_%s", o.namingCounter, o.randomString(8))
 		} else {
-			obfName = fmt.Sprintf("l%d_%s", o.namingCounter, generateRandomString(8))
+			obfName = fmt.Sprintf("l%d_%s", o.namingCounter, o.randomString(8))
 		}
 	}
+	o.reserveName(obfName)
 	return obfName
 }
 
-// isObfuscatedNameUsedInProject 检查混淆名在整个项目中是否已被使用
-func (o *Obfuscator) isObfuscatedNameUsedInProject(name string) bool {
-	// 检查objectMapping中的所有混淆名
-	for _, obfName := range o.objectMapping {
-		if obfName == name {
-			return true
-		}
-	}
-
-	// 检查funcMapping和varMapping（向后兼容）
-	for _, obfName := range o.funcMapping {
-		if obfName == name {
-			return true
-		}
-	}
-	for _, obfName := range o.varMapping {
-		if obfName == name {
-			return true
-		}
-	}
-
-	// 检查导入别名映射
-	for _, obfName := range o.importAliasMapping {
-		if obfName == name {
-			return true
-		}
-	}
-
-	return false
-}
-
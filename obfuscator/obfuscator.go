@@ -1,39 +1,41 @@
 package obfuscator
 
 import (
-	"crypto/rand"
 	"fmt"
 	"go/token"
-	"math/big"
 )
 
-// New 创建新的混淆器实例
-func New(projectRoot, outputDir string, config *Config) *Obfuscator {
+// New 创建新的混淆器实例。config 为 nil 时使用默认配置（DefaultConfig）；
+// config 会被复制，之后修改不影响混淆器。opts 设置日志、事件回调等可选参数
+func New(projectRoot, outputDir string, config *Config, opts ...Option) *Obfuscator {
 	if config == nil {
-		config = &Config{
-			ObfuscateExported:  false,
-			ObfuscateFileNames: false,
-			EncryptStrings:     false,
-			InjectJunkCode:     false,
-			RemoveComments:     true,
-			PreserveReflection: true,
-			SkipGeneratedCode:  true,
-			ExcludePatterns:    []string{},
-		}
+		config = DefaultConfig()
+	}
+	cfg := *config
+	config = &cfg
+	opt := applyOptions(opts)
+	if opt.seed != nil {
+		config.Seed = *opt.seed
+	}
+	if opt.workers != nil {
+		config.Workers = *opt.workers
 	}
 
-	seed, _ := rand.Int(rand.Reader, big.NewInt(999999))
-	encryptionKey := generateRandomString(64)
+	seed := newSeed(config.Seed)
+	rng := newRand(seed, "")
+	encryptionKey := randomString(rng, 64)
 	// 生成完全随机的导出函数名（首字母大写）
-	decryptFuncName := fmt.Sprintf("%c%s", 'A'+byte(seed.Int64()%26), generateRandomString(11))
-	decryptPkgName := fmt.Sprintf("p%s", generateRandomString(8))
+	decryptFuncName := fmt.Sprintf("%c%s", 'A'+byte(rng.IntN(26)), randomString(rng, 11))
+	decryptPkgName := fmt.Sprintf("p%s", randomString(rng, 8))
 
-	return &Obfuscator{
+	o := &Obfuscator{
 		varMapping:          make(map[string]string),
 		funcMapping:         make(map[string]string),
 		exportedFuncMapping: make(map[string]string),
 		fset:                token.NewFileSet(),
-		randomSeed:          seed,
+		seed:                seed,
+		rng:                 rng,
+		usedNames:           make(map[string]bool),
 		encryptionKey:       encryptionKey,
 		namingCounter:       0,
 		projectRoot:         projectRoot,
@@ -43,8 +45,15 @@ func New(projectRoot, outputDir string, config *Config) *Obfuscator {
 		fileNameMapping:     make(map[string]string),
 		filePathMapping:     make(map[string]string),
 		protectedNames:      make(map[string]bool),
+		protectionReasons:   make(map[string][]ProtectionReason),
+		protectionSeen:      make(map[protectionKey]bool),
+		protectionSites:     make(map[string][]protectionSite),
 		packageNames:        make(map[string]bool),
 		Config:              config,
+		logger:              opt.logger,
+		events:              opt.events,
+		sourceFS:            opt.sourceFS,
+		output:              opt.output,
 		encryptedStrings:    make(map[string]bool),
 		decryptFuncAdded:    make(map[string]bool),
 		decryptFuncName:     decryptFuncName,
@@ -52,9 +61,31 @@ func New(projectRoot, outputDir string, config *Config) *Obfuscator {
 		decryptPkgCreated:   false,
 		skippedFiles:        make(map[string]string),
 		reflectionPackages:  make(map[string]bool),
+		reflectionAnalyzed:  make(map[string]bool),
+		cgoFiles:            make(map[string]bool),
+		cgoSources:          make(map[string]*cgoSource),
 		fileScopes:          make(map[string]*ScopeAnalyzer),
 		objectMapping:       make(map[*Object]string),
 	}
+	if opt.previous != nil {
+		o.stable = newStableNames(opt.previous)
+		o.reserveStableNames()
+	}
+	return o
+}
+
+// DefaultConfig 返回默认配置：移除注释、保留反射类型、跳过生成代码，其余选项关闭
+func DefaultConfig() *Config {
+	return &Config{
+		ObfuscateExported:  false,
+		ObfuscateFileNames: false,
+		EncryptStrings:     false,
+		InjectJunkCode:     false,
+		RemoveComments:     true,
+		PreserveReflection: true,
+		SkipGeneratedCode:  true,
+		ExcludePatterns:    []string{},
+	}
 }
 
 // GetStatistics 返回混淆统计信息
@@ -78,11 +109,51 @@ func (o *Obfuscator) GetStatistics() *Statistics {
 		}
 	}
 	
+	// 按声明对象单独改名的局部变量和包级声明
+	for _, n := range o.localRenames {
+		varCount += n
+	}
+	for _, d := range o.renamedDecls {
+		switch d.kind {
+		case "func":
+			funcCount++
+		case "var", "const":
+			varCount++
+		}
+	}
+
+	// 项目中的 Go 文件：加载阶段解析的文件和无法解析的文件；解密包不计入
+	totalFiles, obfuscatedFiles := len(o.sourceFiles), 0
+	for path := range o.skippedFiles {
+		if _, parsed := o.sourceByPath[path]; !parsed {
+			totalFiles++
+		}
+	}
+	for _, pkg := range o.transformed {
+		for _, f := range pkg.Files {
+			if _, ok := o.sourceByPath[f.original]; ok {
+				obfuscatedFiles++
+			}
+		}
+	}
+
 	return &Statistics{
-		ProtectedNames: len(o.protectedNames),
-		FunctionsObf:   funcCount,
-		VariablesObf:   varCount,
-		SkippedFiles:   len(o.skippedFiles),
+		TotalFiles:      totalFiles,
+		ObfuscatedFiles: obfuscatedFiles,
+		ProtectedNames:  len(o.protectedNames),
+		FunctionsObf:    funcCount,
+		VariablesObf:    varCount,
+		FieldsObf:       o.fieldsRenamed,
+		IdentsRenamed:   int(o.renamedIdents.Load()),
+		StringsEncrypt:  int(o.stringsEncrypted.Load()),
+		JunkBlocks:      int(o.junkBlocks.Load()),
+		SkippedFiles:    len(o.skippedFiles),
+		ParsedFiles:     len(o.sourceFiles),
+		Packages:        len(o.sourcePackages),
+		Phases:          o.phaseTimings,
+		PeakHeapBytes:   o.peakHeap,
+		TotalAllocBytes: o.totalAlloc,
+		Coverage:        o.coverage(),
 	}
 }
 
@@ -0,0 +1,228 @@
+package obfuscator
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// packageObject 一个按对象单独改名的包级函数、变量或常量
+type packageObject struct {
+	obj    types.Object
+	key    string // 包路径.原名
+	decl   identPos
+	uses   []identPos
+	broken bool // 有引用无法定位到原文件（cgo 文件），整个对象保持原名
+}
+
+// planObjectRenames 为与受保护名称同名的包级函数、变量和常量按声明对象单独改名。
+// 名称的保护记录都属于其他对象（字段、方法、非项目包的选择器）时，同名的包级声明不受影响：
+// 它们的全部引用都来自类型检查，按位置改名不会波及同名的字段和方法。
+// 没有类型信息的文件（类型检查失败、构建约束排除、跳过或排除的文件）中出现的名称仍按名称保护
+func (o *Obfuscator) planObjectRenames() {
+	if len(o.typedPkgs) == 0 {
+		return
+	}
+	position := o.sourcePositions()
+	if position == nil {
+		return
+	}
+
+	// 类型检查未覆盖或不会重写的项目文件：其中出现的名称无法按对象追踪
+	typed := make(map[string]bool)
+	for _, pkg := range o.typedPkgs {
+		for _, f := range pkg.GoFiles {
+			typed[f] = true
+		}
+	}
+	untyped := make(map[string]bool)
+	for _, file := range o.sourceFiles {
+		if _, skipped := o.skippedFiles[file.path]; typed[file.abs] && !skipped {
+			continue
+		}
+		ast.Inspect(file.node, func(n ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok {
+				untyped[id.Name] = true
+			}
+			return true
+		})
+	}
+
+	// 保护记录对应的声明；无法对应到对象的记录使同名的包级声明全部保持原名
+	siteObjects := make(map[token.Pos]types.Object)
+	for _, pkg := range o.typedPkgs {
+		for id, obj := range pkg.TypesInfo.Defs {
+			if _, ok := o.protectionSites[id.Name]; ok && obj != nil {
+				siteObjects[id.Pos()] = obj
+			}
+		}
+		for id, obj := range pkg.TypesInfo.Uses {
+			if _, ok := o.protectionSites[id.Name]; ok && siteObjects[id.Pos()] == nil {
+				siteObjects[id.Pos()] = obj
+			}
+		}
+	}
+	protectedDecls := make(map[identPos]bool)
+	unresolved := make(map[string]bool)
+	for name, sites := range o.protectionSites {
+		for _, site := range sites {
+			obj := site.obj
+			if obj == nil && site.pos.IsValid() {
+				obj = siteObjects[site.pos]
+			}
+			if obj == nil {
+				unresolved[name] = true
+				continue
+			}
+			if decl, ok, _ := position(obj.Pos(), obj.Name()); ok {
+				protectedDecls[decl] = true
+			}
+		}
+	}
+
+	// 声明位置 -> 包级对象；测试变体共享同一声明位置
+	objects := make(map[identPos]*packageObject)
+	lookup := func(obj types.Object) *packageObject {
+		if !isPackageLevelValue(obj) {
+			return nil
+		}
+		name := obj.Name()
+		if !o.protectedNames[name] || o.protectedByRule(name) || unresolved[name] || untyped[name] {
+			return nil
+		}
+		decl, ok, mapped := position(obj.Pos(), name)
+		if !ok || !mapped || protectedDecls[decl] {
+			return nil
+		}
+		if p, ok := objects[decl]; ok {
+			return p
+		}
+		p := &packageObject{obj: obj, key: obj.Pkg().Path() + "." + name, decl: decl}
+		objects[decl] = p
+		return p
+	}
+
+	for _, pkg := range o.typedPkgs {
+		for _, obj := range pkg.TypesInfo.Defs {
+			if obj != nil {
+				lookup(obj)
+			}
+		}
+		for id, obj := range pkg.TypesInfo.Uses {
+			p := lookup(obj)
+			if p == nil {
+				continue
+			}
+			use, ok, mapped := position(id.Pos(), id.Name)
+			if !ok {
+				continue
+			}
+			if !mapped {
+				p.broken = true
+				continue
+			}
+			p.uses = append(p.uses, use)
+		}
+	}
+
+	if o.objectRenames == nil {
+		o.objectRenames = make(map[string]string)
+	}
+	ordered := make([]*packageObject, 0, len(objects))
+	for _, p := range objects {
+		ordered = append(ordered, p)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		if ordered[i].decl.file != ordered[j].decl.file {
+			return ordered[i].decl.file < ordered[j].decl.file
+		}
+		return ordered[i].decl.offset < ordered[j].decl.offset
+	})
+
+	renamed, broken := 0, 0
+	for _, p := range ordered {
+		if p.broken {
+			broken++
+			continue
+		}
+		name := p.obj.Name()
+		newName, ok := o.stable.get(stableObject, p.key)
+		if !ok {
+			newName = o.newObjectName(p)
+		}
+		for _, use := range append(p.uses, p.decl) {
+			o.addIdentRename(use, name, newName)
+		}
+		o.recordRenamedDecl(p.decl, objectDeclKind(p.obj), name, newName)
+		o.objectRenames[p.key] = newName
+		renamed++
+
+		// 同一目录中以该名称命名的示例函数随之改名
+		for _, ex := range o.exampleFuncs {
+			rest := strings.TrimPrefix(ex.name, "Example")
+			ident, tail := rest, ""
+			if i := strings.Index(rest, "_"); i >= 0 {
+				ident, tail = rest[:i], rest[i:]
+			}
+			if ident == name && filepath.Dir(ex.pos.file) == filepath.Dir(p.decl.file) {
+				o.addIdentRename(ex.pos, ex.name, "Example"+newName+tail)
+				o.recordRenamedDecl(ex.pos, "example", ex.name, "Example"+newName+tail)
+			}
+		}
+	}
+	if renamed > 0 {
+		o.logf("包级声明改名: %d 个与受保护名称同名的包级函数、变量和常量按声明单独改名", renamed)
+	}
+	if broken > 0 {
+		o.warnf("%d 个包级声明在 cgo 文件中的引用无法定位，保持原名", broken)
+	}
+}
+
+// isPackageLevelValue 判断对象是否为包级函数、变量或常量（不含方法）
+func isPackageLevelValue(obj types.Object) bool {
+	switch obj.(type) {
+	case *types.Func, *types.Var, *types.Const:
+	default:
+		return false
+	}
+	return obj.Pkg() != nil && obj.Parent() == obj.Pkg().Scope()
+}
+
+// objectDeclKind 返回包级对象在运行报告中的类别
+func objectDeclKind(obj types.Object) string {
+	switch obj.(type) {
+	case *types.Func:
+		return "func"
+	case *types.Const:
+		return "const"
+	}
+	return "var"
+}
+
+// newObjectName 生成包级对象的混淆名，命名规则与按名称混淆的包级名称一致。
+// 随机源按包路径和原名派生，其他声明增删时名称不变
+func (o *Obfuscator) newObjectName(p *packageObject) string {
+	r := newRand(o.seed, "object\x00"+p.key)
+	_, isFunc := p.obj.(*types.Func)
+	var prefix string
+	switch {
+	case isFunc && p.obj.Exported():
+		prefix = "Fn"
+	case isFunc:
+		prefix = "fn"
+	case p.obj.Exported():
+		prefix = "V"
+	default:
+		prefix = "l"
+	}
+	for {
+		name := fmt.Sprintf("%s%s", prefix, randomString(r, 12))
+		if o.reserveName(name) {
+			return name
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package obfuscator
+
+import (
+	"fmt"
+	"io/fs"
+	"strings"
+	"time"
+)
+
+// Logger 接收混淆过程中的进度和警告信息，*log.Logger 满足该接口。
+// 未通过 WithLogger 注入时库不输出任何内容
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// discardLogger 默认的 Logger，丢弃全部输出
+type discardLogger struct{}
+
+func (discardLogger) Printf(string, ...interface{}) {}
+
+// EventKind 事件类别
+type EventKind string
+
+const (
+	EventPhaseStart EventKind = "phase_start" // 阶段开始
+	EventPhaseEnd   EventKind = "phase_end"   // 阶段结束，Duration 为耗时
+	EventFileDone   EventKind = "file_done"   // 一个文件已混淆并写出
+	EventWarning    EventKind = "warning"     // 不影响继续执行的问题（无法解析的文件、类型检查失败等）
+)
+
+// Event 混淆过程中的一个结构化事件
+type Event struct {
+	Kind     EventKind
+	Phase    string        // 所在阶段的名称
+	File     string        // 相关文件（相对项目目录，可能为空）
+	Message  string        // 可读的描述
+	Duration time.Duration // EventPhaseEnd 的阶段耗时
+}
+
+// EventHandler 接收事件的回调。转换阶段并发处理文件，回调可能被多个 goroutine 同时调用
+type EventHandler func(Event)
+
+// options 构造函数的可选参数
+type options struct {
+	logger   Logger
+	events   EventHandler
+	seed     *string
+	workers  *int
+	sourceFS fs.FS
+	output   OutputSink
+	previous *Result
+}
+
+// Option 构造 Obfuscator 或 LinkerObfuscator 时的可选参数
+type Option func(*options)
+
+// WithLogger 设置接收进度和警告信息的 Logger
+func WithLogger(l Logger) Option {
+	return func(opts *options) { opts.logger = l }
+}
+
+// WithEventHandler 设置结构化事件回调（阶段开始/结束、文件完成、警告）
+func WithEventHandler(h EventHandler) Option {
+	return func(opts *options) { opts.events = h }
+}
+
+// WithSeed 设置命名种子，覆盖 Config.Seed（链接器混淆中覆盖 LinkConfig.ToolexecSeed）
+func WithSeed(seed string) Option {
+	return func(opts *options) { opts.seed = &seed }
+}
+
+// WithWorkers 设置转换阶段的并发数，覆盖 Config.Workers
+func WithWorkers(n int) Option {
+	return func(opts *options) { opts.workers = &n }
+}
+
+// WithSourceFS 从 fsys 读取项目（根目录需包含 go.mod），New 的 projectRoot 参数只用于显示。
+// 类型检查需要真实目录，输入会先写入临时目录，Run 结束后删除
+func WithSourceFS(fsys fs.FS) Option {
+	return func(opts *options) { opts.sourceFS = fsys }
+}
+
+// WithOutput 把混淆结果写入 sink（目录、tar/zip 流、内存等），New 的 outputDir 参数不再使用。
+// 目录以外的 sink 先在临时目录中生成，Run 成功后写入 sink 并删除临时目录
+func WithOutput(sink OutputSink) Option {
+	return func(opts *options) { opts.output = sink }
+}
+
+// WithPreviousResult 沿用上一次运行（相同项目和配置）分配的名称：包级函数、变量和常量、导入别名、
+// 文件名和序列化字段名保持不变，只有新出现的名称重新生成。局部变量仍按本次运行重新命名
+func WithPreviousResult(prev *Result) Option {
+	return func(opts *options) { opts.previous = prev }
+}
+
+// applyOptions 合并可选参数，未设置 Logger 时使用 discardLogger
+func applyOptions(opts []Option) options {
+	o := options{logger: discardLogger{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.logger == nil {
+		o.logger = discardLogger{}
+	}
+	return o
+}
+
+// logf 输出进度信息
+func (o *Obfuscator) logf(format string, args ...interface{}) {
+	o.logger.Printf(format, args...)
+}
+
+// warnf 输出警告并发出 EventWarning 事件
+func (o *Obfuscator) warnf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	o.logger.Printf("警告: %s", msg)
+	o.emit(Event{Kind: EventWarning, Phase: o.currentPhase, Message: msg})
+}
+
+// emit 发出事件（未设置回调时忽略）
+func (o *Obfuscator) emit(e Event) {
+	if o.events != nil {
+		o.events(e)
+	}
+}
+
+// logf 输出链接器混淆的进度信息。多行文本按行输出，与原先直接打印到终端的格式一致
+func (lo *LinkerObfuscator) logf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	for _, line := range strings.Split(strings.TrimSuffix(msg, "\n"), "\n") {
+		lo.logger.Printf("%s", line)
+	}
+}
@@ -0,0 +1,122 @@
+package obfuscator
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// overlayJSON go build -overlay 读取的文件格式：原路径 -> 替换文件（空字符串表示删除）
+type overlayJSON struct {
+	Replace map[string]string
+}
+
+// overlayChanged 判断输出文件与原文件相比是否改名或内容有变化（解密包等新文件总是返回 true）
+func overlayChanged(f *FileContext, source []byte) bool {
+	if filepath.Base(f.OutputPath) != filepath.Base(f.original) {
+		return true
+	}
+	orig, err := ioutil.ReadFile(f.original)
+	return err != nil || !bytes.Equal(orig, source)
+}
+
+// writeOverlay 把写出的文件登记到 outputDir/overlay.json：输出文件按其相对输出目录的路径
+// 替换项目目录中的同名文件，改名的文件同时从原路径删除
+func (o *Obfuscator) writeOverlay(pkgs []*PackageContext) error {
+	root, err := filepath.Abs(o.projectRoot)
+	if err != nil {
+		return err
+	}
+	out, err := filepath.Abs(o.outputDir)
+	if err != nil {
+		return err
+	}
+
+	overlay := overlayJSON{Replace: make(map[string]string)}
+	for _, pkg := range pkgs {
+		for _, f := range pkg.Files {
+			if !f.written {
+				continue
+			}
+			output, err := filepath.Abs(f.OutputPath)
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(out, output)
+			if err != nil {
+				return err
+			}
+			overlay.Replace[filepath.Join(root, rel)] = output
+			if filepath.Base(f.OutputPath) != filepath.Base(f.original) {
+				original, err := filepath.Abs(f.original)
+				if err != nil {
+					return err
+				}
+				overlay.Replace[original] = ""
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(overlay, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(out, 0755); err != nil {
+		return err
+	}
+	path := filepath.Join(out, "overlay.json")
+	if err := ioutil.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	o.overlayFile = path
+	o.logf("已写出 %d 个改动的文件和 %s", countWritten(pkgs), path)
+	return nil
+}
+
+// overlayDirs 返回 overlay.json 中替换或新增的文件所在的目录（相对 root，去重排序）
+func overlayDirs(path, root string) ([]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var overlay overlayJSON
+	if err := json.Unmarshal(data, &overlay); err != nil {
+		return nil, err
+	}
+	root, err = filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var dirs []string
+	for from, to := range overlay.Replace {
+		if to == "" {
+			continue
+		}
+		dir, err := filepath.Rel(root, filepath.Dir(from))
+		if err != nil || dir == ".." || strings.HasPrefix(dir, ".."+string(filepath.Separator)) || seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+// countWritten 统计写出的文件数
+func countWritten(pkgs []*PackageContext) int {
+	n := 0
+	for _, pkg := range pkgs {
+		for _, f := range pkg.Files {
+			if f.written {
+				n++
+			}
+		}
+	}
+	return n
+}
@@ -0,0 +1,60 @@
+package obfuscator_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cross-file-obfuscator/obfuscator"
+)
+
+// TestOverlayMode overlay 模式只写出改动的文件，原项目保持不变，go build -overlay 和 VerifyTests 使用混淆后的代码
+func TestOverlayMode(t *testing.T) {
+	skipIfShort(t)
+	project := copyTestdata(t, "testsapp")
+	original, err := os.ReadFile(filepath.Join(project, "calc", "calc.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := obfuscator.DefaultConfig()
+	config.Overlay = true
+	config.EncryptStrings = true
+	config.ObfuscateFileNames = true
+	obf, result := obfuscate(t, project, config)
+	if result.Overlay == "" {
+		t.Fatal("overlay 模式应写出 overlay.json")
+	}
+
+	data, err := os.ReadFile(result.Overlay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var overlay struct{ Replace map[string]string }
+	if err := json.Unmarshal(data, &overlay); err != nil {
+		t.Fatal(err)
+	}
+	calc := filepath.Join(project, "calc", "calc.go")
+	if _, ok := overlay.Replace[calc]; !ok {
+		t.Errorf("overlay.json 中缺少 %s: %v", calc, overlay.Replace)
+	}
+	for from, to := range overlay.Replace {
+		if !filepath.IsAbs(from) || (to != "" && !filepath.IsAbs(to)) {
+			t.Errorf("overlay.json 应使用绝对路径: %s -> %s", from, to)
+		}
+	}
+
+	if now, err := os.ReadFile(calc); err != nil || string(now) != string(original) {
+		t.Error("overlay 模式不应修改原项目")
+	}
+	if _, err := os.Stat(filepath.Join(result.OutputDir, "go.mod")); !os.IsNotExist(err) {
+		t.Error("overlay 模式不应复制未改动的文件")
+	}
+
+	runGo(t, project, "build", "-overlay", result.Overlay, "./...")
+	if output, err := obf.VerifyTests(context.Background()); err != nil {
+		t.Fatalf("VerifyTests 失败: %v\n%s", err, output)
+	}
+}
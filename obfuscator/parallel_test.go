@@ -0,0 +1,65 @@
+package obfuscator_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cross-file-obfuscator/obfuscator"
+)
+
+// TestWorkersDeterministic 相同种子下 -j 1 与 -j 8 的输出目录逐字节相同
+func TestWorkersDeterministic(t *testing.T) {
+	for _, name := range []string{"app", "testsapp"} {
+		t.Run(name, func(t *testing.T) {
+			project := copyTestdata(t, name)
+			run := func(workers int) string {
+				config := obfuscator.DefaultConfig()
+				config.EncryptStrings = true
+				config.InjectJunkCode = true
+				config.ObfuscateExported = true
+				config.ObfuscateFileNames = true
+				config.Workers = workers
+				_, result := obfuscate(t, project, config)
+				return result.OutputDir
+			}
+			serial, parallel := readTree(t, run(1)), readTree(t, run(8))
+			if len(serial) != len(parallel) {
+				t.Errorf("文件数不同: -j 1 %d 个，-j 8 %d 个", len(serial), len(parallel))
+			}
+			for path, content := range serial {
+				if other, ok := parallel[path]; !ok {
+					t.Errorf("-j 8 的输出缺少 %s", path)
+				} else if other != content {
+					t.Errorf("%s 的内容随并发数变化", path)
+				}
+			}
+		})
+	}
+}
+
+// readTree 读取目录下的全部文件：相对路径 -> 内容
+func readTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
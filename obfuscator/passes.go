@@ -0,0 +1,315 @@
+package obfuscator
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Pass 转换阶段的一道处理。每个包调用一次 Apply，包内的文件都已加载；
+// 不同包的 Apply 可能被多个 goroutine 同时调用。
+// 只有直接或间接依赖 format 的 Pass（例如内置的 strings）排在 format 之后，修改 FileContext.Source；
+// 其余 Pass（内置的 comments、rename、junk 和不依赖 format 的自定义 Pass）修改语法树，
+// 必须排在 format 之前，否则修改会在格式化后丢失
+type Pass interface {
+	Name() string
+	Dependencies() []string // 必须排在本 Pass 之前的 Pass
+	Apply(pkg *PackageContext) error
+}
+
+// 内置 Pass 的名称
+const (
+	PassComments = "comments" // 移除注释（RemoveComments）
+	PassRename   = "rename"   // 导入别名、包级名称、局部变量和序列化字段改名
+	PassJunk     = "junk"     // 注入垃圾代码（InjectJunkCode）
+	PassFormat   = "format"   // 把语法树格式化为源码，之后的 Pass 修改 FileContext.Source
+	PassStrings  = "strings"  // 加密字符串字面量（EncryptStrings）
+)
+
+// PackageContext 传给 Pass 的一个包：同一输出目录下同名包的全部待混淆文件
+type PackageContext struct {
+	Name   string // 包名
+	Dir    string // 包目录（相对输出目录）
+	Fset   *token.FileSet
+	Files  []*FileContext
+	Config *Config // 只读
+
+	obf      *Obfuscator
+	duration time.Duration // 执行全部 Pass 并写出文件的耗时
+}
+
+// FileContext 包中的一个文件
+type FileContext struct {
+	Path       string    // 原始文件（相对项目目录）
+	OutputPath string    // 输出文件的路径
+	AST        *ast.File // format 之前的 Pass 直接修改语法树
+	Source     []byte    // format 生成的源码，之后的 Pass 修改它；写出时为空则格式化 AST
+
+	original string // 原始文件路径（与 skippedFiles 等的键一致）
+	written  bool   // 是否写出了输出文件（overlay 模式下内容未变的文件不写出）
+
+	// 内置 Pass 的统计，用于运行报告
+	identsRenamed    int
+	stringsEncrypted int
+	encryptedBytes   int
+	junkBlocks       int
+	duration         time.Duration // 内置 Pass 处理和写出该文件的耗时
+}
+
+// Warnf 输出警告并发出 EventWarning 事件
+func (p *PackageContext) Warnf(format string, args ...interface{}) {
+	p.obf.warnf(format, args...)
+}
+
+// builtinPass 内置 Pass：按文件处理，enabled 为 nil 时总是启用
+type builtinPass struct {
+	name    string
+	deps    []string
+	enabled func(*Config) bool
+	apply   func(o *Obfuscator, f *FileContext) error
+}
+
+func (b *builtinPass) Name() string           { return b.name }
+func (b *builtinPass) Dependencies() []string { return b.deps }
+
+func (b *builtinPass) Apply(pkg *PackageContext) error {
+	for _, f := range pkg.Files {
+		start := time.Now()
+		err := b.apply(pkg.obf, f)
+		f.duration += time.Since(start)
+		if err != nil {
+			return fmt.Errorf("%s: %v", f.Path, err)
+		}
+	}
+	return nil
+}
+
+// builtinPasses 内置 Pass，默认按此顺序执行（自定义 Pass 默认插在 format 之前）
+var builtinPasses = []*builtinPass{
+	{
+		name:    PassComments,
+		enabled: func(c *Config) bool { return c.RemoveComments },
+		apply: func(o *Obfuscator, f *FileContext) error {
+			o.removeComments(f.AST)
+			return nil
+		},
+	},
+	{
+		name: PassRename,
+		apply: func(o *Obfuscator, f *FileContext) error {
+			// 应用转换（使用作用域信息），再按类型检查结果逐位置改名（局部变量、序列化字段）并写入合成的标签
+			n := o.applyTransformationsWithScope(f.AST, f.original) + o.applyIdentRenames(f.AST, f.original)
+			o.renamedIdents.Add(int64(n))
+			f.identsRenamed += n
+			return nil
+		},
+	},
+	{
+		name:    PassJunk,
+		enabled: func(c *Config) bool { return c.InjectJunkCode },
+		apply: func(o *Obfuscator, f *FileContext) error {
+			n := o.injectJunkCode(f.AST, f.original)
+			o.junkBlocks.Add(int64(n))
+			f.junkBlocks += n
+			return nil
+		},
+	},
+	{
+		name: PassFormat,
+		apply: func(o *Obfuscator, f *FileContext) error {
+			var buf bytes.Buffer
+			if err := format.Node(&buf, o.fset, f.AST); err != nil {
+				return fmt.Errorf("格式化失败: %v", err)
+			}
+			f.Source = buf.Bytes()
+			return nil
+		},
+	},
+	{
+		name:    PassStrings,
+		deps:    []string{PassFormat},
+		enabled: func(c *Config) bool { return c.EncryptStrings },
+		apply: func(o *Obfuscator, f *FileContext) error {
+			// cgo 文件只在启用 EncryptCgoStrings 时加密
+			if isCgoFile(f.AST) && !o.Config.EncryptCgoStrings {
+				return nil
+			}
+			if !o.encryptStringsInAST(f.AST) {
+				return nil
+			}
+			// 加密字符串字面量（使用解密包的函数），实际加密了字符串时才导入解密包
+			if encrypted, n, size := o.encryptStringsInSourceWithPackage(string(f.Source)); n > 0 {
+				f.Source = []byte(o.ensureDecryptPackageImport(encrypted))
+				o.stringsEncrypted.Add(int64(n))
+				f.stringsEncrypted += n
+				f.encryptedBytes += size
+			}
+			return nil
+		},
+	},
+}
+
+var (
+	passMu     sync.RWMutex
+	passByName = make(map[string]Pass)
+	customPass []string // 自定义 Pass，按注册顺序
+)
+
+func init() {
+	for _, p := range builtinPasses {
+		passByName[p.name] = p
+	}
+}
+
+// RegisterPass 注册自定义 Pass，通常在 init 中调用。注册后默认对所有混淆器生效，
+// 排在内置的 format 之前（依赖 format 时排在其后）；Config.Passes 可以调整顺序或排除它。
+// 名称为空或已被注册时 panic
+func RegisterPass(p Pass) {
+	passMu.Lock()
+	defer passMu.Unlock()
+	if p == nil || p.Name() == "" {
+		panic("obfuscator: RegisterPass 需要非空的 Pass 和名称")
+	}
+	if _, dup := passByName[p.Name()]; dup {
+		panic("obfuscator: Pass 重复注册: " + p.Name())
+	}
+	passByName[p.Name()] = p
+	customPass = append(customPass, p.Name())
+}
+
+// RegisteredPasses 返回全部可用的 Pass 名称（默认顺序）
+func RegisteredPasses() []string {
+	passMu.RLock()
+	defer passMu.RUnlock()
+	return defaultPassOrder()
+}
+
+// defaultPassOrder 内置 Pass 中 format 之前的部分、自定义 Pass、format 及之后的内置 Pass
+func defaultPassOrder() []string {
+	var names []string
+	for _, p := range builtinPasses {
+		if p.name == PassFormat {
+			names = append(names, customPass...)
+		}
+		names = append(names, p.name)
+	}
+	return names
+}
+
+// passPipeline 按配置确定本次执行的 Pass 及顺序。Config.Passes 为空时使用默认顺序，
+// 并按依赖稳定排序；显式指定时按给定顺序执行，不满足依赖则报错。
+// 内置 Pass 还需对应的开关（RemoveComments 等）开启才会执行
+func (o *Obfuscator) passPipeline() ([]Pass, error) {
+	passMu.RLock()
+	defer passMu.RUnlock()
+
+	names := o.Config.Passes
+	explicit := len(names) > 0
+	if !explicit {
+		names = defaultPassOrder()
+	}
+
+	var passes []Pass
+	index := make(map[string]int)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		p, ok := passByName[name]
+		if !ok {
+			return nil, fmt.Errorf("未知的 Pass: %q（可用: %s）", name, strings.Join(defaultPassOrder(), ", "))
+		}
+		if _, dup := index[name]; dup {
+			return nil, fmt.Errorf("Pass %s 重复出现", name)
+		}
+		if b, ok := p.(*builtinPass); ok && b.enabled != nil && !b.enabled(o.Config) {
+			continue
+		}
+		index[name] = len(passes)
+		passes = append(passes, p)
+	}
+
+	formatAt, hasFormat := index[PassFormat]
+	for i, p := range passes {
+		// 修改语法树的 Pass 隐含排在 format 之前
+		if explicit && hasFormat && i > formatAt && !editsSource(p, make(map[string]bool)) {
+			return nil, fmt.Errorf("Pass %s 修改语法树，必须排在 %s 之前（只有依赖 %s 的 Pass 可以排在其后）", p.Name(), PassFormat, PassFormat)
+		}
+		for _, dep := range p.Dependencies() {
+			j, ok := index[dep]
+			if !ok {
+				return nil, fmt.Errorf("Pass %s 依赖 %s，但后者未启用", p.Name(), dep)
+			}
+			if explicit && j > i {
+				return nil, fmt.Errorf("Pass %s 依赖 %s，必须排在其后", p.Name(), dep)
+			}
+		}
+	}
+	if explicit {
+		return passes, nil
+	}
+	return sortPasses(passes)
+}
+
+// editsSource 判断 Pass 是否在 format 之后修改 FileContext.Source，即直接或间接依赖 format
+func editsSource(p Pass, seen map[string]bool) bool {
+	if p.Name() == PassFormat || seen[p.Name()] {
+		return false
+	}
+	seen[p.Name()] = true
+	for _, dep := range p.Dependencies() {
+		if dep == PassFormat {
+			return true
+		}
+		if d, ok := passByName[dep]; ok && editsSource(d, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortPasses 按依赖稳定排序：每次取第一个依赖都已就位的 Pass，无依赖约束时保持原顺序
+func sortPasses(passes []Pass) ([]Pass, error) {
+	placed := make(map[string]bool)
+	sorted := make([]Pass, 0, len(passes))
+	remaining := append([]Pass(nil), passes...)
+	for len(remaining) > 0 {
+		next := -1
+		for i, p := range remaining {
+			ready := true
+			for _, dep := range p.Dependencies() {
+				if !placed[dep] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			var names []string
+			for _, p := range remaining {
+				names = append(names, p.Name())
+			}
+			return nil, fmt.Errorf("Pass 之间存在循环依赖: %s", strings.Join(names, ", "))
+		}
+		placed[remaining[next].Name()] = true
+		sorted = append(sorted, remaining[next])
+		remaining = append(remaining[:next], remaining[next+1:]...)
+	}
+	return sorted, nil
+}
+
+// passNames 返回 Pass 名称列表，用于日志
+func passNames(passes []Pass) []string {
+	names := make([]string, len(passes))
+	for i, p := range passes {
+		names[i] = p.Name()
+	}
+	return names
+}
@@ -0,0 +1,130 @@
+package obfuscator_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"cross-file-obfuscator/obfuscator"
+)
+
+// testPass 只声明名称和依赖的自定义 Pass
+type testPass struct {
+	name string
+	deps []string
+}
+
+func (p testPass) Name() string                               { return p.name }
+func (p testPass) Dependencies() []string                     { return p.deps }
+func (p testPass) Apply(pkg *obfuscator.PackageContext) error { return nil }
+
+// registerTestPass 注册自定义 Pass，测试结束时撤销
+func registerTestPass(t *testing.T, name string, deps ...string) {
+	t.Helper()
+	obfuscator.RegisterPass(testPass{name: name, deps: deps})
+	t.Cleanup(func() { obfuscator.UnregisterPass(name) })
+}
+
+// TestRegisterPassPanics 名称为空、与内置或已注册的 Pass 重名时 panic
+func TestRegisterPassPanics(t *testing.T) {
+	registerTestPass(t, "test-dup")
+	for _, tc := range []struct {
+		name string
+		pass obfuscator.Pass
+	}{
+		{"nil", nil},
+		{"empty name", testPass{}},
+		{"builtin", testPass{name: obfuscator.PassRename}},
+		{"duplicate", testPass{name: "test-dup"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("RegisterPass 应 panic")
+				}
+			}()
+			obfuscator.RegisterPass(tc.pass)
+		})
+	}
+}
+
+// TestPassOrder 默认顺序、自定义 Pass 的插入位置以及显式顺序的约束
+func TestPassOrder(t *testing.T) {
+	registerTestPass(t, "test-ast")
+	registerTestPass(t, "test-source", obfuscator.PassFormat)
+	registerTestPass(t, "test-after-strings", obfuscator.PassStrings)
+
+	all := func(passes string) *obfuscator.Config {
+		config := obfuscator.DefaultConfig()
+		config.RemoveComments = true
+		config.InjectJunkCode = true
+		config.EncryptStrings = true
+		if passes != "" {
+			config.Passes = strings.Split(passes, ",")
+		}
+		return config
+	}
+	minimal := func(passes string) *obfuscator.Config {
+		config := all(passes)
+		config.RemoveComments = false
+		config.InjectJunkCode = false
+		config.EncryptStrings = false
+		return config
+	}
+
+	tests := []struct {
+		name   string
+		config *obfuscator.Config
+		want   string // 期望的执行顺序
+		err    string // 期望的错误片段
+	}{
+		{name: "default", config: all(""), want: "comments,rename,junk,test-ast,format,test-source,strings,test-after-strings"},
+		{name: "disabled builtins are dropped", config: minimal("comments,rename,junk,test-ast,format,test-source"), want: "rename,test-ast,format,test-source"},
+		{name: "dependency on disabled builtin", config: minimal(""), err: "未启用"},
+		{name: "explicit", config: all("rename,format,strings"), want: "rename,format,strings"},
+		{name: "custom ast before format", config: all("test-ast,rename,format"), want: "test-ast,rename,format"},
+		{name: "source pass after format", config: all("rename,format,test-source"), want: "rename,format,test-source"},
+		{name: "indirect format dependency", config: all("format,strings,test-after-strings"), want: "format,strings,test-after-strings"},
+		{name: "builtin ast pass after format", config: all("format,rename"), err: "必须排在 format 之前"},
+		{name: "custom ast pass after format", config: all("rename,format,test-ast"), err: "必须排在 format 之前"},
+		{name: "dependency order", config: all("strings,format"), err: "必须排在其后"},
+		{name: "missing dependency", config: all("rename,strings"), err: "未启用"},
+		{name: "unknown", config: all("rename,nope"), err: "未知的 Pass"},
+		{name: "duplicate", config: all("rename,rename"), err: "重复出现"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := obfuscator.PassOrder(tc.config)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("期望包含 %q 的错误，得到 %v (%v)", tc.err, err, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := strings.Split(tc.want, ","); !reflect.DeepEqual(got, want) {
+				t.Errorf("顺序 %v，期望 %v", got, want)
+			}
+		})
+	}
+}
+
+// TestPassOrderCycle 自定义 Pass 之间的循环依赖在默认顺序下报错
+func TestPassOrderCycle(t *testing.T) {
+	registerTestPass(t, "test-a", "test-b")
+	registerTestPass(t, "test-b", "test-a")
+	if _, err := obfuscator.PassOrder(obfuscator.DefaultConfig()); err == nil || !strings.Contains(err.Error(), "循环依赖") {
+		t.Errorf("期望循环依赖错误，得到 %v", err)
+	}
+}
+
+// TestRegisteredPasses 自定义 Pass 默认排在 format 之前
+func TestRegisteredPasses(t *testing.T) {
+	registerTestPass(t, "test-listed")
+	want := []string{"comments", "rename", "junk", "test-listed", "format", "strings"}
+	if got := obfuscator.RegisteredPasses(); !reflect.DeepEqual(got, want) {
+		t.Errorf("RegisteredPasses() = %v，期望 %v", got, want)
+	}
+}
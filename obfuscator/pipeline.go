@@ -2,178 +2,424 @@ package obfuscator
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"go/ast"
 	"go/format"
-	"go/parser"
 	"go/token"
 	"io"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 )
 
-// Run 执行整个混淆流程
-func (o *Obfuscator) Run() error {
-	// 如果启用了字符串加密，创建解密包并保护相关名称
+// Run 执行整个混淆流程：先分析项目（Analyze），再写出混淆后的代码。
+// ctx 取消时在当前阶段结束后（转换阶段在已开始的文件完成后）返回 ctx.Err()
+func (o *Obfuscator) Run(ctx context.Context) (*Result, error) {
+	// 先确定 Pass 顺序，配置错误时不做任何分析和输出
+	passes, err := o.passPipeline()
+	if err != nil {
+		return nil, err
+	}
+	if err := o.prepareIO(); err != nil {
+		return nil, err
+	}
+	defer o.cleanupIO()
+
+	if err := o.analyze(ctx); err != nil {
+		return nil, err
+	}
+	if err := o.transform(ctx, passes); err != nil {
+		return nil, err
+	}
+	if o.stagedOutput {
+		if err := o.flushOutput(); err != nil {
+			return nil, fmt.Errorf("写入输出失败: %v", err)
+		}
+	}
+	result := o.result()
+	if o.output != nil {
+		if err := o.output.Close(); err != nil {
+			return nil, fmt.Errorf("关闭输出失败: %v", err)
+		}
+	}
+	return result, nil
+}
+
+// Analyze 扫描项目、收集受保护名称并构建混淆映射，不写出混淆后的代码。
+// 之后可以用 Explain 查询某个名称未被混淆的原因
+func (o *Obfuscator) Analyze(ctx context.Context) error {
+	if err := o.prepareIO(); err != nil {
+		return err
+	}
+	defer o.cleanupIO()
+	return o.analyze(ctx)
+}
+
+// analyze 执行分析阶段（阶段 0-3）
+func (o *Obfuscator) analyze(ctx context.Context) error {
+	// 如果启用了字符串加密，提前保护解密函数名称和包名
 	if o.Config.EncryptStrings {
-		// 提前保护解密函数名称和包名
-		o.protectedNames[o.decryptFuncName] = true
+		o.protect(o.decryptFuncName, reasonDecryptFunc, "", token.NoPos)
 		o.packageNames[o.decryptPkgName] = true
-		
-		if err := o.createDecryptPackage(); err != nil {
-			return fmt.Errorf("创建解密包失败: %v", err)
+	}
+
+	// 如果启用了依赖混淆，先把依赖 vendor 到输出目录，后续各阶段一并处理
+	if o.Config.ObfuscateVendor {
+		if err := o.vendorDependencies(ctx); err != nil {
+			return fmt.Errorf("vendor 依赖失败: %v", err)
 		}
 	}
 
-	log.Println("阶段 0/5: 收集导入信息...")
-	if err := o.collectImportInfo(); err != nil {
-		return fmt.Errorf("收集导入信息失败: %v", err)
+	// 记录 //go:embed 嵌入的文件（包括被跳过文件中的指令）
+	o.collectEmbeddedFiles()
+
+	if err := ctx.Err(); err != nil {
+		return err
 	}
+	o.logf("阶段 0/5: 加载源码并收集导入信息...")
+	// 每个文件只解析一次，后续阶段（包括类型检查和转换）共用这些语法树
+	done := o.beginPhase("加载源码")
+	if err := o.loadSources(); err != nil {
+		return fmt.Errorf("加载源码失败: %v", err)
+	}
+	done()
+	o.logf("已解析 %d 个文件（%d 个包）", len(o.sourceFiles), len(o.sourcePackages))
+	done = o.beginPhase("收集导入信息")
+	o.collectImportInfo()
+	done()
 
-	log.Println("阶段 1/5: 扫描项目并收集保护名称...")
-	err := filepath.Walk(o.projectRoot, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if info.IsDir() || !strings.HasSuffix(path, ".go") {
-			return nil
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	o.logf("阶段 1/5: 扫描项目并收集保护名称...")
+	// 类型检查结果供反射分析和局部变量按对象改名使用，失败时只做基于名称的分析
+	done = o.beginPhase("类型检查")
+	if err := o.loadPackages(ctx); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
 		}
-		if strings.Contains(path, "vendor/") {
-			return nil
+		o.warnf("类型检查失败，回退到基于名称的分析: %v", err)
+	}
+	if o.Config.PreserveReflection && len(o.typedPkgs) > 0 {
+		o.analyzeReflection()
+	}
+	done()
+	done = o.beginPhase("收集保护名称")
+	for _, file := range o.sourceFiles {
+		path, node := file.path, file.node
+		if _, skipped := o.skippedFiles[path]; skipped {
+			continue
 		}
 
-		// 检查是否跳过生成代码
-		if o.Config.SkipGeneratedCode && o.isGeneratedFile(path) {
-			o.skippedFiles[path] = "Generated code"
-			return nil
+		// 检查生成代码：按生成器策略跳过、只混淆私有名称或完整混淆
+		generator, privateOnly := "", false
+		if o.Config.SkipGeneratedCode {
+			var generated bool
+			if generator, generated = o.generatedFileInfo(path, node); generated {
+				switch o.generatedPolicy(generator) {
+				case GeneratedSkip:
+					o.skippedFiles[path] = "Generated code"
+					if generator != "" {
+						o.skippedFiles[path] += " (" + generator + ")"
+					}
+					o.protectSkippedFileDecls(node, reasonGenerated, generatorLabel(generator))
+					continue
+				case GeneratedPrivate:
+					privateOnly = true
+				}
+			}
 		}
 
 		// 检查是否排除文件
-		if o.isExcluded(path) {
+		if excluded, why := o.shouldExcludeFile(path); excluded {
 			o.skippedFiles[path] = "Excluded by pattern"
-			return nil
-		}
-
-		// 解析文件
-		node, err := parser.ParseFile(o.fset, path, nil, parser.ParseComments)
-		if err != nil {
-			log.Printf("警告: 无法解析文件 %s: %v", path, err)
-			o.skippedFiles[path] = fmt.Sprintf("Parse error: %v", err)
-			return nil
+			o.protectSkippedFileDecls(node, reasonExcludedFile, why)
+			continue
 		}
 
 		// 收集保护名称
 		o.collectProtectedNames(node)
+		if privateOnly {
+			o.protectFileDecls(node, reasonGenerated, generatorLabel(generator)+"，只混淆私有名称", true)
+		}
+		o.protectLinknameTargets(node)
+		o.protectCgoNames(node, path)
+		o.protectTemplateRefs(node, path)
+		o.protectTestEntryPoints(node, path)
 
-		// 检查反射使用
-		if o.Config.PreserveReflection {
-			o.protectReflectionTypes(node)
+		// 依赖包的导出 API 保持不变
+		if o.isVendoredPath(path) {
+			o.protectVendoredExportedAPI(node)
 		}
 
-		return nil
-	})
-	if err != nil {
-		return fmt.Errorf("扫描项目失败: %v", err)
+		// 类型检查未覆盖的文件（依赖、类型检查失败或存在无法追踪的接口值）回退到基于导入的保护
+		if o.Config.PreserveReflection && o.needsReflectionHeuristic(path) {
+			o.protectReflectionTypes(node)
+		}
+	}
+	// 模板在运行时按名称访问字段和方法；Go 代码中引用的模板文件已按其分隔符解析过
+	o.collectTemplateFiles()
+	if len(o.fieldRenamePlan) > 0 {
+		o.finalizeFieldRenames()
 	}
+	done()
 
-	log.Println("阶段 2/5: 构建作用域分析...")
-	if err := o.buildScopeAnalysis(); err != nil {
-		return fmt.Errorf("作用域分析失败: %v", err)
+	if err := ctx.Err(); err != nil {
+		return err
 	}
+	o.logf("阶段 2/5: 构建作用域分析...")
+	done = o.beginPhase("作用域分析")
+	o.buildScopeAnalysis()
+	done()
 
-	log.Println("阶段 3/5: 构建混淆映射...")
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	o.logf("阶段 3/5: 构建混淆映射...")
+	done = o.beginPhase("构建混淆映射")
 	o.buildObfuscationMapsWithScope()
+	o.renameExamples()
+	o.planObjectRenames()
+	o.planLocalRenames()
+	done()
+
+	// 记录混淆可能影响运行行为的位置（反射、unsafe、linkname、plugin），写入运行报告
+	o.scanRisks()
+	o.measureCoverage()
+	return nil
+}
+
+// transform 复制项目并应用混淆（阶段 4、5），需在 Analyze 之后调用
+func (o *Obfuscator) transform(ctx context.Context, passes []Pass) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// 如果启用了字符串加密，创建解密包
+	if o.Config.EncryptStrings {
+		if err := o.createDecryptPackage(); err != nil {
+			return fmt.Errorf("创建解密包失败: %v", err)
+		}
+	}
+
+	// 增量运行时确定需要重写的包（名称映射此时已经确定）
+	o.expandIncremental()
 
-	log.Println("阶段 4/5: 复制项目文件...")
+	o.logf("阶段 4/5: 复制项目文件...")
 	// 构建文件名映射（原始路径 -> 混淆后路径）
+	done := o.beginPhase("复制项目文件")
 	fileMapping := make(map[string]string)
 	if err := o.copyProjectAndBuildMapping(fileMapping); err != nil {
 		return fmt.Errorf("复制项目失败: %v", err)
 	}
+	o.fileMapping = fileMapping
+	done()
 
-	log.Println("阶段 5/5: 应用混淆...")
-	// 第一遍：只处理非平台特定的文件（优先添加解密函数）
-	err = filepath.Walk(o.outputDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if info.IsDir() || !strings.HasSuffix(path, ".go") {
-			return nil
-		}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
+	o.logf("阶段 5/5: 应用混淆...")
+	done = o.beginPhase("应用混淆")
+	defer done()
+	// 收集需要处理的文件（按路径排序）。解密包已在转换前创建，文件之间没有顺序依赖
+	keep := func(path string) bool {
 		// 检查是否跳过文件
-		relPath, _ := filepath.Rel(o.outputDir, path)
-		originalPath := filepath.Join(o.projectRoot, relPath)
+		originalPath := o.originalPathFor(path, fileMapping)
 		if _, skipped := o.skippedFiles[originalPath]; skipped {
-			return nil
+			return false
 		}
-
-		// 只处理非平台特定的文件
-		if !o.isFilePlatformSpecific(path) {
-			if err := o.obfuscateFileWithMapping(path, fileMapping); err != nil {
-				return fmt.Errorf("混淆文件 %s 失败: %v", path, err)
+		if o.isIgnoredVendorPath(originalPath) {
+			return false
+		}
+		return o.rewritesDir(originalPath)
+	}
+	var files []string
+	walkRoot := o.outputDir
+	if o.Config.Overlay {
+		// overlay 模式下输出目录中只有解密包，其他文件来自映射
+		for path := range fileMapping {
+			if keep(path) {
+				files = append(files, path)
 			}
 		}
+		walkRoot = ""
+		if o.decryptPkgCreated {
+			walkRoot = filepath.Join(o.outputDir, o.decryptPkgName)
+		}
+	}
+	if walkRoot != "" {
+		err := filepath.Walk(walkRoot, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || !strings.HasSuffix(path, ".go") {
+				return nil
+			}
+			if keep(path) {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("应用混淆失败: %v", err)
+		}
+	}
+	sort.Strings(files)
 
-		return nil
-	})
+	o.logf("转换 Pass: %s", strings.Join(passNames(passes), " → "))
+	pkgs, err := o.packageContexts(files, fileMapping)
 	if err != nil {
-		return fmt.Errorf("应用混淆失败（第一遍）: %v", err)
+		return fmt.Errorf("应用混淆失败: %v", err)
+	}
+	o.transformed = pkgs
+	o.passOrder = passNames(passes)
+	if err := o.transformPackages(ctx, pkgs, passes); err != nil {
+		return err
 	}
+	if o.Config.Overlay {
+		if err := o.writeOverlay(pkgs); err != nil {
+			return fmt.Errorf("写入 overlay 文件失败: %v", err)
+		}
+	}
+	return nil
+}
 
-	// 第二遍：处理平台特定的文件（如果包还没有解密函数，在第一个遇到的文件中添加）
-	err = filepath.Walk(o.outputDir, func(path string, info os.FileInfo, err error) error {
+// packageContexts 按输出目录和包名把文件分组为 PackageContext，包按首个文件的路径排序
+func (o *Obfuscator) packageContexts(files []string, fileMapping map[string]string) ([]*PackageContext, error) {
+	var pkgs []*PackageContext
+	byKey := make(map[string]*PackageContext)
+	for _, path := range files {
+		originalPath := o.originalPathFor(path, fileMapping)
+		// 使用加载阶段的语法树（输出文件是原文件的副本）
+		node, err := o.sourceAST(originalPath, path)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("解析文件 %s 失败: %v", path, err)
+		}
+
+		key := filepath.Dir(path) + "#" + node.Name.Name
+		pkg, ok := byKey[key]
+		if !ok {
+			dir, _ := filepath.Rel(o.outputDir, filepath.Dir(path))
+			pkg = &PackageContext{Name: node.Name.Name, Dir: dir, Fset: o.fset, Config: o.Config, obf: o}
+			byKey[key] = pkg
+			pkgs = append(pkgs, pkg)
+		}
+		pkg.Files = append(pkg.Files, &FileContext{
+			Path:       o.displayPath(originalPath),
+			OutputPath: path,
+			AST:        node,
+			original:   originalPath,
+		})
+	}
+	return pkgs, nil
+}
+
+// transformPackages 用工作池并发处理各个包。转换阶段只读取分析阶段的结果，
+// 随机性按文件派生（fileRand），因此输出与并发数无关。多个包失败时返回最靠前的错误；
+// ctx 取消后不再分派新的包
+func (o *Obfuscator) transformPackages(ctx context.Context, pkgs []*PackageContext, passes []Pass) error {
+	workers := o.Config.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(pkgs) {
+		workers = len(pkgs)
+	}
+	files := 0
+	for _, pkg := range pkgs {
+		files += len(pkg.Files)
+	}
+	o.logf("使用 %d 个并发任务处理 %d 个包（%d 个文件）", workers, len(pkgs), files)
+
+	errs := make([]error, len(pkgs))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := o.transformPackage(pkgs[i], passes); err != nil {
+					errs[i] = fmt.Errorf("混淆包 %s 失败: %v", pkgs[i].Dir, err)
+				}
+			}
+		}()
+	}
+dispatch:
+	for i := range pkgs {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break dispatch
 		}
-		if info.IsDir() || !strings.HasSuffix(path, ".go") {
-			return nil
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for _, err := range errs {
+		if err != nil {
+			return fmt.Errorf("应用混淆失败: %v", err)
 		}
+	}
+	return nil
+}
 
-		// 检查是否跳过文件
-		relPath, _ := filepath.Rel(o.outputDir, path)
-		originalPath := filepath.Join(o.projectRoot, relPath)
-		if _, skipped := o.skippedFiles[originalPath]; skipped {
-			return nil
+// transformPackage 依次执行各个 Pass，然后写出包中的文件
+func (o *Obfuscator) transformPackage(pkg *PackageContext, passes []Pass) error {
+	start := time.Now()
+	defer func() { pkg.duration = time.Since(start) }()
+	for _, p := range passes {
+		if err := p.Apply(pkg); err != nil {
+			return fmt.Errorf("Pass %s: %v", p.Name(), err)
 		}
+	}
 
-		// 只处理平台特定的文件
-		if o.isFilePlatformSpecific(path) {
-			if err := o.obfuscateFileWithMapping(path, fileMapping); err != nil {
-				return fmt.Errorf("混淆文件 %s 失败: %v", path, err)
+	for _, f := range pkg.Files {
+		written := time.Now()
+		source := f.Source
+		if source == nil {
+			var buf bytes.Buffer
+			if err := format.Node(&buf, o.fset, f.AST); err != nil {
+				return fmt.Errorf("格式化 %s 失败: %v", f.Path, err)
 			}
+			source = buf.Bytes()
 		}
-
-		return nil
-	})
-	if err != nil {
-		return fmt.Errorf("应用混淆失败（第二遍）: %v", err)
+		// overlay 模式下文件名和内容都没有变化的文件不写出
+		if !o.Config.Overlay || overlayChanged(f, source) {
+			if o.Config.Overlay {
+				if err := os.MkdirAll(filepath.Dir(f.OutputPath), 0755); err != nil {
+					return err
+				}
+			}
+			if err := ioutil.WriteFile(f.OutputPath, source, 0644); err != nil {
+				return err
+			}
+			f.written = true
+		}
+		f.duration += time.Since(written)
+		o.emit(Event{Kind: EventFileDone, Phase: o.currentPhase, File: f.Path})
 	}
-
 	return nil
 }
 
 // collectImportInfo 收集所有文件的导入信息
-func (o *Obfuscator) collectImportInfo() error {
-	return filepath.Walk(o.projectRoot, func(path string, info os.FileInfo, err error) error {
-		if err != nil || info.IsDir() || !strings.HasSuffix(path, ".go") {
-			return err
-		}
-		if strings.Contains(path, "vendor/") {
-			return nil
-		}
+func (o *Obfuscator) collectImportInfo() {
+	for _, file := range o.sourceFiles {
+		path, node := file.path, file.node
 
 		// 跳过排除的文件
 		if shouldExclude, _ := o.shouldExcludeFile(path); shouldExclude {
-			return nil
-		}
-
-		node, err := parser.ParseFile(o.fset, path, nil, parser.ParseComments)
-		if err != nil {
-			return nil // 跳过无法解析的文件
+			continue
 		}
 
 		// 处理所有导入
@@ -194,13 +440,13 @@ func (o *Obfuscator) collectImportInfo() error {
 				pkgName = imp.Name.Name
 			} else {
 				// 使用基础名称
-				pkgName = filepath.Base(pkgPath)
+				pkgName = importPathName(pkgPath)
 			}
 
 			// 存储映射
 			if existingName, exists := o.importPathToName[pkgPath]; exists {
 				if existingName != pkgName {
-					log.Printf("警告: 包 %s 的名称不一致: %s vs %s", pkgPath, existingName, pkgName)
+					o.warnf("包 %s 的名称不一致: %s vs %s", pkgPath, existingName, pkgName)
 				}
 			} else {
 				o.importPathToName[pkgPath] = pkgName
@@ -209,17 +455,21 @@ func (o *Obfuscator) collectImportInfo() error {
 			// 标记包名为受保护
 			o.packageNames[pkgName] = true
 
-			// 只为标准库创建别名
-			if isStandardLibrary(pkgPath) {
+			// 只为标准库创建别名（伪包 "C" 不能重命名）
+			if isStandardLibrary(pkgPath) && pkgPath != "C" {
 				if _, exists := o.importAliasMapping[pkgPath]; !exists {
-					alias := fmt.Sprintf("p%s", generateRandomString(8))
+					alias, ok := o.stable.get(stableAlias, pkgPath)
+					if !ok {
+						alias = fmt.Sprintf("p%s", o.randomString(8))
+						for !o.reserveName(alias) {
+							alias = fmt.Sprintf("p%s", o.randomString(8))
+						}
+					}
 					o.importAliasMapping[pkgPath] = alias
 				}
 			}
 		}
-
-		return nil
-	})
+	}
 }
 
 // collectProtectedNames 收集所有不应被混淆的名称
@@ -235,7 +485,7 @@ func (o *Obfuscator) collectProtectedNames(node *ast.File) {
 		if imp.Name != nil {
 			pkgName = imp.Name.Name
 		} else {
-			pkgName = filepath.Base(pkgPath)
+			pkgName = importPathName(pkgPath)
 		}
 		importPaths[pkgName] = pkgPath
 	}
@@ -249,16 +499,16 @@ func (o *Obfuscator) collectProtectedNames(node *ast.File) {
 					for _, field := range structType.Fields.List {
 						// 保护命名字段
 						for _, fieldName := range field.Names {
-							o.protectedNames[fieldName.Name] = true
+							o.protect(fieldName.Name, reasonStructField, x.Name.Name+"."+fieldName.Name, fieldName.Pos())
 						}
 						// 保护匿名字段
 						if len(field.Names) == 0 {
 							if ident, ok := field.Type.(*ast.Ident); ok {
-								o.protectedNames[ident.Name] = true
+								o.protect(ident.Name, reasonEmbeddedField, "嵌入在 "+x.Name.Name+" 中", ident.Pos())
 							}
 							if starExpr, ok := field.Type.(*ast.StarExpr); ok {
 								if ident, ok := starExpr.X.(*ast.Ident); ok {
-									o.protectedNames[ident.Name] = true
+									o.protect(ident.Name, reasonEmbeddedField, "嵌入在 "+x.Name.Name+" 中", ident.Pos())
 								}
 							}
 						}
@@ -270,7 +520,7 @@ func (o *Obfuscator) collectProtectedNames(node *ast.File) {
 				if interfaceType.Methods != nil {
 					for _, method := range interfaceType.Methods.List {
 						for _, methodName := range method.Names {
-							o.protectedNames[methodName.Name] = true
+							o.protect(methodName.Name, reasonInterfaceMethod, x.Name.Name+"."+methodName.Name, methodName.Pos())
 						}
 					}
 				}
@@ -279,11 +529,14 @@ func (o *Obfuscator) collectProtectedNames(node *ast.File) {
 		case *ast.SelectorExpr:
 			// 只混淆项目内部包的选择器，其他所有选择器都保护
 			shouldProtect := true
+			detail := x.Sel.Name + "（接收者是表达式）"
 			
 			if ident, ok := x.X.(*ast.Ident); ok {
 				pkgName := ident.Name
+				detail = pkgName + "." + x.Sel.Name + "（" + pkgName + " 不是导入的项目包）"
 				// 检查是否是导入的包
 				if pkgPath, exists := importPaths[pkgName]; exists {
+					detail = pkgPath + "." + x.Sel.Name
 					// 如果是项目内部的包，不保护（允许混淆）
 					if o.isProjectImportPath(pkgPath) {
 						shouldProtect = false
@@ -293,13 +546,13 @@ func (o *Obfuscator) collectProtectedNames(node *ast.File) {
 			}
 			
 			if shouldProtect {
-				o.protectedNames[x.Sel.Name] = true
+				o.protect(x.Sel.Name, reasonExternalSelector, detail, x.Sel.Pos())
 			}
 
 		case *ast.FuncDecl:
 			// 保护方法名
 			if x.Recv != nil {
-				o.protectedNames[x.Name.Name] = true
+				o.protect(x.Name.Name, reasonMethod, "", x.Name.Pos())
 			}
 		}
 		return true
@@ -374,22 +627,12 @@ func (o *Obfuscator) isProjectPackage(pkgName string) bool {
 
 // buildObfuscationMaps 构建混淆映射（旧版本，保留用于向后兼容）
 func (o *Obfuscator) buildObfuscationMaps() {
-	filepath.Walk(o.projectRoot, func(path string, info os.FileInfo, err error) error {
-		if err != nil || info.IsDir() || !strings.HasSuffix(path, ".go") {
-			return err
-		}
-		if strings.Contains(path, "vendor/") {
-			return nil
-		}
+	for _, file := range o.sourceFiles {
+		path, node := file.path, file.node
 
 		// 跳过排除的文件
 		if _, excluded := o.skippedFiles[path]; excluded {
-			return nil
-		}
-
-		node, err := parser.ParseFile(o.fset, path, nil, parser.ParseComments)
-		if err != nil {
-			return nil
+			continue
 		}
 
 		// 只收集包级别的函数和变量
@@ -423,37 +666,22 @@ func (o *Obfuscator) buildObfuscationMaps() {
 			}
 		}
 
-		return nil
-	})
+	}
 }
 
 // buildScopeAnalysis 为所有文件构建作用域分析
-func (o *Obfuscator) buildScopeAnalysis() error {
-	return filepath.Walk(o.projectRoot, func(path string, info os.FileInfo, err error) error {
-		if err != nil || info.IsDir() || !strings.HasSuffix(path, ".go") {
-			return err
-		}
-		if strings.Contains(path, "vendor/") {
-			return nil
-		}
-
+func (o *Obfuscator) buildScopeAnalysis() {
+	for _, file := range o.sourceFiles {
 		// 跳过排除的文件
-		if _, excluded := o.skippedFiles[path]; excluded {
-			return nil
-		}
-
-		node, err := parser.ParseFile(o.fset, path, nil, parser.ParseComments)
-		if err != nil {
-			return nil
+		if _, excluded := o.skippedFiles[file.path]; excluded {
+			continue
 		}
 
 		// 创建作用域分析器并分析文件
 		analyzer := NewScopeAnalyzer(o.fset)
-		analyzer.Analyze(node)
-		o.fileScopes[path] = analyzer
-
-		return nil
-	})
+		analyzer.Analyze(file.node)
+		o.fileScopes[file.path] = analyzer
+	}
 }
 
 // buildObfuscationMapsWithScope 使用作用域分析构建混淆映射
@@ -465,7 +693,7 @@ func (o *Obfuscator) buildObfuscationMapsWithScope() {
 	for filePath, analyzer := range o.fileScopes {
 		fileScope := analyzer.GetFileScope()
 		if fileScope == nil {
-			log.Printf("警告: 文件 %s 没有文件作用域", filePath)
+			o.warnf("文件 %s 没有文件作用域", filePath)
 			continue
 		}
 
@@ -485,19 +713,28 @@ func (o *Obfuscator) buildObfuscationMapsWithScope() {
 		o.collectObjectsForObfuscation(fileScope)
 	}
 
+	// 按文件和位置排序，之后按名称顺序分配混淆名：给定种子时结果可以复现
+	sortObjects(allPackageLevelObjects)
+
 	// 第二步：按名称分组对象（方案1 + build-tag支持）
 	// 同名的对象将使用相同的混淆名（支持build-tag场景）
 	nameToObjects := make(map[string][]*Object)
+	var names []string
 	for _, obj := range allPackageLevelObjects {
+		if _, seen := nameToObjects[obj.Name]; !seen {
+			names = append(names, obj.Name)
+		}
 		nameToObjects[obj.Name] = append(nameToObjects[obj.Name], obj)
 	}
+	sort.Strings(names)
 	
 	// 第三步：为每个名称生成混淆名，同名对象使用相同的混淆名
 	funcCount := 0
 	varCount := 0
 	nameCount := make(map[string]int) // 用于后续的同步逻辑
 	
-	for name, objects := range nameToObjects {
+	for _, name := range names {
+		objects := nameToObjects[name]
 		if len(objects) == 0 {
 			continue
 		}
@@ -534,28 +771,28 @@ func (o *Obfuscator) buildObfuscationMapsWithScope() {
 		
 		// 如果有多个同名对象，打印日志
 		if len(objects) > 1 {
-			log.Printf("同名对象使用相同混淆名: %s → %s (在 %d 个文件中定义)", name, obfName, len(objects))
+			o.logf("同名对象使用相同混淆名: %s → %s (在 %d 个文件中定义)", name, obfName, len(objects))
 		}
 	}
 
-	// 第三步：为局部变量生成混淆名称（每个对象独立生成）
-	localVarCount := 0
+	// 第四步：移除未生成混淆名的受保护对象
+	var pending []*Object
 	for obj, obfName := range o.objectMapping {
 		// 如果已经有混淆名称，跳过
-		if obfName != "" {
-			continue
+		if obfName == "" {
+			pending = append(pending, obj)
 		}
-		
+	}
+	sortObjects(pending)
+	for _, obj := range pending {
 		// 检查是否应该保护
 		if o.shouldProtectObject(obj) {
 			delete(o.objectMapping, obj)
 			continue
 		}
 		
-		// 为局部变量生成唯一的混淆名称
 		obfuscatedName := o.generateUniqueObfuscatedNameForObject(obj)
 		o.objectMapping[obj] = obfuscatedName
-		localVarCount++
 	}
 
 	// 同步到funcMapping和varMapping（方案1版本）：
@@ -581,9 +818,19 @@ func (o *Obfuscator) buildObfuscationMapsWithScope() {
 		}
 	}
 	
-	log.Printf("收集到 %d 个包级别名称（函数: %d, 变量: %d），%d 个局部变量", 
-		len(nameToObjects), funcCount, varCount, localVarCount)
-	log.Printf("同步了 %d 个名称到名称映射（用于跨文件引用）", syncCount)
+	o.logf("收集到 %d 个包级别名称（函数: %d, 变量: %d）", 
+		len(nameToObjects), funcCount, varCount)
+	o.logf("同步了 %d 个名称到名称映射（用于跨文件引用）", syncCount)
+}
+
+// sortObjects 按文件路径和声明位置排序对象
+func sortObjects(objects []*Object) {
+	sort.Slice(objects, func(i, j int) bool {
+		if objects[i].FilePath != objects[j].FilePath {
+			return objects[i].FilePath < objects[j].FilePath
+		}
+		return objects[i].Pos < objects[j].Pos
+	})
 }
 
 // collectObjectsForObfuscation 递归收集作用域中需要混淆的对象
@@ -606,13 +853,9 @@ func (o *Obfuscator) collectObjectsForObfuscation(scope *Scope) {
 			if obj.Kind == ObjFunc || obj.Kind == ObjVar || obj.Kind == ObjConst {
 				o.objectMapping[obj] = "" // 先标记，稍后生成名称
 			}
-		} else {
-			// 局部作用域的变量（函数内部、块内部等）
-			// 也收集局部变量进行混淆
-			if obj.Kind == ObjVar || obj.Kind == ObjConst {
-				o.objectMapping[obj] = "" // 先标记，稍后生成名称
-			}
 		}
+		// 局部作用域的变量不在这里收集：名称映射只作用于包级对象，
+		// 局部变量由 planLocalRenames 按类型检查得到的声明对象单独改名
 	}
 
 	// 递归处理子作用域
@@ -660,46 +903,19 @@ func (o *Obfuscator) generateObfuscatedNameForObject(obj *Object) string {
 	// 生成唯一的混淆名称
 	maxAttempts := 100
 	for attempt := 0; attempt < maxAttempts; attempt++ {
-		obf := fmt.Sprintf("%s%s", prefix, generateRandomString(12))
+		obf := fmt.Sprintf("%s%s", prefix, o.randomString(12))
 
-		// 检查此名称是否已被使用
-		if !o.isObfuscatedNameUsed(obf) {
+		// 检查此名称是否已被使用（O(1) 查找已登记的混淆名）
+		if o.reserveName(obf) {
 			return obf
 		}
 	}
 
 	// 回退：使用基于计数器的方法
 	o.namingCounter++
-	return fmt.Sprintf("%s%d_%s", prefix, o.namingCounter, generateRandomString(8))
-}
-
-// isObfuscatedNameUsed 检查混淆名称是否已被使用
-func (o *Obfuscator) isObfuscatedNameUsed(name string) bool {
-	// 检查对象映射
-	for _, obfName := range o.objectMapping {
-		if obfName == name {
-			return true
-		}
-	}
-
-	// 检查旧的映射（向后兼容）
-	for _, obfName := range o.varMapping {
-		if obfName == name {
-			return true
-		}
-	}
-	for _, obfName := range o.funcMapping {
-		if obfName == name {
-			return true
-		}
-	}
-	for _, obfName := range o.importAliasMapping {
-		if obfName == name {
-			return true
-		}
-	}
-
-	return false
+	obf := fmt.Sprintf("%s%d_%s", prefix, o.namingCounter, o.randomString(8))
+	o.reserveName(obf)
+	return obf
 }
 
 // copyProject 复制项目到输出目录
@@ -718,8 +934,9 @@ func (o *Obfuscator) copyProject() error {
 		outputPath := filepath.Join(o.outputDir, relPath)
 		if o.Config.ObfuscateFileNames && strings.HasSuffix(path, ".go") {
 			// 检查文件是否被排除
+			// 被 //go:embed 嵌入的文件保持原名，否则模式失效或运行时按名称查找失败
 			_, isSkipped := o.skippedFiles[path]
-			if !isSkipped {
+			if !isSkipped && !o.isEmbeddedFile(path) {
 				dir := filepath.Dir(outputPath)
 				base := filepath.Base(outputPath)
 				// 使用 obfuscateFileName 函数，它会保护 main.go 等特殊文件
@@ -741,6 +958,16 @@ func (o *Obfuscator) copyProject() error {
 
 // copyProjectAndBuildMapping 复制项目到输出目录并构建文件映射
 func (o *Obfuscator) copyProjectAndBuildMapping(fileMapping map[string]string) error {
+	// 依赖已由 go mod vendor 直接生成在输出目录，原地处理
+	if o.vendorRoot != "" {
+		filepath.Walk(o.vendorRoot, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() && strings.HasSuffix(path, ".go") {
+				fileMapping[path] = path
+			}
+			return nil
+		})
+	}
+
 	return filepath.Walk(o.projectRoot, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -751,12 +978,18 @@ func (o *Obfuscator) copyProjectAndBuildMapping(fileMapping map[string]string) e
 			return err
 		}
 
+		// 项目自带的 vendor/ 会被重新生成的依赖目录取代
+		if o.vendorRoot != "" && info.IsDir() && relPath == "vendor" {
+			return filepath.SkipDir
+		}
+
 		// 处理文件名混淆（只对未被排除的 Go 文件）
 		outputPath := filepath.Join(o.outputDir, relPath)
 		if o.Config.ObfuscateFileNames && strings.HasSuffix(path, ".go") {
 			// 检查文件是否被排除
+			// 被 //go:embed 嵌入的文件保持原名，否则模式失效或运行时按名称查找失败
 			_, isSkipped := o.skippedFiles[path]
-			if !isSkipped {
+			if !isSkipped && !o.isEmbeddedFile(path) {
 				dir := filepath.Dir(outputPath)
 				base := filepath.Base(outputPath)
 				// 使用 obfuscateFileName 函数，它会保护 main.go 等特殊文件
@@ -772,15 +1005,34 @@ func (o *Obfuscator) copyProjectAndBuildMapping(fileMapping map[string]string) e
 			fileMapping[outputPath] = path
 		}
 
+		// overlay 模式不复制项目，改动的 Go 文件在写出时创建所在目录
+		if o.Config.Overlay {
+			return nil
+		}
+
 		if info.IsDir() {
 			return os.MkdirAll(outputPath, info.Mode())
 		}
 
+		// 增量运行只复制需要重写的包中的 Go 文件，其他文件由调用方同步
+		if o.onlyDirs != nil && (!strings.HasSuffix(path, ".go") || !o.rewritesDir(path)) {
+			return nil
+		}
+
 		// 复制文件（包括被排除的文件）
 		return o.copyFile(path, outputPath)
 	})
 }
 
+// originalPathFor 返回输出目录中文件对应的原始路径
+func (o *Obfuscator) originalPathFor(path string, fileMapping map[string]string) string {
+	if originalPath, exists := fileMapping[path]; exists {
+		return originalPath
+	}
+	relPath, _ := filepath.Rel(o.outputDir, path)
+	return filepath.Join(o.projectRoot, relPath)
+}
+
 // copyFile 复制单个文件
 func (o *Obfuscator) copyFile(src, dst string) error {
 	srcFile, err := os.Open(src)
@@ -803,172 +1055,53 @@ func (o *Obfuscator) copyFile(src, dst string) error {
 	return err
 }
 
-// obfuscateFile 混淆单个文件
-func (o *Obfuscator) obfuscateFile(filePath string) error {
-	// 解析文件
-	node, err := parser.ParseFile(o.fset, filePath, nil, parser.ParseComments)
-	if err != nil {
-		return fmt.Errorf("解析文件失败: %v", err)
-	}
-
-	// 移除注释（保留构建标签和编译指令）
-	if o.Config.RemoveComments {
-		var filteredComments []*ast.CommentGroup
-		for _, cg := range node.Comments {
-			var keepComments []*ast.Comment
-			for _, c := range cg.List {
-				if o.shouldKeepComment(c.Text) {
-					keepComments = append(keepComments, c)
-				}
-			}
-			if len(keepComments) > 0 {
-				cg.List = keepComments
-				filteredComments = append(filteredComments, cg)
-			}
+// removeComments 移除注释（保留构建标签和编译指令）
+func (o *Obfuscator) removeComments(node *ast.File) {
+	// cgo 序言是 C 代码，整体保留；示例的输出注释由 go test 比对，同样保留
+	preamble := cgoPreambleGroups(node)
+	exampleOutput := exampleOutputComments(node)
+	var filteredComments []*ast.CommentGroup
+	for _, cg := range node.Comments {
+		if preamble[cg] {
+			filteredComments = append(filteredComments, cg)
+			continue
 		}
-		node.Comments = filteredComments
-
-		// 清除文档注释
-		for _, decl := range node.Decls {
-			if genDecl, ok := decl.(*ast.GenDecl); ok {
-				genDecl.Doc = nil
-				for _, spec := range genDecl.Specs {
-					if typeSpec, ok := spec.(*ast.TypeSpec); ok {
-						typeSpec.Doc = nil
-						typeSpec.Comment = nil
-					}
-					if valueSpec, ok := spec.(*ast.ValueSpec); ok {
-						valueSpec.Doc = nil
-						valueSpec.Comment = nil
-					}
-					if importSpec, ok := spec.(*ast.ImportSpec); ok {
-						importSpec.Doc = nil
-						importSpec.Comment = nil
-					}
-				}
-			}
-			if funcDecl, ok := decl.(*ast.FuncDecl); ok {
-				funcDecl.Doc = nil
+		var keepComments []*ast.Comment
+		for _, c := range cg.List {
+			if o.shouldKeepComment(c.Text) || exampleOutput[c] {
+				keepComments = append(keepComments, c)
 			}
 		}
-	}
-
-	// 获取原始文件路径（从输出目录映射回项目根目录）
-	relPath, _ := filepath.Rel(o.outputDir, filePath)
-	originalPath := filepath.Join(o.projectRoot, relPath)
-
-	// 应用转换（使用作用域信息）
-	o.applyTransformationsWithScope(node, originalPath)
-
-	// 格式化并写入
-	var buf bytes.Buffer
-	if err := format.Node(&buf, o.fset, node); err != nil {
-		return fmt.Errorf("格式化失败: %v", err)
-	}
-
-	source := buf.String()
-
-	// 字符串加密
-	if o.Config.EncryptStrings {
-		hadEncryption := o.encryptStringsInAST(node)
-
-		if hadEncryption {
-			// 确保导入解密包
-			source = o.ensureDecryptPackageImport(source)
-			
-			// 加密字符串字面量（使用解密包的函数）
-			source, _ = o.encryptStringsInSourceWithPackage(source)
+		if len(keepComments) > 0 {
+			cg.List = keepComments
+			filteredComments = append(filteredComments, cg)
 		}
 	}
+	node.Comments = filteredComments
 
-	// 写回文件
-	return ioutil.WriteFile(filePath, []byte(source), 0644)
-}
-
-// obfuscateFileWithMapping 使用文件映射混淆单个文件
-func (o *Obfuscator) obfuscateFileWithMapping(filePath string, fileMapping map[string]string) error {
-	// 解析文件
-	node, err := parser.ParseFile(o.fset, filePath, nil, parser.ParseComments)
-	if err != nil {
-		return fmt.Errorf("解析文件失败: %v", err)
-	}
-
-	// 移除注释（保留构建标签和编译指令）
-	if o.Config.RemoveComments {
-		var filteredComments []*ast.CommentGroup
-		for _, cg := range node.Comments {
-			var keepComments []*ast.Comment
-			for _, c := range cg.List {
-				if o.shouldKeepComment(c.Text) {
-					keepComments = append(keepComments, c)
+	// 清除文档注释
+	for _, decl := range node.Decls {
+		if genDecl, ok := decl.(*ast.GenDecl); ok {
+			genDecl.Doc = nil
+			for _, spec := range genDecl.Specs {
+				if typeSpec, ok := spec.(*ast.TypeSpec); ok {
+					typeSpec.Doc = nil
+					typeSpec.Comment = nil
 				}
-			}
-			if len(keepComments) > 0 {
-				cg.List = keepComments
-				filteredComments = append(filteredComments, cg)
-			}
-		}
-		node.Comments = filteredComments
-
-		// 清除文档注释
-		for _, decl := range node.Decls {
-			if genDecl, ok := decl.(*ast.GenDecl); ok {
-				genDecl.Doc = nil
-				for _, spec := range genDecl.Specs {
-					if typeSpec, ok := spec.(*ast.TypeSpec); ok {
-						typeSpec.Doc = nil
-						typeSpec.Comment = nil
-					}
-					if valueSpec, ok := spec.(*ast.ValueSpec); ok {
-						valueSpec.Doc = nil
-						valueSpec.Comment = nil
-					}
-					if importSpec, ok := spec.(*ast.ImportSpec); ok {
-						importSpec.Doc = nil
-						importSpec.Comment = nil
-					}
+				if valueSpec, ok := spec.(*ast.ValueSpec); ok {
+					valueSpec.Doc = nil
+					valueSpec.Comment = nil
+				}
+				if importSpec, ok := spec.(*ast.ImportSpec); ok {
+					importSpec.Doc = nil
+					importSpec.Comment = nil
 				}
-			}
-			if funcDecl, ok := decl.(*ast.FuncDecl); ok {
-				funcDecl.Doc = nil
 			}
 		}
-	}
-
-	// 从文件映射获取原始文件路径
-	originalPath, exists := fileMapping[filePath]
-	if !exists {
-		// 如果映射中没有，尝试从输出目录映射回项目根目录
-		relPath, _ := filepath.Rel(o.outputDir, filePath)
-		originalPath = filepath.Join(o.projectRoot, relPath)
-	}
-
-	// 应用转换（使用作用域信息）
-	o.applyTransformationsWithScope(node, originalPath)
-
-	// 格式化并写入
-	var buf bytes.Buffer
-	if err := format.Node(&buf, o.fset, node); err != nil {
-		return fmt.Errorf("格式化失败: %v", err)
-	}
-
-	source := buf.String()
-
-	// 字符串加密
-	if o.Config.EncryptStrings {
-		hadEncryption := o.encryptStringsInAST(node)
-
-		if hadEncryption {
-			// 确保导入解密包
-			source = o.ensureDecryptPackageImport(source)
-			
-			// 加密字符串字面量（使用解密包的函数）
-			source, _ = o.encryptStringsInSourceWithPackage(source)
+		if funcDecl, ok := decl.(*ast.FuncDecl); ok {
+			funcDecl.Doc = nil
 		}
 	}
-
-	// 写回文件
-	return ioutil.WriteFile(filePath, []byte(source), 0644)
 }
 
 // applyTransformations 应用 AST 转换
@@ -994,7 +1127,7 @@ func (o *Obfuscator) applyTransformations(node *ast.File) {
 						if importSpec.Name != nil {
 							pkgNameInCode = importSpec.Name.Name
 						} else {
-							pkgNameInCode = filepath.Base(pkgPath)
+							pkgNameInCode = importPathName(pkgPath)
 						}
 
 						// 只为标准库应用别名
@@ -1081,19 +1214,19 @@ func (o *Obfuscator) applyTransformations(node *ast.File) {
 
 	// 步骤 5: 注入垃圾代码
 	if o.Config.InjectJunkCode {
+		r := o.fileRand(o.fset.Position(node.Package).Filename)
 		for _, decl := range node.Decls {
 			if fn, ok := decl.(*ast.FuncDecl); ok {
 				if fn.Body != nil && len(fn.Body.List) > 0 && !o.shouldSkipJunkCodeInjection(fn) {
-					junkStmts := o.generateJunkStatements(false)
-					fn.Body.List = append(junkStmts, fn.Body.List...)
+					fn.Body.List = append(o.generateJunkStatements(r, false, fn.Body.Lbrace), fn.Body.List...)
 				}
 			}
 		}
 	}
 }
 
-// applyTransformationsWithScope 使用作用域信息应用 AST 转换
-func (o *Obfuscator) applyTransformationsWithScope(node *ast.File, originalFilePath string) {
+// applyTransformationsWithScope 使用作用域信息应用 AST 转换，返回按作用域改名的标识符出现次数
+func (o *Obfuscator) applyTransformationsWithScope(node *ast.File, originalFilePath string) int {
 	// 步骤 1: 构建此文件中的包映射
 	filePackages := make(map[string]string) // 代码中的包名 -> 混淆别名
 
@@ -1115,7 +1248,7 @@ func (o *Obfuscator) applyTransformationsWithScope(node *ast.File, originalFileP
 						if importSpec.Name != nil {
 							pkgNameInCode = importSpec.Name.Name
 						} else {
-							pkgNameInCode = filepath.Base(pkgPath)
+							pkgNameInCode = importPathName(pkgPath)
 						}
 
 						// 只为标准库应用别名
@@ -1158,10 +1291,10 @@ func (o *Obfuscator) applyTransformationsWithScope(node *ast.File, originalFileP
 	
 	if hasScope {
 		// 步骤 3: 使用作用域信息混淆标识符
-		o.obfuscateIdentifiersWithScope(node, analyzer)
+		return o.obfuscateIdentifiersWithScope(node, analyzer)
 	} else {
 		// 回退到旧的混淆方法（如果没有作用域信息）
-		log.Printf("警告: 文件 %s 没有作用域信息，使用旧的混淆方法", originalFilePath)
+		o.warnf("文件 %s 没有作用域信息，使用旧的混淆方法", originalFilePath)
 		
 		// 步骤 3: 混淆函数声明
 		ast.Inspect(node, func(n ast.Node) bool {
@@ -1210,22 +1343,27 @@ func (o *Obfuscator) applyTransformationsWithScope(node *ast.File, originalFileP
 			return true
 		})
 	}
+	return 0
+}
 
-	// 步骤 5: 注入垃圾代码
-	if o.Config.InjectJunkCode {
-		for _, decl := range node.Decls {
-			if fn, ok := decl.(*ast.FuncDecl); ok {
-				if fn.Body != nil && len(fn.Body.List) > 0 && !o.shouldSkipJunkCodeInjection(fn) {
-					junkStmts := o.generateJunkStatements(false)
-					fn.Body.List = append(junkStmts, fn.Body.List...)
-				}
+// injectJunkCode 向函数体开头注入垃圾代码，随机源按文件派生。返回注入了垃圾代码的函数数
+func (o *Obfuscator) injectJunkCode(node *ast.File, originalFilePath string) int {
+	r := o.fileRand(originalFilePath)
+	blocks := 0
+	for _, decl := range node.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok {
+			if fn.Body != nil && len(fn.Body.List) > 0 && !o.shouldSkipJunkCodeInjection(fn) {
+				fn.Body.List = append(o.generateJunkStatements(r, false, fn.Body.Lbrace), fn.Body.List...)
+				blocks++
 			}
 		}
 	}
+	return blocks
 }
 
-// obfuscateIdentifiersWithScope 使用作用域信息混淆标识符
-func (o *Obfuscator) obfuscateIdentifiersWithScope(node *ast.File, analyzer *ScopeAnalyzer) {
+// obfuscateIdentifiersWithScope 使用作用域信息混淆标识符，返回改名的标识符出现次数
+func (o *Obfuscator) obfuscateIdentifiersWithScope(node *ast.File, analyzer *ScopeAnalyzer) int {
+	renamed := 0
 	// 记录哪些标识符是类型引用，不应该被混淆
 	typeRefs := make(map[*ast.Ident]bool)
 	
@@ -1245,6 +1383,14 @@ func (o *Obfuscator) obfuscateIdentifiersWithScope(node *ast.File, analyzer *Sco
 			if x.Type != nil {
 				o.markTypeIdents(x.Type, typeRefs)
 			}
+		case *ast.TypeSpec:
+			// 类型定义和别名的右侧（如约束接口、泛型实例化）
+			o.markTypeIdents(x.Type, typeRefs)
+		case *ast.CompositeLit:
+			// 复合字面量的类型（如 List[int]{}）
+			if x.Type != nil {
+				o.markTypeIdents(x.Type, typeRefs)
+			}
 		case *ast.CallExpr:
 			// 类型转换：只有当Fun是类型标识符时才标记
 			// 例如：int(x), MyType(x)
@@ -1271,6 +1417,11 @@ func (o *Obfuscator) obfuscateIdentifiersWithScope(node *ast.File, analyzer *Sco
 				return true
 			}
 
+			// 类型参数可能与包级名称同名，不能按名称映射改名（由 planLocalRenames 按对象改名）
+			if analyzer.IsTypeParamAt(x.Name, o.fset.Position(x.Pos()).Offset) {
+				return true
+			}
+
 			// ✅ 改进的替换策略（方案2 + 精确的作用域处理）：
 			// 
 			// 问题：简单的作用域查找无法区分"当前位置的作用域"和"文件级作用域"
@@ -1297,6 +1448,7 @@ func (o *Obfuscator) obfuscateIdentifiersWithScope(node *ast.File, analyzer *Sco
 				}
 				if obfName, hasObf := o.objectMapping[obj]; hasObf && obfName != "" {
 					x.Name = obfName
+					renamed++
 					return true
 				}
 			}
@@ -1305,15 +1457,18 @@ func (o *Obfuscator) obfuscateIdentifiersWithScope(node *ast.File, analyzer *Sco
 			// funcMapping/varMapping 包含所有唯一名称（不包括同名的私有对象）
 			if obfName, exists := o.funcMapping[x.Name]; exists {
 				x.Name = obfName
+				renamed++
 				return true
 			}
 			if obfName, exists := o.varMapping[x.Name]; exists {
 				x.Name = obfName
+				renamed++
 				return true
 			}
 		}
 		return true
 	})
+	return renamed
 }
 
 // markTypeIdents 标记表达式中的所有类型标识符
@@ -1337,6 +1492,36 @@ func (o *Obfuscator) markTypeIdents(expr ast.Expr, typeRefs map[*ast.Ident]bool)
 	case *ast.SelectorExpr:
 		// 对于 pkg.Type，标记 Type
 		typeRefs[x.Sel] = true
+	case *ast.IndexExpr:
+		// 泛型实例化 List[T]
+		o.markTypeIdents(x.X, typeRefs)
+		o.markTypeIdents(x.Index, typeRefs)
+	case *ast.IndexListExpr:
+		// 泛型实例化 Map[K, V]
+		o.markTypeIdents(x.X, typeRefs)
+		for _, index := range x.Indices {
+			o.markTypeIdents(index, typeRefs)
+		}
+	case *ast.BinaryExpr:
+		// 约束中的联合类型 ~int | ~string
+		if x.Op == token.OR {
+			o.markTypeIdents(x.X, typeRefs)
+			o.markTypeIdents(x.Y, typeRefs)
+		}
+	case *ast.UnaryExpr:
+		// 约束中的近似类型 ~int
+		if x.Op == token.TILDE {
+			o.markTypeIdents(x.X, typeRefs)
+		}
+	case *ast.InterfaceType:
+		// 约束接口中嵌入的类型和联合类型（方法名不是类型）
+		if x.Methods != nil {
+			for _, field := range x.Methods.List {
+				if len(field.Names) == 0 {
+					o.markTypeIdents(field.Type, typeRefs)
+				}
+			}
+		}
 	case *ast.FuncType:
 		// 函数类型的参数和返回值
 		if x.Params != nil {
@@ -1375,8 +1560,9 @@ func (o *Obfuscator) findObjectInScopeRecursive(name string, scope *Scope) *Obje
 
 // shouldKeepComment 判断是否应保留注释
 func (o *Obfuscator) shouldKeepComment(text string) bool {
-	// 保留构建标签和编译指令
+	// 保留构建标签、编译指令和 cgo 的 //export
 	return strings.HasPrefix(text, "//go:") ||
+		strings.HasPrefix(text, "//export ") ||
 		strings.HasPrefix(text, "// +build") ||
 		strings.HasPrefix(text, "//+build")
 }
@@ -1411,22 +1597,6 @@ func (o *Obfuscator) hasPlatformSpecificBuildTag(node *ast.File) bool {
 	return false
 }
 
-// isFilePlatformSpecific 检查文件是否平台特定（通过文件名后缀或build tag）
-func (o *Obfuscator) isFilePlatformSpecific(filePath string) bool {
-	// 检查文件名后缀
-	if o.hasPlatformSpecificSuffix(filePath) {
-		return true
-	}
-	
-	// 检查build tag（需要解析文件）
-	node, err := parser.ParseFile(o.fset, filePath, nil, parser.ParseComments)
-	if err != nil {
-		return false // 解析失败，假设不是平台特定的
-	}
-	
-	return o.hasPlatformSpecificBuildTag(node)
-}
-
 // hasPlatformSpecificSuffix 检查文件名是否有平台专用的后缀
 func (o *Obfuscator) hasPlatformSpecificSuffix(filePath string) bool {
 	// 获取文件名（不含扩展名）
@@ -1536,10 +1706,20 @@ func (o *Obfuscator) encryptStringsInSource(source string) (string, bool) {
 	hadEncryption := false
 	inImportBlock := false
 	inConstBlock := false
+	inBlockComment := false
+	inRawString := false
 
 	for i, line := range lines {
 		trimmed := strings.TrimSpace(line)
 
+		// 注释和原始字符串可能跨行，每一行（包括被跳过的行）都要更新状态
+		startsInCode := !inBlockComment && !inRawString
+		literals := scanStringLiterals(line, &inBlockComment, &inRawString)
+		if !startsInCode {
+			result[i] = line
+			continue
+		}
+
 		// 跟踪 import 块
 		if strings.HasPrefix(trimmed, "import (") {
 			inImportBlock = true
@@ -1577,56 +1757,18 @@ func (o *Obfuscator) encryptStringsInSource(source string) (string, bool) {
 			continue
 		}
 
-		// 处理行
+		// 处理行：从后向前替换，保持前面字面量的偏移不变
 		newLine := line
-		inString := false
-		inRune := false
-		escaped := false
-		stringStart := -1
-
-		for j := 0; j < len(line); j++ {
-			ch := line[j]
-
-			if escaped {
-				escaped = false
-				continue
-			}
-
-			if ch == '\\' {
-				escaped = true
-				continue
-			}
-
-			// 处理单引号（rune）
-			if ch == '\'' && !inString {
-				if !inRune {
-					inRune = true
-				} else {
-					inRune = false
-				}
-				continue
-			}
-
-			// 只处理双引号
-			if ch == '"' && !inRune {
-				if !inString {
-					inString = true
-					stringStart = j
-				} else {
-					inString = false
-					if stringStart >= 0 {
-						strWithQuotes := line[stringStart : j+1]
-						if len(strWithQuotes) > 4 {
-							strContent := strWithQuotes[1 : len(strWithQuotes)-1]
-							if len(strContent) > 2 && !strings.Contains(strContent, "\\") {
-								encrypted := o.encryptString(strContent)
-								replacement := fmt.Sprintf(`%s("%s")`, o.decryptFuncName, encrypted)
-								newLine = strings.Replace(newLine, strWithQuotes, replacement, 1)
-								hadEncryption = true
-							}
-						}
-					}
-					stringStart = -1
+		for k := len(literals) - 1; k >= 0; k-- {
+			start, end := literals[k][0], literals[k][1]
+			strWithQuotes := line[start:end]
+			if len(strWithQuotes) > 4 {
+				strContent := strWithQuotes[1 : len(strWithQuotes)-1]
+				if len(strContent) > 2 && !strings.Contains(strContent, "\\") {
+					encrypted := o.encryptString(strContent)
+					replacement := fmt.Sprintf(`%s("%s")`, o.decryptFuncName, encrypted)
+					newLine = newLine[:start] + replacement + newLine[end:]
+					hadEncryption = true
 				}
 			}
 		}
@@ -1643,8 +1785,12 @@ func (o *Obfuscator) createDecryptPackage() error {
 		return nil
 	}
 
-	// 设置解密包路径（在输出目录下，而不是原始项目目录）
+	// 设置解密包路径（在输出目录下，而不是原始项目目录）。
+	// 文件名每次随机生成，重复写入同一输出目录（监视模式）时先清除上一次生成的文件
 	decryptPkgDir := filepath.Join(o.outputDir, o.decryptPkgName)
+	if err := os.RemoveAll(decryptPkgDir); err != nil {
+		return fmt.Errorf("清除解密包目录失败: %v", err)
+	}
 	if err := os.MkdirAll(decryptPkgDir, 0755); err != nil {
 		return fmt.Errorf("创建解密包目录失败: %v", err)
 	}
@@ -1680,7 +1826,7 @@ func %s(s string) string {
 `, o.decryptPkgName, o.decryptFuncName, keyLiteral)
 
 	// 写入文件（使用随机文件名）
-	randomFileName := fmt.Sprintf("%s.go", generateRandomString(10))
+	randomFileName := fmt.Sprintf("%s.go", randomString(newRand(o.seed, "decrypt"), 10))
 	decryptFilePath := filepath.Join(decryptPkgDir, randomFileName)
 	if err := ioutil.WriteFile(decryptFilePath, []byte(decryptFileContent), 0644); err != nil {
 		return fmt.Errorf("写入解密文件失败: %v", err)
@@ -1713,10 +1859,10 @@ func %s(s string) string {
 	o.decryptPkgCreated = true
 
 	// 保护解密函数名称和包名，防止被混淆
-	o.protectedNames[o.decryptFuncName] = true
+	o.protect(o.decryptFuncName, reasonDecryptFunc, "", token.NoPos)
 	o.packageNames[o.decryptPkgName] = true
 
-	log.Printf("✅ 创建解密包: %s (导入路径: %s, 函数名: %s)", decryptPkgDir, o.decryptPkgPath, o.decryptFuncName)
+	o.logf("✅ 创建解密包: %s (导入路径: %s, 函数名: %s)", decryptPkgDir, o.decryptPkgPath, o.decryptFuncName)
 	return nil
 }
 
@@ -1731,6 +1877,10 @@ func (o *Obfuscator) ensureDecryptPackageImport(source string) string {
 
 	lines := strings.Split(source, "\n")
 	for i, line := range lines {
+		// import "C" 必须单独声明并紧跟 cgo 序言，不能并入 import 块
+		if strings.TrimSpace(line) == `import "C"` {
+			continue
+		}
 		if strings.HasPrefix(strings.TrimSpace(line), "import (") {
 			// 在 import 块中添加
 			lines[i] = line + "\n\t" + importLine
@@ -1754,18 +1904,65 @@ func (o *Obfuscator) ensureDecryptPackageImport(source string) string {
 	return source
 }
 
-// encryptStringsInSourceWithPackage 在源代码中加密字符串（使用解密包）
-func (o *Obfuscator) encryptStringsInSourceWithPackage(source string) (string, bool) {
+// scanStringLiterals 返回一行源码中双引号字符串字面量的区间 [start, end)，
+// 跳过注释、原始字符串和 rune 字面量；inBlockComment 和 inRawString 是跨行的状态
+func scanStringLiterals(line string, inBlockComment, inRawString *bool) [][2]int {
+	var literals [][2]int
+	for j := 0; j < len(line); j++ {
+		switch {
+		case *inBlockComment:
+			if strings.HasPrefix(line[j:], "*/") {
+				*inBlockComment = false
+				j++
+			}
+		case *inRawString:
+			if line[j] == '`' {
+				*inRawString = false
+			}
+		case strings.HasPrefix(line[j:], "//"):
+			return literals
+		case strings.HasPrefix(line[j:], "/*"):
+			*inBlockComment = true
+			j++
+		case line[j] == '`':
+			*inRawString = true
+		case line[j] == '"' || line[j] == '\'':
+			quote, start := line[j], j
+			for j++; j < len(line) && line[j] != quote; j++ {
+				if line[j] == '\\' {
+					j++
+				}
+			}
+			if quote == '"' && j < len(line) {
+				literals = append(literals, [2]int{start, j + 1})
+			}
+		}
+	}
+	return literals
+}
+
+// encryptStringsInSourceWithPackage 在源代码中加密字符串（使用解密包），返回加密的字面量数量
+func (o *Obfuscator) encryptStringsInSourceWithPackage(source string) (string, int, int) {
 	lines := strings.Split(source, "\n")
 	result := make([]string, len(lines))
 
-	hadEncryption := false
+	encrypted, size := 0, 0
 	inImportBlock := false
 	inConstBlock := false
+	inBlockComment := false
+	inRawString := false
 
 	for i, line := range lines {
 		trimmed := strings.TrimSpace(line)
 
+		// 注释和原始字符串可能跨行，每一行（包括被跳过的行）都要更新状态
+		startsInCode := !inBlockComment && !inRawString
+		literals := scanStringLiterals(line, &inBlockComment, &inRawString)
+		if !startsInCode {
+			result[i] = line
+			continue
+		}
+
 		// 跟踪 import 块
 		if strings.HasPrefix(trimmed, "import (") {
 			inImportBlock = true
@@ -1803,57 +2000,19 @@ func (o *Obfuscator) encryptStringsInSourceWithPackage(source string) (string, b
 			continue
 		}
 
-		// 处理行
+		// 处理行：从后向前替换，保持前面字面量的偏移不变
 		newLine := line
-		inString := false
-		inRune := false
-		escaped := false
-		stringStart := -1
-
-		for j := 0; j < len(line); j++ {
-			ch := line[j]
-
-			if escaped {
-				escaped = false
-				continue
-			}
-
-			if ch == '\\' {
-				escaped = true
-				continue
-			}
-
-			// 处理单引号（rune）
-			if ch == '\'' && !inString {
-				if !inRune {
-					inRune = true
-				} else {
-					inRune = false
-				}
-				continue
-			}
-
-			// 只处理双引号
-			if ch == '"' && !inRune {
-				if !inString {
-					inString = true
-					stringStart = j
-				} else {
-					inString = false
-					if stringStart >= 0 {
-						strWithQuotes := line[stringStart : j+1]
-						if len(strWithQuotes) > 4 {
-							strContent := strWithQuotes[1 : len(strWithQuotes)-1]
-							if len(strContent) > 2 && !strings.Contains(strContent, "\\") {
-								encrypted := o.encryptString(strContent)
-								// 使用解密包的函数: pkgName.FuncName("encrypted")
-								replacement := fmt.Sprintf(`%s.%s("%s")`, o.decryptPkgName, o.decryptFuncName, encrypted)
-								newLine = strings.Replace(newLine, strWithQuotes, replacement, 1)
-								hadEncryption = true
-							}
-						}
-					}
-					stringStart = -1
+		for k := len(literals) - 1; k >= 0; k-- {
+			start, end := literals[k][0], literals[k][1]
+			strWithQuotes := line[start:end]
+			if len(strWithQuotes) > 4 {
+				strContent := strWithQuotes[1 : len(strWithQuotes)-1]
+				if len(strContent) > 2 && !strings.Contains(strContent, "\\") {
+					// 使用解密包的函数: pkgName.FuncName("encrypted")
+					replacement := fmt.Sprintf(`%s.%s("%s")`, o.decryptPkgName, o.decryptFuncName, o.encryptString(strContent))
+					newLine = newLine[:start] + replacement + newLine[end:]
+					encrypted++
+					size += len(strContent)
 				}
 			}
 		}
@@ -1861,5 +2020,5 @@ func (o *Obfuscator) encryptStringsInSourceWithPackage(source string) (string, b
 		result[i] = newLine
 	}
 
-	return strings.Join(result, "\n"), hadEncryption
+	return strings.Join(result, "\n"), encrypted, size
 }
@@ -0,0 +1,172 @@
+package obfuscator
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 保护原因类别
+const (
+	reasonStructField      = "结构体字段"
+	reasonEmbeddedField    = "嵌入字段"
+	reasonInterfaceMethod  = "接口方法"
+	reasonMethod           = "方法"
+	reasonExternalSelector = "非项目包选择器"
+	reasonReflection       = "反射"
+	reasonVendoredAPI      = "依赖包导出 API"
+	reasonDecryptFunc      = "字符串解密函数"
+	reasonBuiltin          = "内置标识符"
+	reasonSpecialName      = "特殊名称"
+	reasonExported         = "导出名称"
+	reasonPackageName      = "包名"
+	reasonTemplate         = "模板引用"
+	reasonLinkname         = "go:linkname"
+	reasonCgoExport        = "cgo 导出函数"
+	reasonGenerated        = "生成代码"
+	reasonExcludedFile     = "排除的文件"
+	reasonTestEntry        = "测试入口"
+)
+
+// ProtectionReason 名称未被混淆的一条原因
+type ProtectionReason struct {
+	Kind     string // 原因类别，例如 "结构体字段"、"外部包选择器"
+	Detail   string // 补充说明
+	Position string // 源码位置 file:line（全局规则为空）
+}
+
+// String 返回便于输出的单行描述
+func (r ProtectionReason) String() string {
+	s := r.Kind
+	if r.Detail != "" {
+		s += ": " + r.Detail
+	}
+	if r.Position != "" {
+		s += " (" + r.Position + ")"
+	}
+	return s
+}
+
+// protectionKey 用于去重同一名称的相同原因
+type protectionKey struct {
+	name   string
+	reason ProtectionReason
+}
+
+// protectionSite 一条保护记录对应的标识符。pos 是 o.fset 中的位置，有类型信息时解析为声明对象；
+// obj 是记录时已知的声明对象。两者都为空表示无法对应到对象，同名的包级声明都受保护
+type protectionSite struct {
+	pos token.Pos
+	obj types.Object
+}
+
+// protect 将名称标记为受保护，并记录原因和位置
+func (o *Obfuscator) protect(name, kind, detail string, pos token.Pos) {
+	position := ""
+	if pos.IsValid() {
+		p := o.fset.Position(pos)
+		position = fmt.Sprintf("%s:%d", o.displayPath(p.Filename), p.Line)
+	}
+	o.addProtection(name, kind, detail, position, protectionSite{pos: pos})
+}
+
+// protectAt 与 protect 相同，但位置已经格式化（用于来自其他 FileSet 的位置）
+func (o *Obfuscator) protectAt(name, kind, detail, position string) {
+	o.addProtection(name, kind, detail, position, protectionSite{})
+}
+
+// protectObject 与 protectAt 相同，但保护记录属于已知的声明对象（类型、字段、方法）
+func (o *Obfuscator) protectObject(obj types.Object, kind, detail, position string) {
+	o.addProtection(obj.Name(), kind, detail, position, protectionSite{obj: obj})
+}
+
+// addProtection 记录名称的一条保护原因及其对应的标识符
+func (o *Obfuscator) addProtection(name, kind, detail, position string, site protectionSite) {
+	o.protectedNames[name] = true
+	o.protectionSites[name] = append(o.protectionSites[name], site)
+
+	reason := ProtectionReason{Kind: kind, Detail: detail, Position: position}
+	key := protectionKey{name: name, reason: reason}
+	if o.protectionSeen[key] {
+		return
+	}
+	o.protectionSeen[key] = true
+	o.protectionReasons[name] = append(o.protectionReasons[name], reason)
+}
+
+// hasProtectionReason 判断名称是否因指定类别的原因受保护
+func (o *Obfuscator) hasProtectionReason(name, kind string) bool {
+	for _, r := range o.protectionReasons[name] {
+		if r.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// displayPath 将文件路径转换为相对项目根目录的形式，使不同来源的位置保持一致
+func (o *Obfuscator) displayPath(path string) string {
+	absRoot, err := filepath.Abs(o.projectRoot)
+	if err != nil {
+		return path
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(absRoot, absPath); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+// Explain 返回名称未被混淆的全部原因，需在 Analyze 或 Run 之后调用。
+// 没有任何原因时返回空切片，说明该名称会被混淆
+func (o *Obfuscator) Explain(name string) []ProtectionReason {
+	var reasons []ProtectionReason
+
+	// shouldProtect 中按名称判断的全局规则
+	if name == "_" || name == "main" || name == "init" {
+		reasons = append(reasons, ProtectionReason{Kind: reasonSpecialName, Detail: "_、main、init 始终保留"})
+	}
+	if !o.Config.ObfuscateExported && isExported(name) {
+		reasons = append(reasons, ProtectionReason{Kind: reasonExported, Detail: "未启用 -obfuscate-exported"})
+	}
+	if o.packageNames[name] {
+		reasons = append(reasons, ProtectionReason{Kind: reasonPackageName, Detail: "与导入的包名相同"})
+	}
+	if protectedIdentifiers[name] {
+		reasons = append(reasons, ProtectionReason{Kind: reasonBuiltin, Detail: "Go 预声明标识符"})
+	}
+
+	recorded := make([]ProtectionReason, len(o.protectionReasons[name]))
+	copy(recorded, o.protectionReasons[name])
+	sort.SliceStable(recorded, func(i, j int) bool {
+		if recorded[i].Kind != recorded[j].Kind {
+			return recorded[i].Kind < recorded[j].Kind
+		}
+		return recorded[i].Position < recorded[j].Position
+	})
+	return append(reasons, recorded...)
+}
+
+// ObjectRenames 返回与 name 同名、按声明对象单独改名的包级函数、变量和常量数量。
+// 只属于其他对象（字段、方法、非项目包的选择器）的保护原因不影响这些声明
+func (o *Obfuscator) ObjectRenames(name string) int {
+	n := 0
+	for key := range o.objectRenames {
+		if strings.HasSuffix(key, "."+name) {
+			n++
+		}
+	}
+	return n
+}
+
+// LocalRenames 返回与 name 同名、按声明对象单独改名的局部变量数量。
+// 名称级别的保护原因不影响这些局部变量
+func (o *Obfuscator) LocalRenames(name string) int {
+	return o.localRenames[name]
+}
@@ -0,0 +1,59 @@
+package obfuscator
+
+import (
+	crand "crypto/rand"
+	"crypto/sha256"
+	"math/rand/v2"
+)
+
+// newSeed 把命名种子转换为随机源的种子。种子为空时使用密码学随机数，每次运行得到不同的名称
+func newSeed(seed string) [32]byte {
+	if seed == "" {
+		var b [32]byte
+		if _, err := crand.Read(b[:]); err == nil {
+			return b
+		}
+	}
+	return sha256.Sum256([]byte(seed))
+}
+
+// newRand 从种子和标签派生独立的随机源，相同的种子和标签总是得到相同的序列
+func newRand(seed [32]byte, label string) *rand.Rand {
+	h := sha256.New()
+	h.Write(seed[:])
+	h.Write([]byte(label))
+	var s [32]byte
+	copy(s[:], h.Sum(nil))
+	return rand.New(rand.NewChaCha8(s))
+}
+
+// randomString 生成随机字母数字字符串
+func randomString(r *rand.Rand, length int) string {
+	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	result := make([]byte, length)
+	for i := range result {
+		result[i] = charset[r.IntN(len(charset))]
+	}
+	return string(result)
+}
+
+// randomString 分析阶段使用的随机字符串。分析阶段按确定的顺序串行执行，
+// 因此给定种子时生成的名称可以复现
+func (o *Obfuscator) randomString(length int) string {
+	return randomString(o.rng, length)
+}
+
+// fileRand 转换阶段按文件派生的随机源（垃圾代码等）。
+// 只取决于种子和文件相对路径，与并发数和文件处理顺序无关
+func (o *Obfuscator) fileRand(originalPath string) *rand.Rand {
+	return newRand(o.seed, "file\x00"+o.displayPath(originalPath))
+}
+
+// reserveName 登记一个混淆名，名称已被占用时返回 false
+func (o *Obfuscator) reserveName(name string) bool {
+	if o.usedNames[name] {
+		return false
+	}
+	o.usedNames[name] = true
+	return true
+}
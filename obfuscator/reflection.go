@@ -55,7 +55,7 @@ func (o *Obfuscator) protectReflectionTypes(node *ast.File) {
 		switch x := n.(type) {
 		case *ast.TypeSpec:
 			if usesReflection {
-				o.protectedNames[x.Name.Name] = true
+				o.protect(x.Name.Name, reasonReflection, "文件导入了 reflect，保护其中声明的类型", x.Name.Pos())
 			}
 
 			if structType, ok := x.Type.(*ast.StructType); ok {
@@ -63,7 +63,7 @@ func (o *Obfuscator) protectReflectionTypes(node *ast.File) {
 					for _, field := range structType.Fields.List {
 						for _, fieldName := range field.Names {
 							if usesReflection {
-								o.protectedNames[fieldName.Name] = true
+								o.protect(fieldName.Name, reasonReflection, "文件导入了 reflect，保护 "+x.Name.Name+" 的字段", fieldName.Pos())
 							} else if usesJSON {
 								hasJSONTag := false
 								if field.Tag != nil {
@@ -73,7 +73,7 @@ func (o *Obfuscator) protectReflectionTypes(node *ast.File) {
 									}
 								}
 								if !hasJSONTag {
-									o.protectedNames[fieldName.Name] = true
+									o.protect(fieldName.Name, reasonReflection, "文件导入了序列化包，保护 "+x.Name.Name+" 中没有 json 标签的字段", fieldName.Pos())
 								}
 							}
 						}
@@ -82,7 +82,7 @@ func (o *Obfuscator) protectReflectionTypes(node *ast.File) {
 			}
 		case *ast.FuncDecl:
 			if x.Recv != nil && usesReflection {
-				o.protectedNames[x.Name.Name] = true
+				o.protect(x.Name.Name, reasonReflection, "文件导入了 reflect，保护其中声明的方法", x.Name.Pos())
 			}
 		}
 		return true
@@ -0,0 +1,566 @@
+package obfuscator
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strings"
+)
+
+// reflectionSinks 会通过反射读取类型信息的调用：包路径 -> 函数名（方法写作 "类型.方法"） -> 需要检查的实参下标
+var reflectionSinks = map[string]map[string][]int{
+	"reflect": {"TypeOf": {0}, "ValueOf": {0}},
+	"encoding/json": {
+		"Marshal": {0}, "MarshalIndent": {0}, "Unmarshal": {1},
+		"Encoder.Encode": {0}, "Decoder.Decode": {0},
+	},
+	"encoding/xml": {
+		"Marshal": {0}, "MarshalIndent": {0}, "Unmarshal": {1},
+		"Encoder.Encode": {0}, "Decoder.Decode": {0},
+	},
+	"encoding/gob": {
+		"Register": {0}, "RegisterName": {1},
+		"Encoder.Encode": {0}, "Decoder.Decode": {0},
+	},
+	"gopkg.in/yaml.v2":                    {"Marshal": {0}, "Unmarshal": {1}, "Encoder.Encode": {0}, "Decoder.Decode": {0}},
+	"gopkg.in/yaml.v3":                    {"Marshal": {0}, "Unmarshal": {1}, "Encoder.Encode": {0}, "Decoder.Decode": {0}},
+	"sigs.k8s.io/yaml":                    {"Marshal": {0}, "Unmarshal": {1}},
+	"github.com/mitchellh/mapstructure":   {"Decode": {1}, "WeakDecode": {1}},
+	"github.com/go-viper/mapstructure/v2": {"Decode": {1}, "WeakDecode": {1}},
+	"github.com/BurntSushi/toml": {
+		"Marshal": {0}, "Unmarshal": {1}, "Decode": {1}, "DecodeFile": {1},
+		"Encoder.Encode": {0}, "Decoder.Decode": {0},
+	},
+	"text/template": {"Template.Execute": {1}, "Template.ExecuteTemplate": {2}},
+	"html/template": {"Template.Execute": {1}, "Template.ExecuteTemplate": {2}},
+}
+
+// formatSinks 格式化输出函数：包路径 -> 函数名 -> 格式字符串的实参下标
+// 只有 %+v、%#v、%T 会输出字段名或类型名
+var formatSinks = map[string]map[string]int{
+	"fmt": {"Printf": 0, "Sprintf": 0, "Errorf": 0, "Fprintf": 1, "Appendf": 1},
+	"log": {
+		"Printf": 0, "Fatalf": 0, "Panicf": 0,
+		"Logger.Printf": 0, "Logger.Fatalf": 0, "Logger.Panicf": 0,
+	},
+}
+
+// reflectionAnalysis 基于类型检查的反射逃逸分析
+// 先收集每个变量可能持有的表达式（赋值、声明、调用实参到形参），
+// 再从反射/序列化调用的实参出发找出实际流入的具体类型
+type reflectionAnalysis struct {
+	o         *Obfuscator
+	fset      *token.FileSet
+	project   map[string]bool         // 项目包路径
+	flows     map[string][]flowSource // 变量 -> 赋给它的表达式
+	external  map[string]bool         // 可能被项目外部赋值的变量（导出函数的形参等）
+	visiting  map[string]bool
+	visited   map[visitKey]bool
+	reflected map[string]map[string]bool // "包路径.类型名" -> 流入的调用类别
+	named     map[string]*types.Named    // "包路径.类型名" -> 类型（用于结构体标签合成）
+	sinkPos   string                     // 当前正在检查的反射/序列化调用位置（记录保护原因）
+}
+
+// visitKey 同一类型流入不同类别的调用时需要分别记录
+type visitKey struct {
+	t    types.Type
+	kind string
+}
+
+// flowSource 一条赋值来源及其所在包的类型信息
+type flowSource struct {
+	expr ast.Expr
+	info *types.Info
+}
+
+// analyzeReflection 基于 loadPackages 的类型检查结果，只保护实际流入反射/序列化调用的类型。
+// 未通过类型检查的文件由调用方使用基于导入的启发式保护
+func (o *Obfuscator) analyzeReflection() {
+	ra := &reflectionAnalysis{
+		o:         o,
+		fset:      o.typesFset,
+		project:   make(map[string]bool),
+		flows:     make(map[string][]flowSource),
+		external:  make(map[string]bool),
+		visiting:  make(map[string]bool),
+		visited:   make(map[visitKey]bool),
+		reflected: make(map[string]map[string]bool),
+		named:     make(map[string]*types.Named),
+	}
+
+	checked := o.typedPkgs
+	for _, pkg := range checked {
+		ra.project[pkg.PkgPath] = true
+	}
+
+	for _, pkg := range checked {
+		for _, file := range pkg.Syntax {
+			ra.collectFlows(pkg.TypesInfo, file)
+		}
+	}
+
+	// 测试变体与普通包共享源文件，任一变体回退时这些文件都使用启发式保护
+	fallback := make(map[string]bool) // 回退的包路径
+	fallbackFiles := make(map[string]bool)
+	for _, pkg := range checked {
+		unknown := false
+		for _, file := range pkg.Syntax {
+			if !ra.inspectSinks(pkg.TypesInfo, file) {
+				unknown = true
+			}
+		}
+		if unknown {
+			// 有来源无法确定的接口值流入反射调用，整个包回退到启发式保护
+			fallback[pkg.PkgPath] = true
+			for _, path := range pkg.CompiledGoFiles {
+				if abs, err := filepath.Abs(path); err == nil {
+					fallbackFiles[abs] = true
+				}
+			}
+		}
+	}
+	for _, pkg := range checked {
+		for _, path := range pkg.CompiledGoFiles {
+			if abs, err := filepath.Abs(path); err == nil && !fallbackFiles[abs] {
+				o.reflectionAnalyzed[abs] = true
+			}
+		}
+	}
+
+	o.reflectedTypes = ra.reflected
+	o.logf("反射分析: %d 个类型流入反射/序列化调用，%d 个包回退到基于导入的保护", len(ra.reflected), len(fallback))
+
+	if o.Config.SynthesizeTags {
+		if o.failedPkgs > 0 {
+			o.warnf("%d 个包类型检查失败，无法确认字段的全部引用，跳过结构体标签合成", o.failedPkgs)
+		} else {
+			ra.planFieldRenames(checked, fallback)
+		}
+	}
+}
+
+// needsReflectionHeuristic 判断文件是否需要使用基于导入的启发式反射保护
+func (o *Obfuscator) needsReflectionHeuristic(path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return true
+	}
+	return !o.reflectionAnalyzed[abs]
+}
+
+// varKey 变量的唯一标识：依赖包中的对象来自导出数据，与源码中的对象不是同一指针，因此按声明位置区分
+func (ra *reflectionAnalysis) varKey(v *types.Var) string {
+	pkgPath := ""
+	if v.Pkg() != nil {
+		pkgPath = v.Pkg().Path()
+	}
+	if pos := ra.fset.Position(v.Pos()); pos.IsValid() {
+		return pos.String() + ":" + v.Name()
+	}
+	return pkgPath + "." + v.Name()
+}
+
+// collectFlows 记录变量的赋值来源
+func (ra *reflectionAnalysis) collectFlows(info *types.Info, file *ast.File) {
+	assign := func(lhs ast.Expr, rhs ast.Expr) {
+		id, ok := ast.Unparen(lhs).(*ast.Ident)
+		if !ok {
+			return
+		}
+		obj := info.Defs[id]
+		if obj == nil {
+			obj = info.Uses[id]
+		}
+		if v, ok := obj.(*types.Var); ok {
+			key := ra.varKey(v)
+			ra.flows[key] = append(ra.flows[key], flowSource{expr: rhs, info: info})
+		}
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.AssignStmt:
+			if len(x.Lhs) == len(x.Rhs) {
+				for i := range x.Lhs {
+					assign(x.Lhs[i], x.Rhs[i])
+				}
+			}
+		case *ast.ValueSpec:
+			if len(x.Names) == len(x.Values) {
+				for i := range x.Names {
+					assign(x.Names[i], x.Values[i])
+				}
+			}
+		case *ast.FuncDecl:
+			// 导出函数的形参可能由项目外部传入任意值
+			if x.Name.IsExported() {
+				if fn, ok := info.Defs[x.Name].(*types.Func); ok {
+					params := fn.Type().(*types.Signature).Params()
+					for i := 0; i < params.Len(); i++ {
+						ra.external[ra.varKey(params.At(i))] = true
+					}
+				}
+			}
+		case *ast.CallExpr:
+			fn := calleeFunc(info, x)
+			if fn == nil || fn.Pkg() == nil {
+				return true
+			}
+			sig := fn.Type().(*types.Signature)
+			params := sig.Params()
+			if params.Len() == 0 {
+				return true
+			}
+			for i, arg := range x.Args {
+				idx := i
+				if idx >= params.Len() {
+					idx = params.Len() - 1
+				}
+				key := ra.varKey(params.At(idx))
+				if x.Ellipsis.IsValid() && i == len(x.Args)-1 {
+					ra.external[key] = true
+					continue
+				}
+				ra.flows[key] = append(ra.flows[key], flowSource{expr: arg, info: info})
+			}
+		}
+		return true
+	})
+}
+
+// inspectSinks 检查文件中的反射/序列化调用和对未知第三方包的调用，保护流入的类型。
+// 返回 false 表示存在来源无法确定的接口值
+func (ra *reflectionAnalysis) inspectSinks(info *types.Info, file *ast.File) bool {
+	resolved := true
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		fn := calleeFunc(info, call)
+		if fn == nil || fn.Pkg() == nil {
+			return true
+		}
+		pkgPath, name := fn.Pkg().Path(), funcSinkName(fn)
+		kind := sinkKind(pkgPath)
+
+		var args []ast.Expr
+		if indexes, ok := reflectionSinks[pkgPath][name]; ok {
+			for _, idx := range indexes {
+				if idx < len(call.Args) {
+					args = append(args, call.Args[idx])
+				}
+			}
+		} else if fmtIdx, ok := formatSinks[pkgPath][name]; ok && fmtIdx < len(call.Args) {
+			tv, ok := info.Types[call.Args[fmtIdx]]
+			if ok && tv.Value != nil && tv.Value.Kind() == constant.String {
+				format := constant.StringVal(tv.Value)
+				if strings.Contains(format, "%+v") || strings.Contains(format, "%#v") || strings.Contains(format, "%T") {
+					args = call.Args[fmtIdx+1:]
+				}
+			} else {
+				// 格式字符串不是常量，无法判断是否输出字段名
+				args = call.Args[fmtIdx+1:]
+			}
+		} else if ra.unknownExternal(pkgPath) {
+			// 未知的第三方包（包括被 vendor 的依赖）可能通过反射读取接口实参的名称，
+			// 例如 ORM 按字段名映射列：流入接口形参的值记为未知逃逸，保持其类型的名称
+			kind = kindExternal
+			args = interfaceArgs(fn.Type().(*types.Signature), call)
+		}
+
+		ra.sinkPos = ra.position(call.Pos())
+		for _, arg := range args {
+			if !ra.protectExpr(info, arg, kind) {
+				resolved = false
+			}
+		}
+		return true
+	})
+	return resolved
+}
+
+// kindExternal 流入未知第三方包接口形参的调用类别：无法确定对方如何使用名称，类型和字段都保持原名
+const kindExternal = "external"
+
+// unknownExternal 判断包是否为不在已知调用列表中的第三方包。
+// 项目包内的调用按数据流追踪；被 vendor 的依赖虽然参与类型检查，其导出函数的形参来源无法追踪，同样视为未知
+func (ra *reflectionAnalysis) unknownExternal(pkgPath string) bool {
+	if ra.project[pkgPath] && !ra.o.vendoredPkgs[pkgPath] {
+		return false
+	}
+	if _, ok := reflectionSinks[pkgPath]; ok {
+		return false
+	}
+	if _, ok := formatSinks[pkgPath]; ok {
+		return false
+	}
+	return !isStandardLibrary(pkgPath)
+}
+
+// interfaceArgs 返回对应形参含有接口或类型参数的实参
+func interfaceArgs(sig *types.Signature, call *ast.CallExpr) []ast.Expr {
+	params := sig.Params()
+	if params.Len() == 0 {
+		return nil
+	}
+	var args []ast.Expr
+	for i, arg := range call.Args {
+		idx := min(i, params.Len()-1)
+		t := params.At(idx).Type()
+		if sig.Variadic() && idx == params.Len()-1 && !call.Ellipsis.IsValid() {
+			if s, ok := t.Underlying().(*types.Slice); ok {
+				t = s.Elem()
+			}
+		}
+		if containsInterface(t, make(map[types.Type]bool)) {
+			args = append(args, arg)
+		}
+	}
+	return args
+}
+
+// protectExpr 保护表达式可能持有的所有具体类型，返回 false 表示存在无法确定的来源
+func (ra *reflectionAnalysis) protectExpr(info *types.Info, expr ast.Expr, kind string) bool {
+	expr = ast.Unparen(expr)
+	t := info.TypeOf(expr)
+	if t == nil {
+		return false
+	}
+	if !containsInterface(t, make(map[types.Type]bool)) {
+		ra.protectType(t, kind)
+		return true
+	}
+
+	switch e := expr.(type) {
+	case *ast.Ident:
+		v, ok := info.Uses[e].(*types.Var)
+		if !ok {
+			return e.Name == "nil"
+		}
+		key := ra.varKey(v)
+		if ra.external[key] {
+			return false
+		}
+		if ra.visiting[key] {
+			return true
+		}
+		ra.visiting[key] = true
+		defer delete(ra.visiting, key)
+
+		sources := ra.flows[key]
+		if len(sources) == 0 {
+			// 没有赋值来源的接口变量只能是零值，或由未分析到的代码赋值
+			return v.Parent() != nil && v.Parent() != v.Pkg().Scope()
+		}
+		ok = true
+		for _, src := range sources {
+			if !ra.protectExpr(src.info, src.expr, kind) {
+				ok = false
+			}
+		}
+		return ok
+
+	case *ast.UnaryExpr:
+		if e.Op == token.AND {
+			return ra.protectExpr(info, e.X, kind)
+		}
+
+	case *ast.CompositeLit:
+		ra.protectType(t, kind)
+		ok := true
+		for _, elt := range e.Elts {
+			if kv, isKV := elt.(*ast.KeyValueExpr); isKV {
+				// 结构体字面量的键是字段名，不是表达式
+				if _, isStruct := t.Underlying().(*types.Struct); !isStruct {
+					if !ra.protectExpr(info, kv.Key, kind) {
+						ok = false
+					}
+				}
+				elt = kv.Value
+			}
+			if !ra.protectExpr(info, elt, kind) {
+				ok = false
+			}
+		}
+		return ok
+
+	case *ast.CallExpr:
+		// 转换为接口类型，例如 any(v)
+		if tv, isConv := info.Types[e.Fun]; isConv && tv.IsType() && len(e.Args) == 1 {
+			return ra.protectExpr(info, e.Args[0], kind)
+		}
+
+	case *ast.BasicLit:
+		return true
+	}
+
+	return false
+}
+
+// protectType 保护类型名、方法名，并递归保护结构体字段
+func (ra *reflectionAnalysis) protectType(t types.Type, kind string) {
+	t = types.Unalias(t)
+	if ra.visited[visitKey{t, kind}] {
+		return
+	}
+	ra.visited[visitKey{t, kind}] = true
+
+	switch x := t.(type) {
+	case *types.Named:
+		obj := x.Obj()
+		if obj.Pkg() != nil && ra.project[obj.Pkg().Path()] {
+			ra.protect(obj, kind)
+			for i := 0; i < x.NumMethods(); i++ {
+				ra.protect(x.Method(i), kind)
+			}
+			key := obj.Pkg().Path() + "." + obj.Name()
+			if ra.reflected[key] == nil {
+				ra.reflected[key] = make(map[string]bool)
+			}
+			ra.reflected[key][kind] = true
+			ra.named[key] = x.Origin()
+		}
+		if args := x.TypeArgs(); args != nil {
+			for i := 0; i < args.Len(); i++ {
+				ra.protectType(args.At(i), kind)
+			}
+		}
+		ra.protectType(x.Underlying(), kind)
+	case *types.Pointer:
+		ra.protectType(x.Elem(), kind)
+	case *types.Slice:
+		ra.protectType(x.Elem(), kind)
+	case *types.Array:
+		ra.protectType(x.Elem(), kind)
+	case *types.Chan:
+		ra.protectType(x.Elem(), kind)
+	case *types.Map:
+		ra.protectType(x.Key(), kind)
+		ra.protectType(x.Elem(), kind)
+	case *types.Struct:
+		for i := 0; i < x.NumFields(); i++ {
+			field := x.Field(i)
+			if field.Pkg() != nil && ra.project[field.Pkg().Path()] {
+				ra.protect(field, kind)
+			}
+			ra.protectType(field.Type(), kind)
+		}
+	case *types.Interface:
+		for i := 0; i < x.NumExplicitMethods(); i++ {
+			if m := x.ExplicitMethod(i); m.Pkg() != nil && ra.project[m.Pkg().Path()] {
+				ra.protect(m, kind)
+			}
+		}
+	}
+}
+
+// protect 保护对象（类型、方法、字段）的名称并记录流入的调用类别和调用位置
+func (ra *reflectionAnalysis) protect(obj types.Object, kind string) {
+	ra.o.protectObject(obj, reasonReflection, fmt.Sprintf("流入 %s 调用 (%s)", kind, ra.sinkPos), ra.position(obj.Pos()))
+}
+
+// position 将分析用 FileSet 中的位置格式化为 file:line
+func (ra *reflectionAnalysis) position(pos token.Pos) string {
+	if !pos.IsValid() {
+		return ""
+	}
+	p := ra.fset.Position(pos)
+	return fmt.Sprintf("%s:%d", ra.o.displayPath(p.Filename), p.Line)
+}
+
+// containsInterface 判断类型中是否含有接口（接口值的动态类型无法静态确定）
+func containsInterface(t types.Type, seen map[types.Type]bool) bool {
+	t = types.Unalias(t)
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+
+	switch x := t.(type) {
+	case *types.Interface:
+		return true
+	case *types.TypeParam:
+		return true
+	case *types.Named:
+		return containsInterface(x.Underlying(), seen)
+	case *types.Pointer:
+		return containsInterface(x.Elem(), seen)
+	case *types.Slice:
+		return containsInterface(x.Elem(), seen)
+	case *types.Array:
+		return containsInterface(x.Elem(), seen)
+	case *types.Chan:
+		return containsInterface(x.Elem(), seen)
+	case *types.Map:
+		return containsInterface(x.Key(), seen) || containsInterface(x.Elem(), seen)
+	case *types.Struct:
+		for i := 0; i < x.NumFields(); i++ {
+			if containsInterface(x.Field(i).Type(), seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// calleeFunc 返回调用的目标函数（包括方法），无法静态确定时返回 nil
+func calleeFunc(info *types.Info, call *ast.CallExpr) *types.Func {
+	var id *ast.Ident
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		id = fun
+	case *ast.SelectorExpr:
+		id = fun.Sel
+	case *ast.IndexExpr:
+		if sel, ok := fun.X.(*ast.SelectorExpr); ok {
+			id = sel.Sel
+		} else if ident, ok := fun.X.(*ast.Ident); ok {
+			id = ident
+		}
+	}
+	if id == nil {
+		return nil
+	}
+	fn, _ := info.Uses[id].(*types.Func)
+	return fn
+}
+
+// funcSinkName 返回函数在 reflectionSinks 中的名称，方法写作 "类型.方法"
+func funcSinkName(fn *types.Func) string {
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return fn.Name()
+	}
+	t := recv.Type()
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	if named, ok := types.Unalias(t).(*types.Named); ok {
+		return named.Obj().Name() + "." + fn.Name()
+	}
+	return fn.Name()
+}
+
+// sinkKind 返回调用所属的类别（json、xml、yaml、toml、mapstructure、gob、template、reflect、fmt）
+func sinkKind(pkgPath string) string {
+	switch {
+	case pkgPath == "sigs.k8s.io/yaml":
+		// 先转换为 JSON，再由 encoding/json 按 json 标签编解码
+		return "json"
+	case strings.Contains(pkgPath, "yaml"):
+		return "yaml"
+	case strings.Contains(pkgPath, "toml"):
+		return "toml"
+	case strings.Contains(pkgPath, "mapstructure"):
+		return "mapstructure"
+	case strings.HasSuffix(pkgPath, "/template"):
+		return "template"
+	case pkgPath == "log":
+		return "fmt"
+	}
+	return filepath.Base(pkgPath)
+}
@@ -0,0 +1,61 @@
+package obfuscator_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cross-file-obfuscator/obfuscator"
+)
+
+// TestUnknownExternalEscape 流入未知第三方包接口形参的类型保持原名：第三方包可能通过反射读取字段名
+func TestUnknownExternalEscape(t *testing.T) {
+	skipIfShort(t)
+	config := obfuscator.DefaultConfig()
+	config.ObfuscateExported = true
+	obf, out := obfuscateThirdparty(t, config)
+
+	for _, name := range []string{"Account", "Owner", "Balance"} {
+		found := false
+		for _, r := range obf.Explain(name) {
+			if r.Kind == "反射" && strings.Contains(r.Detail, "external") {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%s 应因流入第三方包调用而保护，得到 %v", name, obf.Explain(name))
+		}
+	}
+
+	bin := filepath.Join(t.TempDir(), "app")
+	runGo(t, out, "build", "-o", bin, ".")
+	if got := runBinary(t, bin); got != thirdpartyOutput {
+		t.Errorf("输出 %q，期望 %q", got, thirdpartyOutput)
+	}
+}
+
+// TestSynthesizeTagsExternalEscape 同时流入 json 和第三方包接口形参的类型不合成标签改名：
+// 第三方包按字段名反射，不看标签。只流入 json 的类型照常改名
+func TestSynthesizeTagsExternalEscape(t *testing.T) {
+	skipIfShort(t)
+	config := obfuscator.DefaultConfig()
+	config.SynthesizeTags = true
+	obf, out := obfuscateThirdparty(t, config)
+
+	renamed := make(map[string]bool)
+	for _, tag := range obf.SynthesizedTags() {
+		renamed[tag.Type+"."+tag.Field] = true
+	}
+	if renamed["example.com/app.Account.Owner"] || renamed["example.com/app.Account.Balance"] {
+		t.Errorf("流入第三方包的 Account 字段不应改名: %v", obf.SynthesizedTags())
+	}
+	if !renamed["example.com/app.Settings.Theme"] {
+		t.Errorf("只流入 json 的 Settings.Theme 应合成标签改名: %v", obf.SynthesizedTags())
+	}
+
+	bin := filepath.Join(t.TempDir(), "app")
+	runGo(t, out, "build", "-o", bin, ".")
+	if got := runBinary(t, bin); got != thirdpartyOutput {
+		t.Errorf("输出 %q，期望 %q", got, thirdpartyOutput)
+	}
+}
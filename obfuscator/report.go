@@ -0,0 +1,421 @@
+package obfuscator
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 风险规则：混淆后可能改变运行行为、需要人工确认的代码
+const (
+	RiskReflectByName = "reflect-by-name"      // 通过 MethodByName、FieldByName 等按名称访问成员
+	RiskReflection    = "reflection-protected" // 因反射分析保持原名的名称
+	RiskUnsafe        = "unsafe"               // 导入 unsafe
+	RiskLinkname      = "linkname"             // //go:linkname 指令
+	RiskPlugin        = "plugin"               // 导入 plugin，按符号名查找
+)
+
+// riskRules 风险规则的说明和 SARIF 级别，按输出顺序排列
+var riskRules = []struct {
+	id, level, text string
+}{
+	{RiskReflectByName, "warning", "按名称通过反射访问成员，名称被混淆后会在运行时找不到"},
+	{RiskLinkname, "warning", "go:linkname 按符号名链接，两端名称必须一致"},
+	{RiskPlugin, "warning", "plugin 按符号名查找导出的函数和变量"},
+	{RiskUnsafe, "note", "unsafe 代码可能依赖内存布局，请确认混淆后的行为"},
+	{RiskReflection, "note", "名称流入反射或序列化调用，为保证运行正确保持原名"},
+}
+
+// Risk 混淆可能影响运行行为的一处代码
+type Risk struct {
+	Rule    string `json:"rule"` // Risk* 常量
+	Message string `json:"message"`
+	File    string `json:"file"` // 相对项目目录
+	Line    int    `json:"line"`
+	Column  int    `json:"column,omitempty"`
+}
+
+// Report 一次运行的机器可读报告：逐文件、逐包的混淆结果和风险位置
+type Report struct {
+	Output     string          `json:"output,omitempty"` // 输出目录（目录以外的 sink 时为空）
+	Passes     []string        `json:"passes"`
+	Statistics *Statistics     `json:"statistics"`
+	Packages   []PackageReport `json:"packages"`
+	Files      []FileReport    `json:"files"`
+	Risks      []Risk          `json:"risks"`
+}
+
+// PackageReport 一个包的混淆覆盖情况
+type PackageReport struct {
+	Dir              string  `json:"dir"` // 相对项目目录
+	Name             string  `json:"name"`
+	Files            int     `json:"files"`
+	ObfuscatedFiles  int     `json:"obfuscated_files"`
+	SkippedFiles     int     `json:"skipped_files"`
+	RenamedObjects   int     `json:"renamed_objects"`
+	IdentsRenamed    int     `json:"idents_renamed"`
+	StringsEncrypted int     `json:"strings_encrypted"`
+	JunkBlocks       int     `json:"junk_blocks"`
+	Coverage         float64 `json:"coverage"` // 混淆的文件占比
+	DurationMS       float64 `json:"duration_ms"`
+}
+
+// FileReport 一个文件的混淆结果
+type FileReport struct {
+	Path             string          `json:"path"`             // 相对项目目录
+	Output           string          `json:"output,omitempty"` // 相对输出目录
+	Package          string          `json:"package"`
+	Skipped          string          `json:"skipped,omitempty"` // 跳过原因
+	RenamedObjects   []RenamedObject `json:"renamed_objects,omitempty"`
+	IdentsRenamed    int             `json:"idents_renamed"`
+	StringsEncrypted int             `json:"strings_encrypted"`
+	JunkBlocks       int             `json:"junk_blocks"`
+	DurationMS       float64         `json:"duration_ms"`
+}
+
+// RenamedObject 文件中声明并被改名的一个对象
+type RenamedObject struct {
+	Name string `json:"name"`
+	To   string `json:"to"`
+	Kind string `json:"kind"` // func、var、const、local、field、example
+	Line int    `json:"line"`
+}
+
+// report 汇总运行报告，需在 transform 之后调用
+func (o *Obfuscator) report(outputDir string) *Report {
+	r := &Report{
+		Output:     outputDir,
+		Passes:     append([]string{}, o.passOrder...),
+		Statistics: o.GetStatistics(),
+		Packages:   []PackageReport{},
+		Files:      []FileReport{},
+		Risks:      append([]Risk{}, o.risks...),
+	}
+
+	objects := o.renamedObjects()
+	packages := make(map[string]*PackageReport)
+	pkgFor := func(dir, name string) *PackageReport {
+		key := dir + "#" + name
+		p, ok := packages[key]
+		if !ok {
+			p = &PackageReport{Dir: dir, Name: name}
+			packages[key] = p
+		}
+		return p
+	}
+
+	for _, pkg := range o.transformed {
+		for _, f := range pkg.Files {
+			dir := filepath.ToSlash(filepath.Dir(f.Path))
+			fr := FileReport{
+				Path:             filepath.ToSlash(f.Path),
+				Package:          pkg.Name,
+				RenamedObjects:   objects[f.Path],
+				IdentsRenamed:    f.identsRenamed,
+				StringsEncrypted: f.stringsEncrypted,
+				JunkBlocks:       f.junkBlocks,
+				DurationMS:       milliseconds(f.duration.Seconds()),
+			}
+			if rel, err := filepath.Rel(o.outputDir, f.OutputPath); err == nil {
+				fr.Output = filepath.ToSlash(rel)
+			}
+			r.Files = append(r.Files, fr)
+
+			p := pkgFor(dir, pkg.Name)
+			p.Files++
+			p.ObfuscatedFiles++
+			p.RenamedObjects += len(fr.RenamedObjects)
+			p.IdentsRenamed += fr.IdentsRenamed
+			p.StringsEncrypted += fr.StringsEncrypted
+			p.JunkBlocks += fr.JunkBlocks
+		}
+		if len(pkg.Files) > 0 {
+			dir := filepath.ToSlash(filepath.Dir(pkg.Files[0].Path))
+			pkgFor(dir, pkg.Name).DurationMS += milliseconds(pkg.duration.Seconds())
+		}
+	}
+
+	for path, reason := range o.skippedFiles {
+		display := o.displayPath(path)
+		name := ""
+		if file, ok := o.sourceByPath[path]; ok {
+			name = file.node.Name.Name
+		}
+		r.Files = append(r.Files, FileReport{Path: filepath.ToSlash(display), Package: name, Skipped: reason})
+		p := pkgFor(filepath.ToSlash(filepath.Dir(display)), name)
+		p.Files++
+		p.SkippedFiles++
+	}
+
+	for _, p := range packages {
+		if p.Files > 0 {
+			p.Coverage = float64(p.ObfuscatedFiles) / float64(p.Files)
+		}
+		r.Packages = append(r.Packages, *p)
+	}
+	sort.Slice(r.Packages, func(i, j int) bool {
+		if r.Packages[i].Dir != r.Packages[j].Dir {
+			return r.Packages[i].Dir < r.Packages[j].Dir
+		}
+		return r.Packages[i].Name < r.Packages[j].Name
+	})
+	sort.Slice(r.Files, func(i, j int) bool { return r.Files[i].Path < r.Files[j].Path })
+	return r
+}
+
+// renamedObjects 按文件（相对项目目录）整理改名的声明：包级对象和按位置改名的声明
+func (o *Obfuscator) renamedObjects() map[string][]RenamedObject {
+	byFile := make(map[string][]RenamedObject)
+	for obj, to := range o.objectMapping {
+		if to == "" || to == obj.Name {
+			continue
+		}
+		pos := o.fset.Position(obj.Pos)
+		file := o.displayPath(pos.Filename)
+		byFile[file] = append(byFile[file], RenamedObject{Name: obj.Name, To: to, Kind: objectKindName(obj.Kind), Line: pos.Line})
+	}
+	for _, d := range o.renamedDecls {
+		file, ok := o.sourceByAbs[d.pos.file]
+		if !ok {
+			continue
+		}
+		tokFile := o.fset.File(file.node.Pos())
+		if tokFile == nil || d.pos.offset > tokFile.Size() {
+			continue
+		}
+		line := tokFile.Line(tokFile.Pos(d.pos.offset))
+		display := o.displayPath(d.pos.file)
+		byFile[display] = append(byFile[display], RenamedObject{Name: d.from, To: d.to, Kind: d.kind, Line: line})
+	}
+	for _, objs := range byFile {
+		sort.Slice(objs, func(i, j int) bool {
+			if objs[i].Line != objs[j].Line {
+				return objs[i].Line < objs[j].Line
+			}
+			return objs[i].Name < objs[j].Name
+		})
+	}
+	return byFile
+}
+
+// objectKindName 报告中使用的对象类别名称
+func objectKindName(kind ObjectKind) string {
+	switch kind {
+	case ObjConst:
+		return "const"
+	case ObjVar:
+		return "var"
+	case ObjFunc:
+		return "func"
+	case ObjField:
+		return "field"
+	case ObjMethod:
+		return "method"
+	case ObjTypeParam:
+		return "typeparam"
+	}
+	return "unknown"
+}
+
+// milliseconds 秒转换为毫秒，保留三位小数
+func milliseconds(seconds float64) float64 {
+	return float64(int64(seconds*1e6)) / 1e3
+}
+
+// scanRisks 扫描项目源码（不含依赖）中混淆可能影响运行行为的位置
+func (o *Obfuscator) scanRisks() {
+	o.risks = nil
+	for _, file := range o.sourceFiles {
+		if o.isVendoredPath(file.path) {
+			continue
+		}
+		o.scanFileRisks(file)
+	}
+
+	// 反射分析保护的名称，位置形如 file:line
+	names := make([]string, 0, len(o.protectionReasons))
+	for name := range o.protectionReasons {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, reason := range o.protectionReasons[name] {
+			if reason.Kind != reasonReflection || reason.Position == "" {
+				continue
+			}
+			i := strings.LastIndex(reason.Position, ":")
+			if i < 0 {
+				continue
+			}
+			line, err := strconv.Atoi(reason.Position[i+1:])
+			if err != nil {
+				continue
+			}
+			o.risks = append(o.risks, Risk{
+				Rule:    RiskReflection,
+				Message: fmt.Sprintf("%s 保持原名: %s", name, reason.Detail),
+				File:    filepath.ToSlash(reason.Position[:i]),
+				Line:    line,
+			})
+		}
+	}
+
+	sort.SliceStable(o.risks, func(i, j int) bool {
+		a, b := o.risks[i], o.risks[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+}
+
+// scanFileRisks 检查一个文件的导入、linkname 指令和按名称的反射调用
+func (o *Obfuscator) scanFileRisks(file *sourceFile) {
+	display := filepath.ToSlash(o.displayPath(file.path))
+	add := func(rule, message string, pos ast.Node) {
+		p := o.fset.Position(pos.Pos())
+		o.risks = append(o.risks, Risk{Rule: rule, Message: message, File: display, Line: p.Line, Column: p.Column})
+	}
+
+	importsReflect := false
+	for _, imp := range file.node.Imports {
+		switch strings.Trim(imp.Path.Value, `"`) {
+		case "unsafe":
+			add(RiskUnsafe, "导入了 unsafe", imp)
+		case "plugin":
+			add(RiskPlugin, "导入了 plugin，插件中按名称查找的符号不能被混淆", imp)
+		case "reflect":
+			importsReflect = true
+		}
+	}
+	for _, group := range file.node.Comments {
+		for _, c := range group.List {
+			if strings.HasPrefix(c.Text, "//go:linkname ") {
+				add(RiskLinkname, strings.TrimPrefix(c.Text, "//"), c)
+			}
+		}
+	}
+	if !importsReflect {
+		return
+	}
+	ast.Inspect(file.node, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		switch sel.Sel.Name {
+		case "MethodByName", "FieldByName", "FieldByNameFunc":
+			message := "调用了 " + sel.Sel.Name
+			if len(call.Args) > 0 {
+				if lit, ok := call.Args[0].(*ast.BasicLit); ok {
+					message += "(" + lit.Value + ")"
+				}
+			}
+			add(RiskReflectByName, message, sel.Sel)
+		}
+		return true
+	})
+}
+
+// WriteJSON 以缩进的 JSON 写出报告
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// SARIF 2.1.0 的最小子集
+type (
+	sarifLog struct {
+		Version string     `json:"version"`
+		Schema  string     `json:"$schema"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool       sarifTool              `json:"tool"`
+		Results    []sarifResult          `json:"results"`
+		Properties map[string]interface{} `json:"properties,omitempty"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name  string      `json:"name"`
+		Rules []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID                   string       `json:"id"`
+		ShortDescription     sarifMessage `json:"shortDescription"`
+		DefaultConfiguration struct {
+			Level string `json:"level"`
+		} `json:"defaultConfiguration"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations"`
+	}
+	sarifLocation struct {
+		PhysicalLocation struct {
+			ArtifactLocation struct {
+				URI string `json:"uri"`
+			} `json:"artifactLocation"`
+			Region struct {
+				StartLine   int `json:"startLine"`
+				StartColumn int `json:"startColumn,omitempty"`
+			} `json:"region"`
+		} `json:"physicalLocation"`
+	}
+)
+
+// WriteSARIF 以 SARIF 2.1.0 写出风险位置，逐包的覆盖情况放在 run 的 properties 中
+func (r *Report) WriteSARIF(w io.Writer) error {
+	run := sarifRun{
+		Tool:       sarifTool{Driver: sarifDriver{Name: "cross-file-obfuscator"}},
+		Results:    []sarifResult{},
+		Properties: map[string]interface{}{"packages": r.Packages, "statistics": r.Statistics},
+	}
+	levels := make(map[string]string)
+	for _, rule := range riskRules {
+		sr := sarifRule{ID: rule.id, ShortDescription: sarifMessage{Text: rule.text}}
+		sr.DefaultConfiguration.Level = rule.level
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sr)
+		levels[rule.id] = rule.level
+	}
+	for _, risk := range r.Risks {
+		var loc sarifLocation
+		loc.PhysicalLocation.ArtifactLocation.URI = risk.File
+		loc.PhysicalLocation.Region.StartLine = risk.Line
+		loc.PhysicalLocation.Region.StartColumn = risk.Column
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    risk.Rule,
+			Level:     levels[risk.Rule],
+			Message:   sarifMessage{Text: risk.Message},
+			Locations: []sarifLocation{loc},
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	})
+}
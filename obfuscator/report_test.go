@@ -0,0 +1,119 @@
+package obfuscator_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"cross-file-obfuscator/obfuscator"
+)
+
+// TestReportRisks 每条风险规则在夹具中的对应位置触发，SARIF 输出符合 2.1.0 的结构
+func TestReportRisks(t *testing.T) {
+	project := copyTestdata(t, "riskapp")
+	_, result := obfuscate(t, project, obfuscator.DefaultConfig())
+	report := result.Report
+
+	want := map[string]int{ // 规则 -> 行号
+		obfuscator.RiskPlugin:        6,
+		obfuscator.RiskUnsafe:        8,
+		obfuscator.RiskLinkname:      11,
+		obfuscator.RiskReflection:    15,
+		obfuscator.RiskReflectByName: 26,
+	}
+	found := make(map[string]bool)
+	for _, risk := range report.Risks {
+		if risk.File != "main.go" {
+			t.Errorf("风险位置应相对项目目录: %+v", risk)
+		}
+		if line, ok := want[risk.Rule]; ok && risk.Line == line {
+			found[risk.Rule] = true
+		}
+	}
+	for rule, line := range want {
+		if !found[rule] {
+			t.Errorf("规则 %s 未在 main.go:%d 触发: %+v", rule, line, report.Risks)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var decoded obfuscator.Report
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("JSON 报告无法解析: %v", err)
+	}
+	if len(decoded.Risks) != len(report.Risks) || len(decoded.Files) == 0 {
+		t.Errorf("JSON 报告内容不完整: %d 个风险，%d 个文件", len(decoded.Risks), len(decoded.Files))
+	}
+
+	buf.Reset()
+	if err := report.WriteSARIF(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var sarif struct {
+		Version string `json:"version"`
+		Schema  string `json:"$schema"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string `json:"name"`
+					Rules []struct {
+						ID                   string `json:"id"`
+						DefaultConfiguration struct {
+							Level string `json:"level"`
+						} `json:"defaultConfiguration"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Level     string `json:"level"`
+				Message   struct{ Text string }
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct{ URI string }
+						Region           struct{ StartLine int }
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &sarif); err != nil {
+		t.Fatalf("SARIF 输出无法解析: %v", err)
+	}
+	if sarif.Version != "2.1.0" || !strings.Contains(sarif.Schema, "sarif-2.1.0") || len(sarif.Runs) != 1 {
+		t.Fatalf("SARIF 头部不正确: version=%q schema=%q runs=%d", sarif.Version, sarif.Schema, len(sarif.Runs))
+	}
+	run := sarif.Runs[0]
+	if run.Tool.Driver.Name == "" {
+		t.Error("SARIF 缺少 tool.driver.name")
+	}
+	levels := make(map[string]string)
+	for _, rule := range run.Tool.Driver.Rules {
+		switch rule.DefaultConfiguration.Level {
+		case "none", "note", "warning", "error":
+		default:
+			t.Errorf("规则 %s 的级别 %q 不是 SARIF 级别", rule.ID, rule.DefaultConfiguration.Level)
+		}
+		levels[rule.ID] = rule.DefaultConfiguration.Level
+	}
+	if len(run.Results) != len(report.Risks) {
+		t.Errorf("SARIF 有 %d 个结果，报告有 %d 个风险", len(run.Results), len(report.Risks))
+	}
+	for _, res := range run.Results {
+		if level, ok := levels[res.RuleID]; !ok || level != res.Level {
+			t.Errorf("结果引用了未声明的规则或级别不一致: %s %s", res.RuleID, res.Level)
+		}
+		if res.Message.Text == "" || len(res.Locations) != 1 {
+			t.Errorf("结果缺少消息或位置: %+v", res)
+			continue
+		}
+		loc := res.Locations[0].PhysicalLocation
+		if loc.ArtifactLocation.URI != "main.go" || loc.Region.StartLine < 1 {
+			t.Errorf("结果位置不正确: %+v", loc)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package obfuscator
+
+import (
+	"path/filepath"
+	"sort"
+)
+
+// Result Run 的返回值：输出位置、完整的名称映射和统计信息
+type Result struct {
+	OutputDir       string // 输出目录（WithOutput 为目录以外的 sink 时为空）
+	Mapping         *Mapping
+	Statistics      *Statistics
+	SkippedFiles    map[string]string // 未混淆的文件（相对项目目录）-> 原因
+	SynthesizedTags []SynthesizedTag  // 启用 SynthesizeTags 时补全的标签
+	EmbeddedFiles   []string          // 被 //go:embed 嵌入的文件（相对项目目录）
+	Report          *Report           // 逐文件、逐包的混淆结果和风险位置
+	Overlay         string            // Config.Overlay 时写出的 overlay.json（绝对路径），用于 go build -overlay
+}
+
+// Mapping 混淆前后的名称对应关系
+type Mapping struct {
+	Functions      map[string]string `json:"functions"`       // 包级函数：原名 -> 混淆名
+	Variables      map[string]string `json:"variables"`       // 包级变量和常量：原名 -> 混淆名
+	ImportAliases  map[string]string `json:"import_aliases"`  // 标准库导入路径 -> 别名
+	Files          map[string]string `json:"files"`           // 改名的文件：原路径 -> 输出路径（均相对各自根目录）
+	Objects        map[string]string `json:"objects"`         // 按对象改名的包级函数、变量和常量：包路径.原名 -> 混淆名
+	Identifiers    []IdentMapping    `json:"identifiers"`     // 按位置改名的标识符（局部变量、类型参数、序列化字段、包级声明）
+	DecryptPackage string            `json:"decrypt_package"` // 字符串解密包的导入路径（未加密字符串时为空）
+	DecryptFunc    string            `json:"decrypt_func"`    // 字符串解密函数名
+}
+
+// IdentMapping 一处按位置改名的标识符
+type IdentMapping struct {
+	File   string `json:"file"` // 相对项目目录
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+}
+
+// result 汇总 Run 的结果，需在 transform 之后调用
+func (o *Obfuscator) result() *Result {
+	skipped := make(map[string]string, len(o.skippedFiles))
+	for path, reason := range o.skippedFiles {
+		skipped[o.displayPath(path)] = reason
+	}
+	outputDir := o.outputDir
+	if o.stagedOutput {
+		outputDir = ""
+	}
+	return &Result{
+		OutputDir:       outputDir,
+		Mapping:         o.mapping(),
+		Statistics:      o.GetStatistics(),
+		SkippedFiles:    skipped,
+		SynthesizedTags: o.SynthesizedTags(),
+		EmbeddedFiles:   o.EmbeddedFiles(),
+		Report:          o.report(outputDir),
+		Overlay:         o.overlayFile,
+	}
+}
+
+// mapping 复制当前的名称映射
+func (o *Obfuscator) mapping() *Mapping {
+	m := &Mapping{
+		Functions:     copyStringMap(o.funcMapping),
+		Variables:     copyStringMap(o.varMapping),
+		Objects:       copyStringMap(o.objectRenames),
+		ImportAliases: copyStringMap(o.importAliasMapping),
+		Files:         make(map[string]string),
+	}
+	if o.decryptPkgCreated {
+		m.DecryptPackage = o.decryptPkgPath
+		m.DecryptFunc = o.decryptFuncName
+	}
+
+	for output, original := range o.fileMapping {
+		from := o.displayPath(original)
+		to, err := filepath.Rel(o.outputDir, output)
+		if err != nil || from == to {
+			continue
+		}
+		m.Files[from] = to
+	}
+
+	for abs, renames := range o.identRenames {
+		file, ok := o.sourceByAbs[abs]
+		if !ok {
+			continue
+		}
+		tokFile := o.fset.File(file.node.Pos())
+		if tokFile == nil {
+			continue
+		}
+		display := o.displayPath(abs)
+		for offset, r := range renames {
+			if offset > tokFile.Size() {
+				continue
+			}
+			pos := tokFile.Position(tokFile.Pos(offset))
+			m.Identifiers = append(m.Identifiers, IdentMapping{File: display, Line: pos.Line, Column: pos.Column, From: r.from, To: r.to})
+		}
+	}
+	sort.Slice(m.Identifiers, func(i, j int) bool {
+		a, b := m.Identifiers[i], m.Identifiers[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return m
+}
+
+// copyStringMap 复制映射，调用方修改结果不影响混淆器
+func copyStringMap(src map[string]string) map[string]string {
+	dst := make(map[string]string, len(src))
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
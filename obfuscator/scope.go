@@ -40,6 +40,7 @@ const (
 	ObjLabel              // 标签
 	ObjField              // 结构体字段
 	ObjMethod             // 方法
+	ObjTypeParam          // 类型参数
 )
 
 // ScopeAnalyzer 作用域分析器
@@ -149,6 +150,14 @@ func (sa *ScopeAnalyzer) analyzeFuncDecl(decl *ast.FuncDecl) {
 	// 进入函数作用域
 	funcScope := sa.enterScope(decl)
 	
+	// 分析类型参数（泛型函数）和接收者中声明的类型参数（泛型类型的方法）
+	sa.analyzeTypeParams(decl.Type.TypeParams)
+	if decl.Recv != nil {
+		for _, field := range decl.Recv.List {
+			sa.declareReceiverTypeParams(field.Type)
+		}
+	}
+
 	// 分析接收者
 	if decl.Recv != nil {
 		sa.analyzeFieldList(decl.Recv, ObjVar)
@@ -192,7 +201,15 @@ func (sa *ScopeAnalyzer) analyzeGenDecl(decl *ast.GenDecl) {
 			
 		case *ast.TypeSpec:
 			sa.declareObject(s.Name.Name, ObjType, s, s.Name.Pos())
-			sa.analyzeExpr(s.Type)
+			// 泛型类型的类型参数只在类型声明内部可见
+			if s.TypeParams != nil {
+				sa.enterScope(s)
+				sa.analyzeTypeParams(s.TypeParams)
+				sa.analyzeExpr(s.Type)
+				sa.leaveScope()
+			} else {
+				sa.analyzeExpr(s.Type)
+			}
 		}
 	}
 }
@@ -211,6 +228,62 @@ func (sa *ScopeAnalyzer) analyzeFieldList(fields *ast.FieldList, kind ObjectKind
 	}
 }
 
+// analyzeTypeParams 声明类型参数并分析约束
+func (sa *ScopeAnalyzer) analyzeTypeParams(params *ast.FieldList) {
+	if params == nil {
+		return
+	}
+	// 类型参数先全部声明，约束中可以引用后面的类型参数
+	for _, field := range params.List {
+		for _, name := range field.Names {
+			sa.declareObject(name.Name, ObjTypeParam, field, name.Pos())
+		}
+	}
+	for _, field := range params.List {
+		sa.analyzeExpr(field.Type)
+	}
+}
+
+// declareReceiverTypeParams 声明方法接收者 *List[K, V] 中的类型参数
+func (sa *ScopeAnalyzer) declareReceiverTypeParams(expr ast.Expr) {
+	var indices []ast.Expr
+	switch x := expr.(type) {
+	case *ast.StarExpr:
+		sa.declareReceiverTypeParams(x.X)
+		return
+	case *ast.ParenExpr:
+		sa.declareReceiverTypeParams(x.X)
+		return
+	case *ast.IndexExpr:
+		indices = []ast.Expr{x.Index}
+	case *ast.IndexListExpr:
+		indices = x.Indices
+	}
+	for _, index := range indices {
+		if ident, ok := index.(*ast.Ident); ok && ident.Name != "_" {
+			sa.declareObject(ident.Name, ObjTypeParam, expr, ident.Pos())
+		}
+	}
+}
+
+// IsTypeParamAt 判断原文件中指定偏移处的标识符是否引用类型参数。
+// 输出文件是原文件的逐字节副本，按偏移换算回分析时的位置
+func (sa *ScopeAnalyzer) IsTypeParamAt(name string, offset int) bool {
+	if sa.fileScope == nil {
+		return false
+	}
+	file := sa.fset.File(sa.fileScope.Start)
+	if file == nil || offset < 0 || offset > file.Size() {
+		return false
+	}
+	scope := sa.GetScopeAt(file.Pos(offset))
+	if scope == nil {
+		return false
+	}
+	obj := scope.LookupObject(name)
+	return obj != nil && obj.Kind == ObjTypeParam
+}
+
 // analyzeBlockStmt 分析块语句
 func (sa *ScopeAnalyzer) analyzeBlockStmt(block *ast.BlockStmt) {
 	// 块语句创建新作用域
@@ -433,6 +506,13 @@ func (sa *ScopeAnalyzer) analyzeExpr(expr ast.Expr) {
 	case *ast.IndexExpr:
 		sa.analyzeExpr(e.X)
 		sa.analyzeExpr(e.Index)
+
+	case *ast.IndexListExpr:
+		// 泛型实例化 Map[K, V]
+		sa.analyzeExpr(e.X)
+		for _, index := range e.Indices {
+			sa.analyzeExpr(index)
+		}
 		
 	case *ast.SliceExpr:
 		sa.analyzeExpr(e.X)
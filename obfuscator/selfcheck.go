@@ -0,0 +1,288 @@
+package obfuscator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	smokeTimeout     = 10 * time.Second
+	tracebackDelay   = 300 * time.Millisecond
+	tracebackTimeout = 5 * time.Second
+)
+
+var (
+	goroutineHeaderRe = regexp.MustCompile(`(?m)^goroutine \d+ (?:.* )?\[[^\]]+\]:$`)
+	tracebackFrameRe  = regexp.MustCompile(`(?m)^\S.*\(.*\)\n\t\S+:\d+`)
+)
+
+// crashMarkers 出现在输出中即说明二进制已损坏
+var crashMarkers = []string{
+	"panic: ",
+	"fatal error: ",
+	"unexpected fault address",
+	"SIGSEGV",
+	"SIGBUS",
+	"SIGILL",
+	"unknown pc",
+	"unexpected return pc",
+}
+
+// selfCheckWithFallback 对后处理后的二进制做自检，失败时恢复备份并依次尝试更安全的配置
+// 回退顺序：只混淆项目包 → 完全禁用 pclntab 修改
+func (lo *LinkerObfuscator) selfCheckWithFallback() error {
+	if reason := lo.selfCheckSkipReason(); reason != "" {
+		lo.logf("   ⚠️  跳过自检: %s\n", reason)
+		return nil
+	}
+
+	err := lo.selfCheckBinary()
+	if err == nil || errors.Is(err, errTracebackNotRun) {
+		lo.logSelfCheckPassed(err, "")
+		return nil
+	}
+	lo.logf("   ❌ 自检失败: %v\n", err)
+
+	// 只恢复本次后处理写出的备份，之前运行留下的 .backup 可能对应旧的构建
+	backupPath := lo.backupPath
+	if backupPath == "" {
+		return fmt.Errorf("自检失败，且本次构建未修改二进制，无法回退: %v", err)
+	}
+	original, readErr := os.ReadFile(backupPath)
+	if readErr != nil {
+		return fmt.Errorf("自检失败且无法读取备份 %s: %v", backupPath, readErr)
+	}
+
+	fallbacks := []struct {
+		name  string
+		apply func(cfg *LinkConfig)
+	}{
+		{"只混淆项目包 (-only-project)", func(cfg *LinkConfig) { cfg.OnlyObfuscateProject = true }},
+		{"禁用 pclntab 修改 (-disable-pclntab)", func(cfg *LinkConfig) { cfg.DisablePclntab = true }},
+	}
+
+	for _, fb := range fallbacks {
+		if err := os.WriteFile(lo.outputBin, original, 0755); err != nil {
+			return fmt.Errorf("恢复备份失败: %v", err)
+		}
+		lo.logf("   ↩️  已从备份恢复，改用更安全的配置重试: %s\n", fb.name)
+
+		safer := *lo.config
+		fb.apply(&safer)
+		lo.config = &safer
+
+		if err := lo.postProcessBinary(); err != nil {
+			return fmt.Errorf("使用 %s 重新后处理失败: %v", fb.name, err)
+		}
+		if err = lo.selfCheckBinary(); err == nil || errors.Is(err, errTracebackNotRun) {
+			lo.logSelfCheckPassed(err, fb.name)
+			return nil
+		}
+		lo.logf("   ❌ 自检仍失败: %v\n", err)
+	}
+
+	if err := os.WriteFile(lo.outputBin, original, 0755); err != nil {
+		return fmt.Errorf("恢复备份失败: %v", err)
+	}
+	return fmt.Errorf("所有回退配置均未通过自检，已恢复未修改的二进制（请检查 -smoke 参数是否正确）: %v", err)
+}
+
+// logSelfCheckPassed 输出自检结果。回溯检查未执行时不报告为通过，notRun 为 errTracebackNotRun 或 nil
+func (lo *LinkerObfuscator) logSelfCheckPassed(notRun error, fallback string) {
+	suffix := ""
+	if fallback != "" {
+		suffix = "（已回退到: " + fallback + "）"
+	}
+	if notRun != nil {
+		lo.logf("   ⚠️  冒烟命令通过，但%v，自检未完成%s\n", notRun, suffix)
+		return
+	}
+	lo.logf("   ✅ 自检通过%s\n", suffix)
+}
+
+// selfCheckSkipReason 返回无法在本机执行自检的原因
+func (lo *LinkerObfuscator) selfCheckSkipReason() string {
+	targetOS, targetArch := lo.targetPlatform()
+	if targetOS != runtime.GOOS || targetArch != runtime.GOARCH {
+		return fmt.Sprintf("目标平台 %s/%s 与本机 %s/%s 不同，无法执行", targetOS, targetArch, runtime.GOOS, runtime.GOARCH)
+	}
+	return ""
+}
+
+// selfCheckBinary 运行冒烟命令并检查强制回溯输出
+func (lo *LinkerObfuscator) selfCheckBinary() error {
+	args := strings.Fields(lo.config.SmokeArgs)
+	if len(args) == 0 {
+		args = []string{"-h"}
+	}
+
+	if err := lo.runSmokeCommand(args); err != nil {
+		return err
+	}
+	return lo.checkForcedTraceback(args)
+}
+
+// runSmokeCommand 执行冒烟命令并检查退出码
+// 超时仍在运行的进程（如服务程序）视为启动成功
+func (lo *LinkerObfuscator) runSmokeCommand(args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), smokeTimeout)
+	defer cancel()
+
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, lo.outputBin, args...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err := cmd.Run()
+
+	if marker := findCrashMarker(output.String()); marker != "" {
+		return fmt.Errorf("冒烟命令 %q 输出中出现 %q:\n%s", strings.Join(args, " "), marker, truncateOutput(output.String()))
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		lo.logf("   冒烟命令在 %v 内未退出，视为正常运行\n", smokeTimeout)
+		return nil
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		code := exitErr.ExitCode()
+		// 未指定冒烟命令时使用 -h，flag 包对未知参数返回 2，这也说明程序能正常启动
+		if lo.config.SmokeArgs == "" && code == 2 {
+			lo.logf("   冒烟命令 -h 退出码: %d\n", code)
+			return nil
+		}
+		return fmt.Errorf("冒烟命令 %q 退出码为 %d:\n%s", strings.Join(args, " "), code, truncateOutput(output.String()))
+	}
+	if err != nil {
+		return fmt.Errorf("无法执行冒烟命令: %v", err)
+	}
+
+	lo.logf("   冒烟命令 %q 退出码: 0\n", strings.Join(args, " "))
+	return nil
+}
+
+// errTracebackNotRun 无法强制输出回溯（不是二进制损坏），自检不能算作通过
+var errTracebackNotRun = errors.New("回溯检查未执行")
+
+// checkForcedTraceback 向运行中的进程发送 SIGQUIT 强制输出 goroutine 回溯，
+// 并检查回溯能被正常解析（pclntab 损坏时运行时会在此处报 unknown pc 等错误）。
+// 进程的输出接到一个预先写满的管道，第一次写 stdout/stderr 时就会阻塞，
+// 因此 -h 这类很快退出的命令也会在收到信号时仍在运行；发送信号后才开始读取管道
+func (lo *LinkerObfuscator) checkForcedTraceback(args []string) error {
+	if runtime.GOOS == "windows" {
+		return fmt.Errorf("%w（Windows 不支持 SIGQUIT）", errTracebackNotRun)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), tracebackTimeout)
+	defer cancel()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("无法创建管道: %v", err)
+	}
+	defer r.Close()
+	filled, err := fillPipe(w)
+	if err != nil {
+		w.Close()
+		return fmt.Errorf("无法填充管道: %v", err)
+	}
+
+	cmd := exec.CommandContext(ctx, lo.outputBin, args...)
+	cmd.Stdout = w
+	cmd.Stderr = w
+	cmd.Env = append(os.Environ(), "GOTRACEBACK=all")
+	err = cmd.Start()
+	w.Close() // 子进程持有自己的副本，退出后读取端收到 EOF
+	if err != nil {
+		return fmt.Errorf("无法启动二进制: %v", err)
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+
+	// 运行时在执行 main 之前安装信号处理；等待一段时间，确保信号到达时已经安装
+	select {
+	case <-exited:
+		return fmt.Errorf("%w（进程在 %v 内没有任何输出就退出了）", errTracebackNotRun, tracebackDelay)
+	case <-time.After(tracebackDelay):
+	}
+
+	signalErr := cmd.Process.Signal(syscall.SIGQUIT)
+	var output bytes.Buffer
+	io.Copy(&output, r)
+	<-exited
+	if signalErr != nil {
+		return fmt.Errorf("%w（无法发送 SIGQUIT: %v）", errTracebackNotRun, signalErr)
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("强制回溯在 %v 内未完成", tracebackTimeout)
+	}
+
+	text := output.String()
+	if len(text) >= filled {
+		text = text[filled:]
+	}
+	for _, marker := range []string{"unknown pc", "unexpected return pc", "fatal error: "} {
+		if strings.Contains(text, marker) {
+			return fmt.Errorf("强制回溯时运行时报错 %q:\n%s", marker, truncateOutput(text))
+		}
+	}
+	if !goroutineHeaderRe.MatchString(text) || !tracebackFrameRe.MatchString(text) {
+		return fmt.Errorf("强制回溯输出无法解析:\n%s", truncateOutput(text))
+	}
+
+	lo.logf("   强制回溯输出可正常解析")
+	return nil
+}
+
+// fillPipe 向非阻塞的管道写入端写入数据直到缓冲区写满，返回写入的字节数。
+// 先按块写入，再逐字节填满剩余空间，之后任何写入都会阻塞
+func fillPipe(w *os.File) (int, error) {
+	filled := 0
+	for _, size := range []int{4096, 1} {
+		chunk := make([]byte, size)
+		w.SetWriteDeadline(time.Now().Add(50 * time.Millisecond))
+		for {
+			n, err := w.Write(chunk)
+			filled += n
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				break
+			}
+			if err != nil {
+				return filled, err
+			}
+		}
+	}
+	return filled, w.SetWriteDeadline(time.Time{})
+}
+
+// findCrashMarker 返回输出中出现的第一个崩溃标记
+func findCrashMarker(output string) string {
+	for _, marker := range crashMarkers {
+		if strings.Contains(output, marker) {
+			return marker
+		}
+	}
+	return ""
+}
+
+// truncateOutput 截断过长的输出，便于在错误信息中展示
+func truncateOutput(output string) string {
+	const limit = 1000
+	if len(output) > limit {
+		return output[:limit] + "\n..."
+	}
+	return output
+}
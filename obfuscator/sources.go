@@ -0,0 +1,155 @@
+package obfuscator
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// sourceFile 加载阶段解析的一个 Go 文件。分析、类型检查和转换各阶段共用同一棵语法树，
+// 转换阶段在这棵树上直接修改（输出文件是原文件的逐字节副本，位置偏移一致）
+type sourceFile struct {
+	path string // 遍历得到的路径，与 skippedFiles 等使用的形式一致
+	abs  string
+	node *ast.File
+}
+
+// sourcePackage 同一目录下同名包的文件，以及它导入的包路径
+type sourcePackage struct {
+	dir     string
+	name    string
+	files   []*sourceFile
+	imports map[string]bool
+}
+
+// PhaseTiming 一个阶段的耗时
+type PhaseTiming struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// loadSources 遍历项目（以及重新生成的依赖目录）并把每个 Go 文件解析一次，
+// 按目录和包名组织成包图。无法解析的文件记录为跳过
+func (o *Obfuscator) loadSources() error {
+	o.sourceByPath = make(map[string]*sourceFile)
+	o.sourceByAbs = make(map[string]*sourceFile)
+	o.sourcePackages = make(map[string]*sourcePackage)
+
+	return o.walkSources(func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !strings.HasSuffix(path, ".go") {
+			return err
+		}
+		if o.isIgnoredVendorPath(path) {
+			return nil
+		}
+
+		node, err := parser.ParseFile(o.fset, path, nil, parser.ParseComments)
+		if err != nil {
+			o.warnf("无法解析文件 %s: %v", path, err)
+			if _, skipped := o.skippedFiles[path]; !skipped {
+				o.skippedFiles[path] = "Parse error: " + err.Error()
+			}
+			return nil
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			abs = path
+		}
+
+		file := &sourceFile{path: path, abs: abs, node: node}
+		o.sourceFiles = append(o.sourceFiles, file)
+		o.sourceByPath[path] = file
+		o.sourceByAbs[abs] = file
+
+		key := filepath.Dir(abs) + "#" + node.Name.Name
+		pkg, ok := o.sourcePackages[key]
+		if !ok {
+			pkg = &sourcePackage{dir: filepath.Dir(abs), name: node.Name.Name, imports: make(map[string]bool)}
+			o.sourcePackages[key] = pkg
+		}
+		pkg.files = append(pkg.files, file)
+		for _, imp := range node.Imports {
+			if imp.Path != nil {
+				pkg.imports[strings.Trim(imp.Path.Value, `"`)] = true
+			}
+		}
+		return nil
+	})
+}
+
+// parseForTypes 供类型检查使用：已加载的文件直接返回加载阶段的语法树，
+// cgo 生成的文件照常解析。项目外的依赖（含标准库）只需要包级声明的类型，
+// 丢弃函数体和注释，避免逐个检查依赖的全部函数体。被 vendor 的依赖使用 vendor 副本的语法树
+func (o *Obfuscator) parseForTypes(fset *token.FileSet, filename string, src []byte) (*ast.File, error) {
+	if copied, ok := o.vendoredFiles[filename]; ok {
+		filename = copied
+	}
+	if file, ok := o.sourceByAbs[filename]; ok {
+		return file.node, nil
+	}
+	mode := parser.AllErrors | parser.SkipObjectResolution
+	node, err := parser.ParseFile(fset, filename, src, mode)
+	if node == nil {
+		return nil, err
+	}
+	// cgo 生成的文件在构建缓存中，经 //line 映射回项目内的源文件，照常完整解析
+	if rel, relErr := filepath.Rel(o.projectRoot, fset.Position(node.Package).Filename); relErr == nil && !strings.HasPrefix(rel, "..") {
+		return parser.ParseFile(fset, filename, src, mode|parser.ParseComments)
+	}
+	for _, decl := range node.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok {
+			fn.Body = nil
+		}
+	}
+	return node, err
+}
+
+// lookupSource 按原始路径查找加载阶段的文件，路径形式不同时按绝对路径匹配
+func (o *Obfuscator) lookupSource(originalPath string) (*sourceFile, bool) {
+	if file, ok := o.sourceByPath[originalPath]; ok {
+		return file, true
+	}
+	if abs, err := filepath.Abs(originalPath); err == nil {
+		if file, ok := o.sourceByAbs[abs]; ok {
+			return file, true
+		}
+	}
+	return nil, false
+}
+
+// sourceAST 返回原文件的语法树；不在加载结果中的文件（例如解密包）从输出路径解析
+func (o *Obfuscator) sourceAST(originalPath, outputPath string) (*ast.File, error) {
+	if file, ok := o.lookupSource(originalPath); ok {
+		return file.node, nil
+	}
+	// overlay 模式下输出目录中没有原文件的副本
+	if o.Config.Overlay {
+		if _, err := os.Stat(originalPath); err == nil {
+			outputPath = originalPath
+		}
+	}
+	return parser.ParseFile(o.fset, outputPath, nil, parser.ParseComments)
+}
+
+// beginPhase 开始计时一个阶段并发出 EventPhaseStart，返回的函数结束计时、采样堆内存并发出 EventPhaseEnd
+func (o *Obfuscator) beginPhase(name string) func() {
+	start := time.Now()
+	o.currentPhase = name
+	o.emit(Event{Kind: EventPhaseStart, Phase: name})
+	return func() {
+		elapsed := time.Since(start)
+		o.phaseTimings = append(o.phaseTimings, PhaseTiming{Name: name, Duration: elapsed})
+		o.emit(Event{Kind: EventPhaseEnd, Phase: name, Duration: elapsed})
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		if m.HeapAlloc > o.peakHeap {
+			o.peakHeap = m.HeapAlloc
+		}
+		o.totalAlloc = m.TotalAlloc
+	}
+}
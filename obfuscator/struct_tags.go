@@ -0,0 +1,415 @@
+package obfuscator
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// synthesizedTagKeys 合成标签时可以补全的键，按此顺序追加；只补全类型实际流入的调用类别
+var synthesizedTagKeys = []string{"json", "xml", "yaml", "toml", "mapstructure"}
+
+// renamableKinds 只按标签决定线上字段名的调用类别；
+// reflect、fmt、template、gob 等会直接使用字段标识符，流入这些调用的类型不能改名
+var renamableKinds = map[string]bool{
+	"json": true, "xml": true, "yaml": true, "toml": true, "mapstructure": true,
+}
+
+// tagPassthroughOptions 带这些选项且名称为空的标签不使用字段名，保持原样
+var tagPassthroughOptions = []string{"inline", "squash", "remain", "chardata", "innerxml", "comment", "any"}
+
+// SynthesizedTag 结构体标签合成报告中的一项
+type SynthesizedTag struct {
+	Type     string   // 包路径.类型名
+	Field    string   // 原字段名
+	NewName  string   // 混淆后的字段名
+	Position string   // 字段声明位置 file:line
+	Added    []string // 新增或补全的标签，例如 json:"Name"
+}
+
+// fieldRename 一个待改名的序列化字段及其全部引用位置
+type fieldRename struct {
+	SynthesizedTag
+	declFile   string
+	declOffset int
+	tag        string // 合成后的标签字面量，无需修改时为空
+	uses       []identPos
+}
+
+// identPos 标识符在源文件中的位置
+type identPos struct {
+	file   string // 绝对路径
+	offset int
+}
+
+// planFieldRenames 为只流入按标签序列化的调用的结构体字段规划改名：
+// 先合成携带原始线上名称的标签，再按类型检查得到的引用位置改名字段标识符
+func (ra *reflectionAnalysis) planFieldRenames(pkgs []*packages.Package, fallback map[string]bool) {
+	// 字段声明位置 -> AST 字段，用于检查多名称字段和读取原标签
+	astFields := make(map[string]*ast.Field)
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Syntax {
+			ast.Inspect(file, func(n ast.Node) bool {
+				if st, ok := n.(*ast.StructType); ok && st.Fields != nil {
+					for _, field := range st.Fields.List {
+						for _, name := range field.Names {
+							astFields[ra.fset.Position(name.Pos()).String()] = field
+						}
+					}
+				}
+				return true
+			})
+		}
+	}
+
+	// 被其他结构体嵌入的类型不改名：标签会影响 encoding/json 对同名提升字段的取舍
+	embedded := make(map[*types.TypeName]bool)
+	for _, named := range ra.named {
+		if st, ok := named.Underlying().(*types.Struct); ok {
+			for i := 0; i < st.NumFields(); i++ {
+				if !st.Field(i).Embedded() {
+					continue
+				}
+				t := st.Field(i).Type()
+				if p, ok := t.(*types.Pointer); ok {
+					t = p.Elem()
+				}
+				if n, ok := types.Unalias(t).(*types.Named); ok {
+					embedded[n.Origin().Obj()] = true
+				}
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(ra.reflected))
+	for key := range ra.reflected {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	byDecl := make(map[string]*fieldRename)
+	var plan []*fieldRename
+	for _, key := range keys {
+		named := ra.named[key]
+		if named == nil || !ra.tagRenamable(key, named, fallback) || embedded[named.Obj()] {
+			continue
+		}
+		st := named.Underlying().(*types.Struct)
+		for i := 0; i < st.NumFields(); i++ {
+			f := st.Field(i)
+			if !f.Exported() || f.Name() == "XMLName" {
+				continue
+			}
+			declPos := ra.fset.Position(f.Pos())
+			astField := astFields[declPos.String()]
+			if astField == nil || len(astField.Names) != 1 {
+				continue
+			}
+			tag, added, ok := synthesizeTag(f.Name(), st.Tag(i), ra.reflected[key])
+			if !ok {
+				continue
+			}
+			absFile, err := filepath.Abs(declPos.Filename)
+			if err != nil {
+				continue
+			}
+
+			r := &fieldRename{
+				SynthesizedTag: SynthesizedTag{
+					Type:     key,
+					Field:    f.Name(),
+					NewName:  ra.o.newFieldName(named, f.Pkg(), key+"."+f.Name()),
+					Position: fmt.Sprintf("%s:%d", declPos.Filename, declPos.Line),
+					Added:    added,
+				},
+				declFile:   absFile,
+				declOffset: declPos.Offset,
+				tag:        tag,
+			}
+			byDecl[declPos.String()] = r
+			plan = append(plan, r)
+		}
+	}
+	if len(plan) == 0 {
+		return
+	}
+
+	// 收集每个字段的全部引用（声明、选择器、复合字面量的键），测试变体中的重复位置在应用时合并
+	for _, pkg := range pkgs {
+		collect := func(id *ast.Ident, obj types.Object) {
+			v, ok := obj.(*types.Var)
+			if !ok || !v.IsField() {
+				return
+			}
+			r := byDecl[ra.fset.Position(v.Origin().Pos()).String()]
+			if r == nil {
+				return
+			}
+			pos := ra.fset.Position(id.Pos())
+			abs, err := filepath.Abs(pos.Filename)
+			if err != nil {
+				abs = pos.Filename
+			}
+			r.uses = append(r.uses, identPos{file: abs, offset: pos.Offset})
+		}
+		for id, obj := range pkg.TypesInfo.Defs {
+			collect(id, obj)
+		}
+		for id, obj := range pkg.TypesInfo.Uses {
+			collect(id, obj)
+		}
+	}
+
+	ra.o.fieldRenamePlan = plan
+}
+
+// tagRenamable 判断类型的字段能否通过合成标签改名
+func (ra *reflectionAnalysis) tagRenamable(key string, named *types.Named, fallback map[string]bool) bool {
+	escaped := false
+	for kind := range ra.reflected[key] {
+		if kind == kindExternal {
+			escaped = true
+			continue
+		}
+		if !renamableKinds[kind] {
+			return false
+		}
+	}
+	pkg := named.Obj().Pkg()
+	if fallback[pkg.Path()] {
+		return false
+	}
+	// 导出字段可能被项目外的代码引用，只有 main/internal 包或显式允许混淆导出名称时才改名
+	if !ra.o.Config.ObfuscateExported && pkg.Name() != "main" && !isInternalImportPath(pkg.Path()) {
+		return false
+	}
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return false
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		if st.Field(i).Embedded() {
+			return false
+		}
+	}
+	// 值还流入了未知第三方包的接口形参：对方可能不看标签而直接按字段名反射
+	if escaped {
+		ra.o.warnf("类型 %s 的值流入第三方包的接口形参，字段保持原名", key)
+		return false
+	}
+	return true
+}
+
+// isInternalImportPath 判断包是否位于 internal 目录下（无法被其他模块导入）
+func isInternalImportPath(path string) bool {
+	return strings.HasSuffix(path, "/internal") || strings.Contains(path, "/internal/") || strings.HasPrefix(path, "internal/")
+}
+
+// newFieldName 生成仍为导出名称（序列化库只处理导出字段）且不与已有字段/方法冲突的新字段名，
+// key 为 包路径.类型名.字段名，上一次运行为其分配过名称时沿用
+func (o *Obfuscator) newFieldName(named *types.Named, pkg *types.Package, key string) string {
+	if name, ok := o.stable.get(stableField, key); ok {
+		if obj, _, _ := types.LookupFieldOrMethod(named, true, pkg, name); obj == nil {
+			return name
+		}
+	}
+	for {
+		name := "X" + o.randomString(7)
+		if obj, _, _ := types.LookupFieldOrMethod(named, true, pkg, name); obj == nil {
+			return name
+		}
+	}
+}
+
+// synthesizeTag 为字段补全携带原始线上名称的标签，只补全 kinds 中出现的调用类别。
+// 返回新的标签字面量（无需修改时为空）和新增的标签；ok 为 false 表示原标签无法解析
+func synthesizeTag(fieldName string, tag string, kinds map[string]bool) (literal string, added []string, ok bool) {
+	pairs, ok := parseStructTag(tag)
+	if !ok {
+		return "", nil, false
+	}
+
+	for _, key := range synthesizedTagKeys {
+		if !kinds[key] {
+			continue
+		}
+		wire := defaultWireName(key, fieldName)
+
+		idx := -1
+		for i, p := range pairs {
+			if p.key == key {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			pairs = append(pairs, tagPair{key: key, value: wire})
+			added = append(added, fmt.Sprintf("%s:%q", key, wire))
+			continue
+		}
+
+		value := pairs[idx].value
+		name, opts, _ := strings.Cut(value, ",")
+		if name != "" || hasPassthroughOption(opts) {
+			continue
+		}
+		if opts != "" || strings.Contains(value, ",") {
+			value = wire + "," + opts
+		} else {
+			value = wire
+		}
+		pairs[idx].value = value
+		added = append(added, fmt.Sprintf("%s:%q", key, value))
+	}
+
+	if len(added) == 0 {
+		return "", nil, true
+	}
+	parts := make([]string, len(pairs))
+	for i, p := range pairs {
+		parts[i] = p.key + ":" + strconv.Quote(p.value)
+	}
+	joined := strings.Join(parts, " ")
+	if strings.Contains(joined, "`") {
+		return strconv.Quote(joined), added, true
+	}
+	return "`" + joined + "`", added, true
+}
+
+// defaultWireName 返回没有标签时各库使用的线上名称：yaml.v2/v3 把字段名整体转为小写；
+// encoding/json、encoding/xml、BurntSushi/toml 和 mapstructure 使用字段名本身（解码时 json、toml、mapstructure 不区分大小写）
+func defaultWireName(key, fieldName string) string {
+	if key == "yaml" {
+		return strings.ToLower(fieldName)
+	}
+	return fieldName
+}
+
+// hasPassthroughOption 判断标签选项中是否有不使用名称的选项
+func hasPassthroughOption(opts string) bool {
+	for _, opt := range strings.Split(opts, ",") {
+		for _, p := range tagPassthroughOptions {
+			if opt == p {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// tagPair 结构体标签中的一个 key:"value"
+type tagPair struct {
+	key   string
+	value string
+}
+
+// parseStructTag 按 reflect.StructTag 的约定解析标签，保留原有顺序
+func parseStructTag(tag string) ([]tagPair, bool) {
+	var pairs []tagPair
+	for tag != "" {
+		i := 0
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		tag = tag[i:]
+		if tag == "" {
+			break
+		}
+
+		i = 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			return nil, false
+		}
+		key := tag[:i]
+		tag = tag[i+1:]
+
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			return nil, false
+		}
+		value, err := strconv.Unquote(tag[:i+1])
+		if err != nil {
+			return nil, false
+		}
+		pairs = append(pairs, tagPair{key: key, value: value})
+		tag = tag[i+1:]
+	}
+	return pairs, true
+}
+
+// finalizeFieldRenames 在收集完跳过文件后确定最终的字段改名：
+// 任一引用位于项目外或不会被处理的文件中时放弃该字段，否则改名后无法编译
+func (o *Obfuscator) finalizeFieldRenames() {
+	absRoot, err := filepath.Abs(o.projectRoot)
+	if err != nil {
+		return
+	}
+	skipped := make(map[string]bool)
+	for path := range o.skippedFiles {
+		if abs, err := filepath.Abs(path); err == nil {
+			skipped[abs] = true
+		}
+	}
+
+	o.tagRewrites = make(map[string]map[int]string)
+	for _, r := range o.fieldRenamePlan {
+		safe := true
+		for _, use := range r.uses {
+			if !strings.HasPrefix(use.file, absRoot+string(filepath.Separator)) || skipped[use.file] || o.isIgnoredVendorPath(use.file) {
+				safe = false
+				break
+			}
+		}
+		if !safe {
+			o.warnf("字段 %s.%s 在不会被混淆的文件中被引用，保持原名", r.Type, r.Field)
+			continue
+		}
+		// 模板按名称访问字段，无法通过类型检查追踪
+		if o.hasProtectionReason(r.Field, reasonTemplate) {
+			o.warnf("字段 %s.%s 在模板中被引用，保持原名", r.Type, r.Field)
+			continue
+		}
+
+		for _, use := range append(r.uses, identPos{file: r.declFile, offset: r.declOffset}) {
+			o.addIdentRename(use, r.Field, r.NewName)
+		}
+		o.recordRenamedDecl(identPos{file: r.declFile, offset: r.declOffset}, "field", r.Field, r.NewName)
+		if r.tag != "" {
+			if o.tagRewrites[r.declFile] == nil {
+				o.tagRewrites[r.declFile] = make(map[int]string)
+			}
+			o.tagRewrites[r.declFile][r.declOffset] = r.tag
+			o.synthesizedTags = append(o.synthesizedTags, r.SynthesizedTag)
+		}
+		o.fieldsRenamed++
+	}
+	o.logf("结构体标签合成: %d 个序列化字段改名，%d 个字段新增标签", o.fieldsRenamed, len(o.synthesizedTags))
+}
+
+// SynthesizedTags 返回结构体标签合成报告（按类型和字段排序）
+func (o *Obfuscator) SynthesizedTags() []SynthesizedTag {
+	report := make([]SynthesizedTag, len(o.synthesizedTags))
+	copy(report, o.synthesizedTags)
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Type != report[j].Type {
+			return report[i].Type < report[j].Type
+		}
+		return report[i].Field < report[j].Field
+	})
+	return report
+}
@@ -0,0 +1,340 @@
+package obfuscator
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template/parse"
+)
+
+// templateFileExts 按扩展名识别的模板文件；其他扩展名的文件只有被 ParseFiles/ParseGlob/ParseFS 引用时才解析
+var templateFileExts = map[string]bool{
+	".tmpl": true, ".tpl": true, ".gotmpl": true, ".gohtml": true,
+}
+
+// 模板解析调用的参数类别
+const (
+	templateArgText  = iota // 模板文本
+	templateArgFiles        // 文件路径
+	templateArgGlob         // glob 模式
+	templateArgFS           // fs.FS 之后的 glob 模式
+)
+
+// templateParseMethods 解析模板的函数/方法 -> 参数类别
+var templateParseMethods = map[string]int{
+	"Parse":      templateArgText,
+	"ParseFiles": templateArgFiles,
+	"ParseGlob":  templateArgGlob,
+	"ParseFS":    templateArgFS,
+}
+
+// collectTemplateFiles 解析项目中按扩展名识别的模板文件，保护其中引用的字段和方法
+func (o *Obfuscator) collectTemplateFiles() {
+	if o.parsedTemplates == nil {
+		o.parsedTemplates = make(map[string]bool)
+	}
+	filepath.Walk(o.projectRoot, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			name := info.Name()
+			if path != o.projectRoot && (strings.HasPrefix(name, ".") || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if templateFileExts[strings.ToLower(filepath.Ext(path))] {
+			o.protectTemplateFile(path, "", "")
+		}
+		return nil
+	})
+}
+
+// protectTemplateFile 解析一个模板文件（每个文件只解析一次）
+func (o *Obfuscator) protectTemplateFile(path, left, right string) {
+	abs, err := filepath.Abs(path)
+	if err != nil || o.parsedTemplates[abs] {
+		return
+	}
+	o.parsedTemplates[abs] = true
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	text := string(data)
+	display := o.displayPath(path)
+	refs, err := templateFieldRefs(display, text, left, right)
+	if err != nil {
+		o.warnf("无法解析模板 %s: %v", display, err)
+		return
+	}
+	for _, ref := range refs {
+		line := 1 + strings.Count(text[:ref.offset], "\n")
+		o.protectAt(ref.name, reasonTemplate, "."+ref.name, fmt.Sprintf("%s:%d", display, line))
+	}
+}
+
+// protectTemplateRefs 查找文件中的模板解析调用：Parse 的字符串参数直接解析，
+// ParseFiles/ParseGlob/ParseFS 的常量路径解析对应的文件
+func (o *Obfuscator) protectTemplateRefs(node *ast.File, path string) {
+	usesTemplate := false
+	for _, imp := range node.Imports {
+		if imp.Path != nil {
+			p := strings.Trim(imp.Path.Value, `"`)
+			if p == "text/template" || p == "html/template" {
+				usesTemplate = true
+				break
+			}
+		}
+	}
+	if !usesTemplate {
+		return
+	}
+	if o.parsedTemplates == nil {
+		o.parsedTemplates = make(map[string]bool)
+	}
+	dir := filepath.Dir(path)
+
+	ast.Inspect(node, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		kind, ok := templateParseMethods[sel.Sel.Name]
+		if !ok {
+			return true
+		}
+		left, right := templateDelims(sel.X)
+
+		switch kind {
+		case templateArgText:
+			if len(call.Args) != 1 {
+				return true
+			}
+			text, ok := constantString(call.Args[0])
+			if !ok {
+				return true
+			}
+			refs, err := templateFieldRefs(o.displayPath(path), text, left, right)
+			if err != nil {
+				return true
+			}
+			for _, ref := range refs {
+				o.protect(ref.name, reasonTemplate, "."+ref.name, call.Args[0].Pos())
+			}
+		case templateArgFiles, templateArgGlob, templateArgFS:
+			args := call.Args
+			if kind == templateArgFS {
+				if len(args) == 0 {
+					return true
+				}
+				args = args[1:]
+			}
+			for _, arg := range args {
+				pattern, ok := constantString(arg)
+				if !ok {
+					continue
+				}
+				// ParseFiles/ParseGlob 相对于运行目录（通常是项目根目录），ParseFS 相对于嵌入文件系统（包目录）
+				for _, base := range []string{o.projectRoot, dir} {
+					full := pattern
+					if !filepath.IsAbs(full) {
+						full = filepath.Join(base, pattern)
+					}
+					matches := []string{full}
+					if kind != templateArgFiles {
+						matches, _ = filepath.Glob(full)
+					}
+					for _, m := range matches {
+						if info, err := os.Stat(m); err == nil && !info.IsDir() {
+							o.protectTemplateFile(m, left, right)
+						}
+					}
+				}
+			}
+		}
+		return true
+	})
+}
+
+// templateDelims 从调用链中查找 Delims("[[", "]]")，未设置时返回空字符串（使用默认分隔符）
+func templateDelims(x ast.Expr) (string, string) {
+	for {
+		call, ok := x.(*ast.CallExpr)
+		if !ok {
+			return "", ""
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return "", ""
+		}
+		if sel.Sel.Name == "Delims" && len(call.Args) == 2 {
+			left, ok1 := constantString(call.Args[0])
+			right, ok2 := constantString(call.Args[1])
+			if ok1 && ok2 {
+				return left, right
+			}
+		}
+		x = sel.X
+	}
+}
+
+// constantString 求值字符串字面量、字面量拼接以及引用同文件字符串常量的标识符
+func constantString(expr ast.Expr) (string, bool) {
+	switch x := expr.(type) {
+	case *ast.BasicLit:
+		if x.Kind != token.STRING {
+			return "", false
+		}
+		s, err := strconv.Unquote(x.Value)
+		return s, err == nil
+	case *ast.ParenExpr:
+		return constantString(x.X)
+	case *ast.BinaryExpr:
+		if x.Op != token.ADD {
+			return "", false
+		}
+		l, ok := constantString(x.X)
+		if !ok {
+			return "", false
+		}
+		r, ok := constantString(x.Y)
+		return l + r, ok
+	case *ast.Ident:
+		if x.Obj == nil || x.Obj.Kind != ast.Con {
+			return "", false
+		}
+		spec, ok := x.Obj.Decl.(*ast.ValueSpec)
+		if !ok {
+			return "", false
+		}
+		for i, name := range spec.Names {
+			if name.Name == x.Name && i < len(spec.Values) {
+				return constantString(spec.Values[i])
+			}
+		}
+	}
+	return "", false
+}
+
+// templateRef 模板中的一个字段或方法引用
+type templateRef struct {
+	name   string
+	offset int // 在模板文本中的字节偏移
+}
+
+// templateFieldRefs 解析模板文本（含 define 定义的子模板），返回 .Field / .Method 形式的引用。
+// 只返回导出名称：模板只能访问导出的字段和方法，小写名称只可能是 map 的键
+func templateFieldRefs(name, text, left, right string) ([]templateRef, error) {
+	tree := parse.New(name)
+	tree.Mode = parse.SkipFuncCheck
+	treeSet := make(map[string]*parse.Tree)
+	if _, err := tree.Parse(text, left, right, treeSet); err != nil {
+		return nil, err
+	}
+
+	var refs []templateRef
+	add := func(offset parse.Pos, idents []string) {
+		for _, ident := range idents {
+			if isExported(ident) {
+				refs = append(refs, templateRef{name: ident, offset: int(offset)})
+			}
+		}
+	}
+	var walk func(node parse.Node)
+	walkBranch := func(b *parse.BranchNode) {
+		walk(b.Pipe)
+		walk(b.List)
+		walk(b.ElseList)
+	}
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				walk(cmd)
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *parse.FieldNode:
+			add(n.Pos, n.Ident)
+		case *parse.ChainNode:
+			walk(n.Node)
+			add(n.Pos, n.Field)
+		case *parse.VariableNode:
+			if len(n.Ident) > 1 {
+				add(n.Pos, n.Ident[1:])
+			}
+		case *parse.IfNode:
+			walkBranch(&n.BranchNode)
+		case *parse.RangeNode:
+			walkBranch(&n.BranchNode)
+		case *parse.WithNode:
+			walkBranch(&n.BranchNode)
+		case *parse.TemplateNode:
+			walk(n.Pipe)
+		}
+	}
+	for _, t := range treeSet {
+		walk(t.Root)
+	}
+	return refs, nil
+}
+
+// protectLinknameTargets 保护 //go:linkname 引用的本地名称和目标名称，
+// 以及没有函数体的函数声明（由汇编或 linkname 提供实现）
+func (o *Obfuscator) protectLinknameTargets(node *ast.File) {
+	for _, group := range node.Comments {
+		for _, c := range group.List {
+			if !strings.HasPrefix(c.Text, "//go:linkname ") {
+				continue
+			}
+			fields := strings.Fields(c.Text)
+			if len(fields) < 2 {
+				continue
+			}
+			o.protect(fields[1], reasonLinkname, c.Text, c.Pos())
+			if len(fields) < 3 {
+				continue
+			}
+			// 目标形如 importpath.name 或 importpath.Type.method，导入路径本身可能包含点
+			target := fields[2]
+			if slash := strings.LastIndex(target, "/"); slash >= 0 {
+				target = target[slash+1:]
+			}
+			parts := strings.Split(target, ".")
+			for _, name := range parts[1:] {
+				o.protect(name, reasonLinkname, c.Text, c.Pos())
+			}
+		}
+	}
+	for _, decl := range node.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body == nil {
+			o.protect(fn.Name.Name, reasonLinkname, "无函数体，由汇编或 linkname 提供实现", fn.Name.Pos())
+		}
+	}
+}
@@ -0,0 +1,12 @@
+package greet
+
+import "strings"
+
+// Greeting 生成问候语
+func Greeting(name string) string {
+	return decorate("hello, " + strings.ToUpper(name))
+}
+
+func decorate(message string) string {
+	return "[" + message + "]"
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"example.com/app/greet"
+)
+
+func main() {
+	name := "world"
+	if len(os.Args) > 1 && os.Args[1] != "-h" {
+		name = os.Args[1]
+	}
+	fmt.Println(greet.Greeting(name))
+}
@@ -0,0 +1,8 @@
+package main
+
+import "C"
+
+//export goDouble
+func goDouble(value C.int) C.int {
+	return value * 2
+}
@@ -0,0 +1,22 @@
+package main
+
+/*
+#include <stdlib.h>
+
+static int add(int a, int b) { return a + b; }
+*/
+import "C"
+
+import "fmt"
+
+// secretLabel 会被字符串加密（常量无法加密，因此使用变量）
+var secretLabel = "cgo-sum"
+
+func addInts(first, second int) int {
+	total := C.add(C.int(first), C.int(second))
+	return int(total)
+}
+
+func main() {
+	fmt.Println(secretLabel, addInts(2, 3))
+}
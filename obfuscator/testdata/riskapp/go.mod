@@ -0,0 +1,3 @@
+module example.com/riskapp
+
+go 1.22
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"plugin"
+	"reflect"
+	"unsafe"
+)
+
+//go:linkname nanotime runtime.nanotime
+func nanotime() int64
+
+type Config struct {
+	Name string
+}
+
+type service struct{}
+
+func (service) Start() string { return "started" }
+
+func main() {
+	data, _ := json.Marshal(Config{Name: "demo"})
+	fmt.Println(string(data))
+
+	m := reflect.ValueOf(service{}).MethodByName("Start")
+	fmt.Println(m.IsValid(), unsafe.Sizeof(service{}), nanotime() > 0)
+
+	if _, err := plugin.Open("missing.so"); err != nil {
+		fmt.Println("no plugin")
+	}
+}
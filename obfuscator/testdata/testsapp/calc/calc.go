@@ -0,0 +1,23 @@
+package calc
+
+// Sum 返回两数之和
+func Sum(a, b int) int {
+	return a + b
+}
+
+// Scale 按系数放大
+func Scale(values []int, factor int) []int {
+	scaled := make([]int, len(values))
+	for i, value := range values {
+		scaled[i] = value * factor
+	}
+	return scaled
+}
+
+//go:noinline
+func clamp(value, limit int) int {
+	if value > limit {
+		return limit
+	}
+	return value
+}
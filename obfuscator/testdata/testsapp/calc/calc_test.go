@@ -0,0 +1,24 @@
+package calc
+
+import "testing"
+
+func TestClamp(t *testing.T) {
+	if got := clamp(10, 3); got != 3 {
+		t.Fatalf("clamp(10, 3) = %d", got)
+	}
+}
+
+func BenchmarkSum(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		Sum(i, i)
+	}
+}
+
+func FuzzSum(f *testing.F) {
+	f.Add(1, 2)
+	f.Fuzz(func(t *testing.T, a, b int) {
+		if Sum(a, b) != Sum(b, a) {
+			t.Fatal("Sum is not commutative")
+		}
+	})
+}
@@ -0,0 +1,25 @@
+package calc_test
+
+import (
+	"fmt"
+	"testing"
+
+	"example.com/testsapp/calc"
+)
+
+func TestScale(t *testing.T) {
+	got := calc.Scale([]int{1, 2}, 3)
+	if len(got) != 2 || got[0] != 3 || got[1] != 6 {
+		t.Fatalf("Scale = %v", got)
+	}
+}
+
+func ExampleSum() {
+	fmt.Println(calc.Sum(1, 2))
+	// Output: 3
+}
+
+func ExampleScale_twice() {
+	fmt.Println(calc.Scale([]int{4}, 2))
+	// Output: [8]
+}
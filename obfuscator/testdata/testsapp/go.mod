@@ -0,0 +1,3 @@
+module example.com/testsapp
+
+go 1.22
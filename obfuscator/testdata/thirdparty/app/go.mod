@@ -0,0 +1,7 @@
+module example.com/app
+
+go 1.22
+
+require example.com/orm v0.0.0
+
+replace example.com/orm => ../orm
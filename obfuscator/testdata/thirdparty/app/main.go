@@ -0,0 +1,28 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"example.com/orm"
+)
+
+// Account 既按 json 标签序列化，又由第三方包按字段名映射列
+type Account struct {
+	Owner   string
+	Balance int
+}
+
+// Settings 只按 json 标签序列化
+type Settings struct {
+	Theme string
+}
+
+func main() {
+	fmt.Println(orm.Join([]string{"a", "b"}, ","))
+	account := &Account{Owner: "x"}
+	fmt.Println(orm.Join(orm.Columns(account), ","))
+	data, _ := json.Marshal(account)
+	settings, _ := json.Marshal(Settings{Theme: "dark"})
+	fmt.Println(string(data), string(settings))
+}
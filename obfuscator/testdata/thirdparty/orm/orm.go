@@ -0,0 +1,26 @@
+// Package orm 模拟第三方依赖
+package orm
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Join 用分隔符连接各项
+func Join(items []string, sep string) string {
+	joinedResult := strings.Join(items, sep)
+	return "[" + joinedResult + "]"
+}
+
+// Columns 与常见的 ORM 一样，通过反射按字段名得到列名
+func Columns(v any) []string {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	columns := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		columns = append(columns, strings.ToLower(t.Field(i).Name))
+	}
+	return columns
+}
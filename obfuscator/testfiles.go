@@ -0,0 +1,161 @@
+package obfuscator
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// testEntryPrefixes go test 按名称查找的函数前缀
+var testEntryPrefixes = []string{"Test", "Benchmark", "Fuzz", "Example"}
+
+// exampleOutputRe 示例函数的输出注释，与 go test 的规则一致
+var exampleOutputRe = regexp.MustCompile(`(?i)^[[:space:]]*(unordered )?output:`)
+
+// exampleFunc 一个示例函数，名称中引用的标识符改名后示例名需要同步修改
+type exampleFunc struct {
+	name string
+	pos  identPos
+}
+
+// isTestFile 判断是否为测试文件
+func isTestFile(path string) bool {
+	return strings.HasSuffix(path, "_test.go")
+}
+
+// isTestEntryName 判断函数名是否为 go test 的入口：前缀之后为空或不以小写字母开头
+func isTestEntryName(name string) bool {
+	if name == "TestMain" {
+		return true
+	}
+	for _, prefix := range testEntryPrefixes {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		rest := name[len(prefix):]
+		if rest == "" {
+			return true
+		}
+		r, _ := utf8.DecodeRuneInString(rest)
+		return !unicode.IsLower(r)
+	}
+	return false
+}
+
+// protectTestEntryPoints 保护测试文件中的 Test/Benchmark/Fuzz/Example 函数和 TestMain，
+// 并记录示例函数，供 renameExamples 按混淆映射同步示例名
+func (o *Obfuscator) protectTestEntryPoints(node *ast.File, path string) {
+	if !isTestFile(path) {
+		return
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return
+	}
+	for _, decl := range node.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil || !isTestEntryName(fn.Name.Name) {
+			continue
+		}
+		o.protect(fn.Name.Name, reasonTestEntry, "", fn.Name.Pos())
+		if strings.HasPrefix(fn.Name.Name, "Example") {
+			offset := o.fset.Position(fn.Name.Pos()).Offset
+			o.exampleFuncs = append(o.exampleFuncs, exampleFunc{name: fn.Name.Name, pos: identPos{file: abs, offset: offset}})
+		}
+	}
+}
+
+// renameExamples 示例名 ExampleF、ExampleF_suffix 中的 F 必须是包中存在的标识符，
+// F 被混淆时按相同的映射修改示例名（类型和方法不改名）
+func (o *Obfuscator) renameExamples() {
+	for _, ex := range o.exampleFuncs {
+		rest := strings.TrimPrefix(ex.name, "Example")
+		if rest == "" || strings.HasPrefix(rest, "_") {
+			continue
+		}
+		ident, tail := rest, ""
+		if i := strings.Index(rest, "_"); i >= 0 {
+			ident, tail = rest[:i], rest[i:]
+		}
+		newIdent, ok := o.funcMapping[ident]
+		if !ok {
+			newIdent, ok = o.varMapping[ident]
+		}
+		if !ok {
+			continue
+		}
+		o.addIdentRename(ex.pos, ex.name, "Example"+newIdent+tail)
+		o.recordRenamedDecl(ex.pos, "example", ex.name, "Example"+newIdent+tail)
+	}
+}
+
+// exampleOutputComments 返回示例函数末尾的输出注释（从 Output: 行开始直到注释组结束），移除注释时必须保留
+func exampleOutputComments(node *ast.File) map[*ast.Comment]bool {
+	keep := make(map[*ast.Comment]bool)
+	for _, decl := range node.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil || fn.Body == nil || !strings.HasPrefix(fn.Name.Name, "Example") {
+			continue
+		}
+		// go test 只检查函数体中的最后一个注释组
+		var last *ast.CommentGroup
+		for _, group := range node.Comments {
+			if group.Pos() > fn.Body.Lbrace && group.End() < fn.Body.Rbrace {
+				last = group
+			}
+		}
+		if last == nil {
+			continue
+		}
+		found := false
+		for _, c := range last.List {
+			if !found {
+				text := strings.TrimPrefix(strings.TrimPrefix(c.Text, "//"), "/*")
+				found = exampleOutputRe.MatchString(text)
+			}
+			if found {
+				keep[c] = true
+			}
+		}
+	}
+	return keep
+}
+
+// VerifyTests 在输出目录运行 go test ./...，证明混淆后的测试与原项目一致。
+// 失败时在原项目中再运行一次，区分混淆引入的失败和原本就存在的失败
+func (o *Obfuscator) VerifyTests(ctx context.Context) (string, error) {
+	if o.sourceFS != nil || o.stagedOutput {
+		return "", fmt.Errorf("验证测试需要项目目录和输出目录（WithSourceFS 或目录以外的 WithOutput 时不可用）")
+	}
+	// overlay 模式在项目目录中通过 -overlay 运行混淆后的测试；go vet 无法进入只存在于 overlay 中的目录
+	//（例如解密包），因此关闭测试前的 vet
+	dir, args := o.outputDir, []string(nil)
+	if o.overlayFile != "" {
+		dir, args = o.projectRoot, []string{"-vet=off", "-overlay", o.overlayFile}
+	}
+	output, err := runGoTest(ctx, dir, args...)
+	if err == nil {
+		return output, nil
+	}
+	if ctx.Err() != nil {
+		return output, ctx.Err()
+	}
+	if _, origErr := runGoTest(ctx, o.projectRoot); origErr != nil {
+		return output, fmt.Errorf("混淆后的测试失败，原项目的测试同样失败: %v", err)
+	}
+	return output, fmt.Errorf("混淆后的测试失败（原项目测试通过）: %v", err)
+}
+
+// runGoTest 在目录中运行 go test ./...，args 为额外的构建参数
+func runGoTest(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "go", append(append([]string{"test"}, args...), "./...")...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	return string(output), err
+}
@@ -0,0 +1,75 @@
+package obfuscator_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cross-file-obfuscator/obfuscator"
+)
+
+// TestObfuscateTestFiles 混淆含内部测试、外部测试包、示例、基准和模糊测试的模块，
+// 混淆导出名称并注入垃圾代码后 VerifyTests 仍然通过
+func TestObfuscateTestFiles(t *testing.T) {
+	skipIfShort(t)
+	project := copyTestdata(t, "testsapp")
+
+	config := obfuscator.DefaultConfig()
+	config.ObfuscateExported = true
+	config.ObfuscateFileNames = true
+	config.EncryptStrings = true
+	config.InjectJunkCode = true
+	obf, result := obfuscate(t, project, config)
+
+	if output, err := obf.VerifyTests(context.Background()); err != nil {
+		t.Fatalf("VerifyTests 失败: %v\n%s", err, output)
+	}
+
+	// 测试入口保持原名；示例名跟随被测函数改名，输出注释保留
+	files, err := filepath.Glob(filepath.Join(result.OutputDir, "calc", "*_test.go"))
+	if err != nil || len(files) != 2 {
+		t.Fatalf("期望 2 个测试文件，得到 %v (%v)", files, err)
+	}
+	var tests string
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tests += string(data)
+	}
+	for _, kept := range []string{"func TestClamp(", "func BenchmarkSum(", "func FuzzSum(", "func TestScale(", "// Output: 3", "// Output: [8]"} {
+		if !strings.Contains(tests, kept) {
+			t.Errorf("测试文件中缺少 %s", kept)
+		}
+	}
+	// 注入的垃圾语句不能把后面函数的编译指令卷进函数体
+	sources, err := filepath.Glob(filepath.Join(result.OutputDir, "calc", "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calc []byte
+	for _, f := range sources {
+		if !strings.HasSuffix(f, "_test.go") {
+			if calc, err = os.ReadFile(f); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if !strings.Contains(string(calc), "\n\n//go:noinline\nfunc ") {
+		t.Errorf("//go:noinline 应紧贴在 clamp 的声明之前:\n%s", calc)
+	}
+
+	sum, ok := result.Mapping.Functions["Sum"]
+	if !ok {
+		t.Fatalf("Sum 应被混淆: %v", result.Mapping.Functions)
+	}
+	if !strings.Contains(tests, "func Example"+sum+"()") || strings.Contains(tests, "func ExampleSum()") {
+		t.Errorf("示例 ExampleSum 应随 Sum 改名为 Example%s", sum)
+	}
+	if scale := result.Mapping.Functions["Scale"]; !strings.Contains(tests, "func Example"+scale+"_twice()") {
+		t.Errorf("示例 ExampleScale_twice 应保留后缀并随 Scale 改名")
+	}
+}
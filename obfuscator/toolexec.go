@@ -0,0 +1,624 @@
+package obfuscator
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// ToolexecEnv 非空时表示当前进程由 go build -toolexec 调用，值为命名种子
+const ToolexecEnv = "CFO_TOOLEXEC"
+
+// toolexecDebugEnv 非空时在 stderr 输出每个包的处理情况
+const toolexecDebugEnv = "CFO_TOOLEXEC_DEBUG"
+
+// toolexecStatsEnv 非空时每个 compile 调用向该文件追加一行改名统计，由驱动进程在编译后汇总
+const toolexecStatsEnv = "CFO_TOOLEXEC_STATS"
+
+// toolexecCorePackages 运行时核心包：大量依赖汇编、linkname 和编译器内建约定，不做任何改写
+var toolexecCorePackages = []string{
+	"runtime",
+	"internal/runtime",
+	"internal/abi",
+	"internal/bytealg",
+	"internal/cpu",
+	"internal/goarch",
+	"internal/goos",
+	"internal/chacha8rand",
+	"internal/reflectlite",
+	"internal/syscall",
+	"reflect",
+	"sync/atomic",
+	"syscall",
+	"unsafe",
+}
+
+// IsToolexecInvocation 判断当前进程是否作为 -toolexec 包装器被调用
+func IsToolexecInvocation() bool {
+	return os.Getenv(ToolexecEnv) != "" && len(os.Args) > 1 && filepath.IsAbs(os.Args[1])
+}
+
+// RunToolexec 作为 -toolexec 包装器执行工具，args[0] 为工具路径，返回进程退出码
+// 只拦截 compile 和 link，其余工具（asm、cgo、pack、buildid 等）原样执行
+func RunToolexec(args []string) int {
+	seed := os.Getenv(ToolexecEnv)
+	tool, toolArgs := args[0], args[1:]
+	name := strings.TrimSuffix(filepath.Base(tool), ".exe")
+
+	if len(toolArgs) == 1 && toolArgs[0] == "-V=full" {
+		return runToolVersion(tool, name, seed)
+	}
+
+	switch name {
+	case "compile":
+		toolArgs = obfuscateCompileArgs(toolArgs, seed)
+	case "link":
+		// 去除符号表和 DWARF，函数名与包路径由驱动进程在链接后统一后处理
+		toolArgs = append([]string{"-s", "-w"}, toolArgs...)
+	}
+
+	return runTool(tool, toolArgs, os.Stdout)
+}
+
+// runToolVersion 在 compile/link 的 -V=full 输出后追加种子哈希，
+// 使 go 的构建缓存区分混淆产物与普通产物、以及不同种子的产物
+func runToolVersion(tool, name, seed string) int {
+	var out bytes.Buffer
+	if code := runTool(tool, []string{"-V=full"}, &out); code != 0 {
+		os.Stdout.Write(out.Bytes())
+		return code
+	}
+	line := strings.TrimSpace(out.String())
+	if name != "compile" && name != "link" {
+		fmt.Println(line)
+		return 0
+	}
+
+	id := toolexecBuildID(seed)
+	if fields := strings.Fields(line); len(fields) > 0 && strings.HasPrefix(fields[len(fields)-1], "buildID=") {
+		// 开发版工具链使用 buildID 的内容部分作为缓存键
+		fmt.Printf("%s.%s\n", line, id)
+	} else {
+		fmt.Printf("%s +cfo-%s\n", line, id)
+	}
+	return 0
+}
+
+// toolexecBuildID 由种子和混淆器自身内容生成缓存标识，混淆器更新后缓存自动失效
+func toolexecBuildID(seed string) string {
+	h := sha256.New()
+	io.WriteString(h, seed)
+	if exe, err := os.Executable(); err == nil {
+		if f, err := os.Open(exe); err == nil {
+			io.Copy(h, f)
+			f.Close()
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+func runTool(tool string, args []string, stdout io.Writer) int {
+	cmd := exec.Command(tool, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		fmt.Fprintf(os.Stderr, "cross-file-obfuscator: 无法执行 %s: %v\n", tool, err)
+		return 1
+	}
+	return 0
+}
+
+// compileInvocation 一次 compile 调用中与混淆相关的参数
+type compileInvocation struct {
+	pkgPath   string
+	lang      string
+	importcfg string
+	outDir    string
+	hasAsm    bool
+	files     []int // 源文件在参数列表中的下标
+}
+
+func parseCompileArgs(args []string) compileInvocation {
+	var inv compileInvocation
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		next := func() string {
+			if i+1 < len(args) {
+				i++
+				return args[i]
+			}
+			return ""
+		}
+		switch {
+		case arg == "-p":
+			inv.pkgPath = next()
+		case arg == "-importcfg":
+			inv.importcfg = next()
+		case arg == "-o":
+			inv.outDir = filepath.Dir(next())
+		case arg == "-symabis", arg == "-asmhdr":
+			inv.hasAsm = true
+			next()
+		case strings.HasPrefix(arg, "-lang="):
+			inv.lang = strings.TrimPrefix(arg, "-lang=")
+		case arg == "-trimpath", arg == "-buildid", arg == "-goversion", arg == "-embedcfg", arg == "-coveragecfg", arg == "-D", arg == "-installsuffix", arg == "-pgoprofile":
+			next()
+		case strings.HasSuffix(arg, ".go") && !strings.HasPrefix(arg, "-"):
+			inv.files = append(inv.files, i)
+		}
+	}
+	return inv
+}
+
+// obfuscateCompileArgs 对一个包的源文件做确定性重命名，返回指向改写后文件的参数
+// 任何一步失败都回退为原始参数，保证构建结果至少与不混淆时一致
+func obfuscateCompileArgs(args []string, seed string) []string {
+	inv := parseCompileArgs(args)
+	debugf := func(format string, a ...interface{}) {
+		if os.Getenv(toolexecDebugEnv) != "" {
+			fmt.Fprintf(os.Stderr, "[cfo] %s: "+format+"\n", append([]interface{}{inv.pkgPath}, a...)...)
+		}
+	}
+
+	if inv.pkgPath == "" || len(inv.files) == 0 || inv.outDir == "" {
+		return args
+	}
+	if isToolexecCorePackage(inv.pkgPath) {
+		recordToolexecStats(inv.pkgPath, nil)
+		return args
+	}
+	for _, idx := range inv.files {
+		base := filepath.Base(args[idx])
+		// cgo 生成的文件依赖 _Cfunc_ 等固定命名约定
+		if strings.HasPrefix(base, "_cgo_") || strings.HasSuffix(base, ".cgo1.go") {
+			debugf("跳过 cgo 包")
+			recordToolexecStats(inv.pkgPath, nil)
+			return args
+		}
+	}
+
+	rewritten, stats, err := rewriteCompilePackage(args, inv, seed)
+	if err != nil {
+		debugf("跳过: %v", err)
+		recordToolexecStats(inv.pkgPath, nil)
+		return args
+	}
+	debugf("已改写 %d 个文件", len(inv.files))
+	recordToolexecStats(inv.pkgPath, stats)
+	return rewritten
+}
+
+// toolexecStats 一个包的改名统计：toolexec 模式只改名局部对象和包级未导出的函数、变量、常量，
+// 其余对象按类别计数，在编译结束后报告
+type toolexecStats struct {
+	Locals   int // 改名的局部变量、常量和参数
+	Package  int // 改名的包级未导出函数、变量和常量
+	Types    int // 保持原名的类型（含类型参数）
+	Methods  int // 保持原名的方法
+	Fields   int // 保持原名的结构体字段
+	Exported int // 保持原名的包级导出函数、变量和常量
+	Skipped  int // 整个包未改写（运行时核心包、cgo 包或类型检查失败）
+}
+
+// recordToolexecStats 向 toolexecStatsEnv 指定的文件追加一行统计，stats 为 nil 表示整个包未改写。
+// 每行一次 O_APPEND 写入，并发的 compile 调用之间不会交错
+func recordToolexecStats(pkgPath string, stats *toolexecStats) {
+	path := os.Getenv(toolexecStatsEnv)
+	if path == "" {
+		return
+	}
+	if stats == nil {
+		stats = &toolexecStats{Skipped: 1}
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fmt.Fprintf(f, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\n", pkgPath,
+		stats.Locals, stats.Package, stats.Types, stats.Methods, stats.Fields, stats.Exported, stats.Skipped)
+}
+
+// readToolexecStats 汇总各个 compile 调用写入的统计，返回总计和涉及的包数
+func readToolexecStats(path string) (total toolexecStats, packages int, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return total, 0, err
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var pkgPath string
+		var s toolexecStats
+		if _, err := fmt.Sscanf(line, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d", &pkgPath,
+			&s.Locals, &s.Package, &s.Types, &s.Methods, &s.Fields, &s.Exported, &s.Skipped); err != nil {
+			continue
+		}
+		total.Locals += s.Locals
+		total.Package += s.Package
+		total.Types += s.Types
+		total.Methods += s.Methods
+		total.Fields += s.Fields
+		total.Exported += s.Exported
+		total.Skipped += s.Skipped
+		packages++
+	}
+	return total, packages, nil
+}
+
+func isToolexecCorePackage(pkgPath string) bool {
+	for _, core := range toolexecCorePackages {
+		if pkgPath == core || strings.HasPrefix(pkgPath, core+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// rewriteCompilePackage 类型检查整个包，重命名可安全改名的对象并写出新文件
+//
+// 只改名不会跨包可见的对象：函数内的局部变量/常量/参数，以及包级未导出的函数、变量和常量。
+// 类型、方法、结构体字段和导出名称不改名（嵌入字段名、反射、接口实现和其他包的导出数据都依赖它们），
+// 返回的统计记录两类对象的数量。
+// 新名称由 (种子, 包路径, 原名) 哈希得到，同一种子下任何包、任何次构建都得到相同名称。
+func rewriteCompilePackage(args []string, inv compileInvocation, seed string) ([]string, *toolexecStats, error) {
+	fset := token.NewFileSet()
+	var files []*ast.File
+	var sources [][]byte
+	for _, idx := range inv.files {
+		src, err := os.ReadFile(args[idx])
+		if err != nil {
+			return nil, nil, err
+		}
+		file, err := parser.ParseFile(fset, args[idx], src, parser.ParseComments)
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, file)
+		sources = append(sources, src)
+	}
+
+	lookup, err := importcfgLookup(inv.importcfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	goarch := os.Getenv("GOARCH")
+	if goarch == "" {
+		goarch = runtime.GOARCH
+	}
+	var firstErr error
+	conf := types.Config{
+		Importer:  importer.ForCompiler(fset, "gc", lookup),
+		Sizes:     types.SizesFor("gc", goarch),
+		GoVersion: inv.lang,
+		Error: func(err error) {
+			if firstErr == nil {
+				firstErr = err
+			}
+		},
+	}
+	info := &types.Info{
+		Defs:      make(map[*ast.Ident]types.Object),
+		Uses:      make(map[*ast.Ident]types.Object),
+		Implicits: make(map[ast.Node]types.Object),
+	}
+	pkg, _ := conf.Check(inv.pkgPath, fset, files, info)
+	if firstErr != nil {
+		return nil, nil, fmt.Errorf("类型检查失败: %v", firstErr)
+	}
+
+	keep := toolexecKeptNames(files)
+	renamable := func(obj types.Object) bool {
+		if obj == nil || obj.Pkg() != pkg || obj.Name() == "_" || keep[obj.Name()] {
+			return false
+		}
+		switch o := obj.(type) {
+		case *types.Var:
+			if o.IsField() {
+				return false
+			}
+		case *types.Const:
+		case *types.Func:
+			if o.Type().(*types.Signature).Recv() != nil {
+				return false
+			}
+		default:
+			return false
+		}
+		if obj.Parent() == pkg.Scope() {
+			// 汇编会按名称引用包级符号，go_asm.h 也会导出包级常量
+			return !inv.hasAsm && !obj.Exported() && obj.Name() != "init" && !(pkg.Name() == "main" && obj.Name() == "main")
+		}
+		// 局部对象：作用域链上不会跨包可见
+		return obj.Parent() != nil && obj.Parent() != types.Universe
+	}
+
+	stats := &toolexecStats{}
+	for _, obj := range info.Defs {
+		if obj == nil || obj.Pkg() != pkg || obj.Name() == "_" {
+			continue
+		}
+		packageLevel := obj.Parent() == pkg.Scope()
+		switch x := obj.(type) {
+		case *types.TypeName:
+			stats.Types++
+			continue
+		case *types.Func:
+			if x.Type().(*types.Signature).Recv() != nil {
+				stats.Methods++
+				continue
+			}
+		case *types.Var:
+			if x.IsField() {
+				stats.Fields++
+				continue
+			}
+		}
+		switch {
+		case renamable(obj) && packageLevel:
+			stats.Package++
+		case renamable(obj):
+			stats.Locals++
+		case packageLevel && obj.Exported():
+			stats.Exported++
+		}
+	}
+
+	srcDir := filepath.Join(inv.outDir, "cfo_src")
+	if err := os.MkdirAll(srcDir, 0755); err != nil {
+		return nil, nil, err
+	}
+
+	newArgs := append([]string(nil), args...)
+	for i, file := range files {
+		edits := make(map[int]identEdit)
+		tokFile := fset.File(file.Pos())
+		rename := func(id *ast.Ident, obj types.Object) {
+			if renamable(obj) {
+				edits[tokFile.Offset(id.Pos())] = identEdit{oldLen: len(id.Name), newName: toolexecName(seed, inv.pkgPath, id.Name)}
+			}
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.Ident:
+				if obj, ok := info.Defs[n]; ok && obj != nil {
+					rename(n, obj)
+				} else if obj, ok := info.Uses[n]; ok {
+					rename(n, obj)
+				}
+			case *ast.TypeSwitchStmt:
+				// switch x := v.(type) 中的 x 没有对应对象，各 case 中的隐式对象才有
+				if assign, ok := n.Assign.(*ast.AssignStmt); ok && len(assign.Lhs) == 1 {
+					if id, ok := assign.Lhs[0].(*ast.Ident); ok {
+						for _, clause := range n.Body.List {
+							if obj := info.Implicits[clause]; obj != nil {
+								rename(id, obj)
+								break
+							}
+						}
+					}
+				}
+			}
+			return true
+		})
+
+		orig := args[inv.files[i]]
+		absOrig, err := filepath.Abs(orig)
+		if err != nil {
+			return nil, nil, err
+		}
+		fileName := toolexecName(seed, inv.pkgPath, filepath.Base(orig)) + ".go"
+
+		var out bytes.Buffer
+		// //line 指令隐藏原文件名，目录保持不变以便 -trimpath 照常生效
+		fmt.Fprintf(&out, "//line %s:1\n", filepath.Join(filepath.Dir(absOrig), fileName))
+		out.Write(applyIdentEdits(sources[i], edits))
+
+		target := filepath.Join(srcDir, fmt.Sprintf("%03d_%s", i, fileName))
+		if err := os.WriteFile(target, out.Bytes(), 0644); err != nil {
+			return nil, nil, err
+		}
+		newArgs[inv.files[i]] = target
+	}
+	return newArgs, stats, nil
+}
+
+// toolexecKeptNames 收集必须保持原名的标识符：linkname 引用的名字、无函数体的声明（由汇编或
+// linkname 提供实现）以及 //export 导出给 C 的函数
+func toolexecKeptNames(files []*ast.File) map[string]bool {
+	keep := make(map[string]bool)
+	for _, file := range files {
+		for _, group := range file.Comments {
+			for _, c := range group.List {
+				switch {
+				case strings.HasPrefix(c.Text, "//go:linkname "):
+					fields := strings.Fields(c.Text)
+					for _, f := range fields[1:] {
+						keep[f] = true
+						if dot := strings.LastIndex(f, "."); dot >= 0 {
+							keep[f[dot+1:]] = true
+						}
+					}
+				case strings.HasPrefix(c.Text, "//export "):
+					keep[strings.TrimSpace(strings.TrimPrefix(c.Text, "//export "))] = true
+				}
+			}
+		}
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body == nil {
+				keep[fn.Name.Name] = true
+			}
+		}
+	}
+	return keep
+}
+
+// identEdit 一处标识符替换
+type identEdit struct {
+	oldLen  int
+	newName string
+}
+
+// applyIdentEdits 按偏移量把标识符替换为新名称
+func applyIdentEdits(src []byte, edits map[int]identEdit) []byte {
+	offsets := make([]int, 0, len(edits))
+	for off := range edits {
+		offsets = append(offsets, off)
+	}
+	sort.Ints(offsets)
+
+	var out bytes.Buffer
+	last := 0
+	for _, off := range offsets {
+		out.Write(src[last:off])
+		out.WriteString(edits[off].newName)
+		last = off + edits[off].oldLen
+	}
+	out.Write(src[last:])
+	return out.Bytes()
+}
+
+// toolexecName 由种子、包路径和原名生成确定性的未导出名称
+func toolexecName(seed, pkgPath, name string) string {
+	const letters = "abcdefghijklmnopqrstuvwxyz"
+	for salt := 0; ; salt++ {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%s\x00%d", seed, pkgPath, name, salt)))
+		b := make([]byte, 8)
+		for i := range b {
+			b[i] = letters[int(sum[i])%len(letters)]
+		}
+		candidate := string(b)
+		if !token.IsKeyword(candidate) && types.Universe.Lookup(candidate) == nil {
+			return candidate
+		}
+	}
+}
+
+// importcfgLookup 根据 compile 的 -importcfg 文件定位依赖包的导出数据
+func importcfgLookup(importcfg string) (importer.Lookup, error) {
+	packageFiles := make(map[string]string)
+	importMap := make(map[string]string)
+
+	f, err := os.Open(importcfg)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		verb, rest, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			continue
+		}
+		switch verb {
+		case "packagefile":
+			packageFiles[key] = value
+		case "importmap":
+			importMap[key] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return func(path string) (io.ReadCloser, error) {
+		if mapped, ok := importMap[path]; ok {
+			path = mapped
+		}
+		file, ok := packageFiles[path]
+		if !ok {
+			return nil, fmt.Errorf("importcfg 中没有包 %s", path)
+		}
+		return os.Open(file)
+	}, nil
+}
+
+// newToolexecSeed 生成随机命名种子
+func newToolexecSeed() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// buildBinaryToolexec 以 -toolexec 模式编译：由本程序拦截每个包的 compile 和最终的 link，
+// 依赖和标准库同样经过改写，不在磁盘上复制整个项目
+func (lo *LinkerObfuscator) buildBinaryToolexec(outputPath string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("无法定位混淆器自身路径: %v", err)
+	}
+	seed := lo.config.ToolexecSeed
+	if seed == "" {
+		seed = newToolexecSeed()
+	}
+
+	buildArgs := []string{"build", "-trimpath", "-toolexec", exe, "-o", outputPath}
+	if lo.config.Overlay != "" {
+		buildArgs = append(buildArgs, "-overlay", lo.config.Overlay)
+	}
+	buildArgs = append(buildArgs, lo.config.EntryPackage)
+	buildCmd := exec.Command("go", buildArgs...)
+	buildCmd.Dir = lo.projectDir
+	// 显式设置目标平台，包装器据此计算类型大小
+	goos, goarch := lo.targetPlatform()
+	buildCmd.Env = append(os.Environ(), ToolexecEnv+"="+seed, "GOOS="+goos, "GOARCH="+goarch)
+	if lo.goos != "" && lo.goarch != "" {
+		buildCmd.Env = append(buildCmd.Env, "CGO_ENABLED=0")
+	}
+
+	statsFile, err := os.CreateTemp("", "cfo-toolexec-*.stats")
+	if err != nil {
+		return err
+	}
+	statsFile.Close()
+	defer os.Remove(statsFile.Name())
+	buildCmd.Env = append(buildCmd.Env, toolexecStatsEnv+"="+statsFile.Name())
+
+	lo.logf("   执行命令: cd %s && %s=<种子> go %s\n", lo.projectDir, ToolexecEnv, strings.Join(buildArgs, " "))
+	lo.logf("   命名种子: %s（相同种子得到相同的混淆名称）\n", seed)
+
+	if output, err := buildCmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("编译失败: %v\n%s", err, output)
+	}
+	lo.logToolexecCoverage(statsFile.Name())
+	return nil
+}
+
+// logToolexecCoverage 报告 toolexec 模式的改名范围：与源码混淆不同，类型、方法、结构体字段和导出名称保持原名。
+// 构建缓存命中的包不会调用 compile，不计入统计
+func (lo *LinkerObfuscator) logToolexecCoverage(statsPath string) {
+	total, packages, err := readToolexecStats(statsPath)
+	if err != nil || packages == 0 {
+		lo.logf("   ⚠️  toolexec 模式只改名局部变量/常量/参数和包级未导出的函数、变量、常量；类型、方法、结构体字段和导出名称保持原名（本次编译的包全部命中构建缓存，无统计）")
+		return
+	}
+	lo.logf("   toolexec 改名范围（本次编译的 %d 个包，其中 %d 个未改写）:", packages, total.Skipped)
+	lo.logf("     已改名: 局部变量/常量/参数 %d，包级未导出函数/变量/常量 %d", total.Locals, total.Package)
+	lo.logf("     ⚠️  保持原名: 类型 %d，方法 %d，结构体字段 %d，导出函数/变量/常量 %d（需要混淆这些名称时使用源码混淆模式）",
+		total.Types, total.Methods, total.Fields, total.Exported)
+}
@@ -0,0 +1,122 @@
+package obfuscator_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"cross-file-obfuscator/obfuscator"
+)
+
+// TestToolexecBuild -toolexec 模式改写未导出名称，相同种子的两次构建得到相同的二进制
+func TestToolexecBuild(t *testing.T) {
+	skipIfShort(t)
+	project := copyTestdata(t, "app")
+
+	build := func(name string) []byte {
+		bin := filepath.Join(t.TempDir(), name)
+		lo := obfuscator.NewLinkerObfuscator(project, bin, &obfuscator.LinkConfig{
+			EntryPackage:   ".",
+			DisablePclntab: true,
+			UseToolexec:    true,
+			ToolexecSeed:   "test-seed",
+		})
+		if err := lo.BuildWithLinkerObfuscation(); err != nil {
+			t.Fatalf("toolexec 编译失败: %v", err)
+		}
+		if got := runBinary(t, bin, "gopher"); got != "[hello, GOPHER]\n" {
+			t.Errorf("toolexec 编译的二进制输出 %q", got)
+		}
+		data, err := os.ReadFile(bin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	first := build("first")
+	// 未导出函数改名，导出函数保持原名
+	if bytes.Contains(first, []byte("greet.decorate")) {
+		t.Error("未导出函数 decorate 应被改名")
+	}
+	if !bytes.Contains(first, []byte("greet.Greeting")) {
+		t.Error("toolexec 模式不改名导出函数，应保留 greet.Greeting")
+	}
+	if second := build("second"); !bytes.Equal(first, second) {
+		t.Error("相同种子的两次构建结果不同")
+	}
+}
+
+// TestToolexecRenameScope toolexec 模式只改名局部对象和包级未导出的函数、变量、常量；
+// 类型、方法、结构体字段和导出名称在所有包中保持原名（已知限制，需要时使用源码混淆模式）
+func TestToolexecRenameScope(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "shapes.go")
+	code := `package shapes
+
+type Point struct {
+	X, Y  int
+	label string
+}
+
+func (p Point) Norm() int {
+	total := p.X*p.X + p.Y*p.Y
+	return total
+}
+
+var Counter int
+
+var hidden = 3
+
+func Describe(p Point) string {
+	return p.label + helperName(p)
+}
+
+func helperName(p Point) string {
+	suffix := "!"
+	return suffix
+}
+`
+	if err := os.WriteFile(src, []byte(code), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sources, stats, err := obfuscator.ToolexecRewrite("example.com/shapes", dir, []string{src}, "test-seed")
+	if err != nil {
+		t.Fatalf("改写失败: %v", err)
+	}
+	out := sources[0]
+	for _, kept := range []string{"type Point struct", "X, Y  int", "label string", "func (", ") Norm() int", "var Counter int", "func Describe("} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("%q 应保持原名:\n%s", kept, out)
+		}
+	}
+	for _, renamed := range []string{"total", "hidden", "helperName", "suffix"} {
+		if strings.Contains(out, renamed) {
+			t.Errorf("%s 应被改名:\n%s", renamed, out)
+		}
+	}
+
+	want := obfuscator.ToolexecStats{Package: 2, Types: 1, Methods: 1, Fields: 3, Exported: 2}
+	want.Locals = stats.Locals
+	if *stats != want || stats.Locals == 0 {
+		t.Errorf("统计 %+v，期望 %+v（局部对象 > 0）", *stats, want)
+	}
+}
+
+// TestToolexecEmptyVersionLine 工具的 -V=full 输出为空行时不能越界
+func TestToolexecEmptyVersionLine(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("需要 shell 脚本")
+	}
+	tool := filepath.Join(t.TempDir(), "compile")
+	if err := os.WriteFile(tool, []byte("#!/bin/sh\necho\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if code := obfuscator.RunToolVersion(tool, "compile", "test-seed"); code != 0 {
+		t.Errorf("退出码 %d", code)
+	}
+}
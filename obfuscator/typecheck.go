@@ -0,0 +1,214 @@
+package obfuscator
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// loadPackages 类型检查项目中的全部包（含测试变体），结果供反射分析和局部变量改名共用。
+// 类型检查失败的包不会出现在 o.typedPkgs 中，o.failedPkgs 记录失败数量
+func (o *Obfuscator) loadPackages(ctx context.Context) error {
+	// 依赖也从源码类型检查（NeedDeps），不依赖编译器导出数据的格式版本；
+	// parseForTypes 丢弃依赖的函数体，只检查其包级声明。
+	// 项目文件复用加载阶段的语法树，位置与 o.fset 一致
+	cfg := &packages.Config{
+		Context:   ctx,
+		Fset:      o.fset,
+		ParseFile: o.parseForTypes,
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles | packages.NeedImports |
+			packages.NeedDeps | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo,
+		Dir:   o.projectRoot,
+		Tests: true,
+	}
+	// "./..." 不匹配 vendor 目录，依赖也不在项目目录中：启用 ObfuscateVendor 时按 vendor/modules.txt
+	// 显式加载依赖包，并把它们的文件对应到输出目录中的副本，使按作用域改名同样覆盖依赖
+	patterns := []string{"./..."}
+	vendored, err := o.vendoredPackages()
+	if err != nil {
+		return err
+	}
+	if len(vendored) > 0 {
+		if err := o.mapVendoredFiles(ctx, vendored); err != nil {
+			return err
+		}
+		patterns = append(patterns, vendored...)
+	}
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return err
+	}
+
+	o.vendoredPkgs = make(map[string]bool)
+	for _, path := range vendored {
+		o.vendoredPkgs[path] = true
+	}
+	o.typesFset = cfg.Fset
+	for _, pkg := range pkgs {
+		// vendor 目录不含依赖的测试文件，依赖的测试变体只能从模块缓存中检查，不参与改名
+		if o.vendoredPkgs[pkg.PkgPath] && pkg.ID != pkg.PkgPath || o.vendoredPkgs[testedPackage(pkg.PkgPath)] {
+			continue
+		}
+		if o.vendoredPkgs[pkg.PkgPath] {
+			pkg.GoFiles = o.vendoredPaths(pkg.GoFiles)
+			pkg.CompiledGoFiles = o.vendoredPaths(pkg.CompiledGoFiles)
+		}
+		if len(pkg.Errors) > 0 || pkg.TypesInfo == nil {
+			o.warnf("包 %s 类型检查失败，该包只使用基于名称的分析", pkg.PkgPath)
+			o.failedPkgs++
+			continue
+		}
+		o.typedPkgs = append(o.typedPkgs, pkg)
+	}
+	return nil
+}
+
+// vendoredPackages 返回 vendor/modules.txt 列出的依赖包路径（未启用 ObfuscateVendor 时为空）
+func (o *Obfuscator) vendoredPackages() ([]string, error) {
+	if o.vendorRoot == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(filepath.Join(o.vendorRoot, "modules.txt"))
+	if err != nil {
+		return nil, fmt.Errorf("读取 vendor/modules.txt 失败: %v", err)
+	}
+	// 以 # 开头的行描述模块，其余每行是一个被 vendor 的包
+	var paths []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			paths = append(paths, line)
+		}
+	}
+	return paths, nil
+}
+
+// mapVendoredFiles 记录依赖包在模块缓存（或 replace 目录）中的文件与 vendor 副本的对应关系。
+// go mod vendor 逐字节复制文件，类型检查时直接使用副本的语法树，位置即落在 vendor 目录中
+func (o *Obfuscator) mapVendoredFiles(ctx context.Context, paths []string) error {
+	cfg := &packages.Config{
+		Context: ctx,
+		Mode:    packages.NeedName | packages.NeedFiles,
+		Dir:     o.projectRoot,
+	}
+	pkgs, err := packages.Load(cfg, paths...)
+	if err != nil {
+		return err
+	}
+	o.vendoredFiles = make(map[string]string)
+	for _, pkg := range pkgs {
+		for _, path := range pkg.GoFiles {
+			copied, err := filepath.Abs(filepath.Join(o.vendorRoot, filepath.FromSlash(pkg.PkgPath), filepath.Base(path)))
+			if err != nil {
+				continue
+			}
+			if _, ok := o.sourceByAbs[copied]; ok {
+				o.vendoredFiles[path] = copied
+			}
+		}
+	}
+	return nil
+}
+
+// vendoredPaths 把依赖文件的路径替换为 vendor 副本的路径
+func (o *Obfuscator) vendoredPaths(paths []string) []string {
+	mapped := make([]string, len(paths))
+	for i, path := range paths {
+		mapped[i] = path
+		if copied, ok := o.vendoredFiles[path]; ok {
+			mapped[i] = copied
+		}
+	}
+	return mapped
+}
+
+// testedPackage 返回外部测试包或测试主包对应的被测包路径，其他包返回空
+func testedPackage(path string) string {
+	if tested, ok := strings.CutSuffix(path, "_test"); ok {
+		return tested
+	}
+	if tested, ok := strings.CutSuffix(path, ".test"); ok {
+		return tested
+	}
+	return ""
+}
+
+// identRename 按位置改名的一个标识符
+type identRename struct {
+	from string
+	to   string
+}
+
+// renamedDecl 按位置改名的一个声明（用于运行报告）
+type renamedDecl struct {
+	pos      identPos
+	kind     string // local、field、example
+	from, to string
+}
+
+// recordRenamedDecl 记录一个按位置改名的声明
+func (o *Obfuscator) recordRenamedDecl(pos identPos, kind, from, to string) {
+	o.renamedDecls = append(o.renamedDecls, renamedDecl{pos: pos, kind: kind, from: from, to: to})
+}
+
+// addIdentRename 记录一个按位置改名的标识符（位置来自类型检查，与原文件的字节偏移一致）
+func (o *Obfuscator) addIdentRename(pos identPos, from, to string) {
+	if o.identRenames == nil {
+		o.identRenames = make(map[string]map[int]identRename)
+	}
+	if o.identRenames[pos.file] == nil {
+		o.identRenames[pos.file] = make(map[int]identRename)
+	}
+	o.identRenames[pos.file][pos.offset] = identRename{from: from, to: to}
+}
+
+// applyIdentRenames 在输出文件中应用按位置记录的改名（字段、局部变量）和合成的结构体标签。
+// 输出文件是原文件的逐字节副本，因此可以直接使用原文件中的偏移量。返回改名的标识符出现次数
+func (o *Obfuscator) applyIdentRenames(node *ast.File, originalPath string) int {
+	abs, err := filepath.Abs(originalPath)
+	if err != nil {
+		return 0
+	}
+	renames := o.identRenames[abs]
+	if len(renames) == 0 {
+		return 0
+	}
+	tags := o.tagRewrites[abs]
+	renamed := 0
+
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.Field:
+			if len(x.Names) == 1 {
+				if tag, ok := tags[o.fset.Position(x.Names[0].Pos()).Offset]; ok {
+					pos := x.Type.End()
+					if x.Tag != nil {
+						pos = x.Tag.Pos()
+					}
+					x.Tag = &ast.BasicLit{ValuePos: pos, Kind: token.STRING, Value: tag}
+				}
+			}
+		case *ast.Ident:
+			if !x.Pos().IsValid() {
+				return true
+			}
+			r, ok := renames[o.fset.Position(x.Pos()).Offset]
+			if !ok || x.Name == r.to {
+				return true
+			}
+			// 名称映射阶段可能已按名称改过该标识符，只统计仍为原名的
+			if x.Name == r.from {
+				renamed++
+			}
+			x.Name = r.to
+		}
+		return true
+	})
+	return renamed
+}
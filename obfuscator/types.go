@@ -2,7 +2,11 @@ package obfuscator
 
 import (
 	"go/token"
-	"math/big"
+	"io/fs"
+	"math/rand/v2"
+	"sync/atomic"
+
+	"golang.org/x/tools/go/packages"
 )
 
 // Obfuscator 是混淆器的主结构体
@@ -18,25 +22,103 @@ type Obfuscator struct {
 
 	// 保护名称
 	protectedNames      map[string]bool
+	protectionReasons   map[string][]ProtectionReason // 名称 -> 保护原因及位置（用于 Explain）
+	protectionSeen      map[protectionKey]bool
+	protectionSites     map[string][]protectionSite  // 名称 -> 各条保护记录对应的标识符（用于按对象判断包级声明）
 	packageNames        map[string]bool
 	reflectionPackages  map[string]bool
 	skippedFiles        map[string]string
+	parsedTemplates     map[string]bool // 已解析的模板文件（绝对路径）
+	embeddedFiles       map[string]bool // 被 //go:embed 嵌入的文件（绝对路径）
+	cgoFiles            map[string]bool // 导入了 "C" 的文件（绝对路径）
+	cgoSources          map[string]*cgoSource
+	exampleFuncs        []exampleFunc   // 测试文件中的示例函数
+
+	// 加载阶段的解析结果，各阶段共用
+	sourceFiles    []*sourceFile
+	sourceByPath   map[string]*sourceFile    // 遍历路径 -> 文件
+	sourceByAbs    map[string]*sourceFile    // 绝对路径 -> 文件
+	sourcePackages map[string]*sourcePackage // "目录#包名" -> 包
+
+	// 阶段耗时和内存
+	currentPhase string
+	phaseTimings []PhaseTiming
+	peakHeap     uint64
+	totalAlloc   uint64
+
+	// 反射分析结果
+	reflectionAnalyzed map[string]bool            // 已通过类型检查分析的文件（绝对路径）
+	reflectedTypes     map[string]map[string]bool // "包路径.类型名" -> 流入的调用类别（json、reflect 等）
+
+	// 类型检查结果（loadPackages）
+	typedPkgs  []*packages.Package
+	typesFset  *token.FileSet
+	failedPkgs int
+
+	// 按位置改名（局部变量、序列化字段）
+	identRenames  map[string]map[int]identRename // 文件（绝对路径） -> 标识符偏移 -> 改名
+	localRenames  map[string]int                 // 原名 -> 按对象单独改名的局部变量数量
+	objectRenames map[string]string              // 包路径.原名 -> 按对象单独改名的包级函数、变量和常量
+	renamedIdents atomic.Int64                   // 实际改名的标识符出现次数（转换阶段并发累加）
+	renamedDecls  []renamedDecl                  // 按位置改名的声明（运行报告）
+	risks         []Risk                         // 混淆风险位置（运行报告）
+	transformed   []*PackageContext              // 转换阶段处理的包（运行报告）
+	passOrder     []string                       // 本次执行的 Pass（运行报告）
+
+	// 覆盖率
+	identCoverage   []KindCoverage  // 各类声明的改名情况（分析阶段结束时统计）
+	stringBytes     int             // 字符串字面量的总字节数
+	declaredNames   map[string]bool // 源码中声明的名称（扫描二进制时判断是否可读）
+	projectLiterals map[string]bool // 源码中的字符串字面量（扫描二进制时判断是否为明文）
+	binaryCoverage  *BinaryCoverage
+
+	// 转换阶段的其他计数（并发累加）
+	stringsEncrypted atomic.Int64 // 加密的字符串字面量
+	junkBlocks       atomic.Int64 // 注入了垃圾代码的函数
+
+	// 结构体标签合成
+	fieldRenamePlan []*fieldRename
+	tagRewrites     map[string]map[int]string // 文件（绝对路径） -> 字段名偏移 -> 新标签字面量
+	synthesizedTags []SynthesizedTag
+	fieldsRenamed   int
 
 	// Token 文件集
 	fset *token.FileSet
 
 	// 随机种子和计数器
-	randomSeed    *big.Int
+	seed          [32]byte
+	rng           *rand.Rand      // 分析阶段的随机源，转换阶段按文件使用 fileRand
+	usedNames     map[string]bool // 已分配的混淆名（包级名称、导入别名、局部变量）
+	stable        *stableNames    // 上一次运行分配的名称（WithPreviousResult）
+	onlyDirs      map[string]bool // 增量运行时需要重写的包目录（相对项目目录），nil 表示全部
 	encryptionKey string
 	namingCounter int
 
 	// 路径配置
-	projectRoot string
-	outputDir   string
+	projectRoot   string
+	outputDir     string
+	vendorRoot    string            // go mod vendor 生成的依赖目录（启用 ObfuscateVendor 时）
+	vendoredFiles map[string]string // 依赖文件在模块缓存中的路径 -> vendor 副本的绝对路径
+	vendoredPkgs  map[string]bool   // vendor/modules.txt 列出的依赖包路径
 
 	// 配置选项
 	Config *Config
 
+	// 日志和事件（WithLogger、WithEventHandler）
+	logger Logger
+	events EventHandler
+
+	// 输出文件 -> 原始文件（transform 中生成，用于 Result）
+	fileMapping map[string]string
+	overlayFile string // Config.Overlay 时写出的 overlay.json（绝对路径）
+
+	// 输入文件系统和输出 sink（WithSourceFS、WithOutput）
+	sourceFS     fs.FS
+	output       OutputSink
+	ioPrepared   bool
+	stagedOutput bool     // 输出先生成在临时目录，Run 结束后写入 output
+	tempDirs     []string // prepareIO 创建的临时目录
+
 	// 字符串加密追踪
 	encryptedStrings map[string]bool
 	decryptFuncAdded map[string]bool // 键为包的完整路径，用于多入口项目
@@ -61,17 +143,34 @@ type Config struct {
 	PreserveReflection bool     // 是否保留反射相关代码
 	SkipGeneratedCode  bool     // 是否跳过自动生成的代码
 	ExcludePatterns    []string // 要排除的文件模式
+	ObfuscateVendor    bool     // 是否通过 go mod vendor 对第三方依赖做源码级混淆
+	SynthesizeTags     bool     // 为序列化字段补全携带原始名称的标签，然后混淆字段名
+	EncryptCgoStrings  bool     // 是否同样加密 cgo 文件（导入 "C"）中的 Go 字符串
+	GeneratedPolicies  map[string]string // 生成器名称（小写，"*" 表示其他）-> skip/private/full，未配置时跳过
+	Seed               string   // 命名种子，相同种子和输入得到相同的输出（为空时随机）
+	Workers            int      // 转换阶段的并发数（<= 0 时使用 CPU 核数）
+	Passes             []string // 转换 Pass 的执行顺序（为空时使用默认顺序），未列出的 Pass 不执行
+	Overlay            bool     // 只写出改动的 Go 文件和 go build -overlay 使用的 overlay.json，不复制整个项目
 }
 
 // Statistics 存储混淆统计信息
 type Statistics struct {
-	TotalFiles      int
-	ObfuscatedFiles int
-	SkippedFiles    int
-	ProtectedNames  int
-	FunctionsObf    int
-	VariablesObf    int
-	StringsEncrypt  int
+	TotalFiles      int           `json:"total_files"`
+	ObfuscatedFiles int           `json:"obfuscated_files"`
+	SkippedFiles    int           `json:"skipped_files"`
+	ProtectedNames  int           `json:"protected_names"`
+	FunctionsObf    int           `json:"functions_obfuscated"`
+	VariablesObf    int           `json:"variables_obfuscated"`
+	FieldsObf       int           `json:"fields_obfuscated"`
+	IdentsRenamed   int           `json:"idents_renamed"` // 实际改名的标识符出现次数（声明和引用）
+	StringsEncrypt  int           `json:"strings_encrypted"`
+	JunkBlocks      int           `json:"junk_blocks"`       // 注入了垃圾代码的函数数
+	ParsedFiles     int           `json:"parsed_files"`      // 加载阶段解析的文件数（每个文件只解析一次）
+	Packages        int           `json:"packages"`          // 按目录和包名划分的包数
+	Phases          []PhaseTiming `json:"phases"`            // 各阶段耗时
+	PeakHeapBytes   uint64        `json:"peak_heap_bytes"`   // 阶段结束时采样到的最大堆内存
+	TotalAllocBytes uint64        `json:"total_alloc_bytes"` // 累计分配的内存
+	Coverage        *Coverage     `json:"coverage"`          // 混淆强度
 }
 
 // LinkConfig 链接器混淆配置
@@ -83,5 +182,12 @@ type LinkConfig struct {
 	ObfuscateThirdParty   bool              // 是否混淆第三方依赖包（谨慎使用）
 	OnlyObfuscateProject  bool              // 只混淆项目包，不修改标准库（减少杀软误报）⭐ 新增
 	DisablePclntab        bool              // 完全禁用 pclntab 修改（最安全）⭐ 新增
+	SelfCheck             bool              // 后处理后运行二进制自检，失败时自动恢复备份并回退到更安全的配置
+	SmokeArgs             string            // 自检时传给二进制的参数（空格分隔，默认 "-h"）
+	WindowsOnlyProject    bool              // 矩阵构建时 Windows 目标自动只混淆项目包（auto 模式使用）
+	UseToolexec           bool              // 以 go build -toolexec 包装器方式编译，直接改写每个包（含依赖和标准库）
+	ToolexecSeed          string            // -toolexec 模式的命名种子（为空时随机生成）
+	EmbeddedFiles         []string          // 源码混淆阶段收集的 //go:embed 嵌入文件（相对项目目录），其数据区间不会被修改
+	Overlay               string            // 源码混淆阶段写出的 overlay.json，编译时传给 go build -overlay（项目目录为原项目）
 }
 
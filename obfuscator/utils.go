@@ -1,27 +1,18 @@
 package obfuscator
 
 import (
-	"crypto/rand"
-	"log"
-	"math/big"
 	"path/filepath"
 	"strings"
 )
 
-// generateRandomString 生成随机字母数字字符串
-func generateRandomString(length int) string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	result := make([]byte, length)
-	for i := range result {
-		num, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
-		if err != nil {
-			log.Printf("Warning: Failed to generate random number: %v, using fallback", err)
-			result[i] = charset[i%len(charset)]
-			continue
-		}
-		result[i] = charset[num.Int64()]
+// importPathName 返回导入路径默认的包名：最后一段，主版本后缀（如 math/rand/v2）取前一段
+func importPathName(importPath string) string {
+	parts := strings.Split(importPath, "/")
+	name := parts[len(parts)-1]
+	if len(parts) > 1 && len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = parts[len(parts)-2]
 	}
-	return string(result)
+	return name
 }
 
 // isStandardLibrary 检查导入路径是否属于 Go 标准库
@@ -0,0 +1,162 @@
+package obfuscator
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// vendorDependencies 在输出目录执行 go mod vendor，使第三方依赖也进入源码混淆流程
+func (o *Obfuscator) vendorDependencies(ctx context.Context) error {
+	if _, err := os.Stat(filepath.Join(o.projectRoot, "go.mod")); err != nil {
+		return fmt.Errorf("项目根目录缺少 go.mod: %v", err)
+	}
+
+	// vendorRoot 与遍历输出目录得到的路径保持同一形式，go mod vendor 则需要绝对路径
+	vendorDir := filepath.Join(o.outputDir, "vendor")
+	absVendorDir, err := filepath.Abs(vendorDir)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(vendorDir); err != nil {
+		return fmt.Errorf("清理旧的 vendor 目录失败: %v", err)
+	}
+
+	cmd := exec.CommandContext(ctx, "go", "mod", "vendor", "-o", absVendorDir)
+	cmd.Dir = o.projectRoot
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("go mod vendor 失败: %v\n%s", err, output)
+	}
+	if _, err := os.Stat(vendorDir); err != nil {
+		o.logf("项目没有第三方依赖，跳过 vendor 混淆")
+		return nil
+	}
+
+	o.vendorRoot = vendorDir
+	skipped := o.skipUnsafeVendoredPackages()
+	o.logf("✅ 已将依赖复制到 %s（%d 个包因汇编/linkname/cgo 保持原样）", vendorDir, skipped)
+	return nil
+}
+
+// skipUnsafeVendoredPackages 跳过依赖汇编、//go:linkname 或 cgo 的依赖包：
+// 这些包会按名称引用符号，改名后无法编译或链接。返回跳过的包数量
+func (o *Obfuscator) skipUnsafeVendoredPackages() int {
+	count := 0
+	filepath.Walk(o.vendorRoot, func(dir string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return nil
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil
+		}
+
+		var goFiles []string
+		reason := ""
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			name := entry.Name()
+			path := filepath.Join(dir, name)
+			switch {
+			case strings.HasSuffix(name, ".s"):
+				reason = "Vendored package uses assembly"
+			case strings.HasSuffix(name, ".go"):
+				goFiles = append(goFiles, path)
+				if reason == "" {
+					reason = vendoredFileSkipReason(path)
+				}
+			}
+		}
+
+		if reason != "" && len(goFiles) > 0 {
+			for _, path := range goFiles {
+				o.skippedFiles[path] = reason
+			}
+			count++
+		}
+		return nil
+	})
+	return count
+}
+
+// vendoredFileSkipReason 检查单个依赖文件是否使用 linkname 或 cgo
+func vendoredFileSkipReason(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "//go:linkname "):
+			return "Vendored package uses go:linkname"
+		case line == `import "C"`:
+			return "Vendored package uses cgo"
+		}
+	}
+	return ""
+}
+
+// isVendoredPath 判断文件是否位于 go mod vendor 生成的依赖目录中
+func (o *Obfuscator) isVendoredPath(path string) bool {
+	return o.vendorRoot != "" && strings.HasPrefix(path, o.vendorRoot+string(filepath.Separator))
+}
+
+// isIgnoredVendorPath 项目自带的 vendor/ 始终不处理；启用 ObfuscateVendor 时只处理重新生成的依赖目录
+func (o *Obfuscator) isIgnoredVendorPath(path string) bool {
+	return strings.Contains(filepath.ToSlash(path), "vendor/") && !o.isVendoredPath(path)
+}
+
+// walkSources 遍历项目源码，启用 ObfuscateVendor 时再遍历依赖目录
+func (o *Obfuscator) walkSources(walkFn filepath.WalkFunc) error {
+	if err := filepath.Walk(o.projectRoot, walkFn); err != nil {
+		return err
+	}
+	if o.vendorRoot != "" {
+		return filepath.Walk(o.vendorRoot, walkFn)
+	}
+	return nil
+}
+
+// protectVendoredExportedAPI 保护依赖包声明的所有导出名称，项目和其他依赖对它的引用保持不变
+func (o *Obfuscator) protectVendoredExportedAPI(node *ast.File) {
+	for _, decl := range node.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Name.IsExported() {
+				o.protect(d.Name.Name, reasonVendoredAPI, "", d.Name.Pos())
+			}
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				continue
+			}
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if s.Name.IsExported() {
+						o.protect(s.Name.Name, reasonVendoredAPI, "", s.Name.Pos())
+					}
+				case *ast.ValueSpec:
+					for _, name := range s.Names {
+						if name.IsExported() {
+							o.protect(name.Name, reasonVendoredAPI, "", name.Pos())
+						}
+					}
+				}
+			}
+		}
+	}
+}
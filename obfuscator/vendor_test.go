@@ -0,0 +1,32 @@
+package obfuscator_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cross-file-obfuscator/obfuscator"
+)
+
+// TestVendoredLocalsRenamed 被 vendor 的依赖同样参与类型检查，其中的局部变量按作用域改名
+func TestVendoredLocalsRenamed(t *testing.T) {
+	skipIfShort(t)
+	config := obfuscator.DefaultConfig()
+	config.ObfuscateVendor = true
+	_, out := obfuscateThirdparty(t, config)
+
+	data, err := os.ReadFile(filepath.Join(out, "vendor", "example.com", "orm", "orm.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "joinedResult") {
+		t.Errorf("依赖中的局部变量未改名:\n%s", data)
+	}
+
+	bin := filepath.Join(t.TempDir(), "app")
+	runGo(t, out, "build", "-o", bin, ".")
+	if got := runBinary(t, bin); got != thirdpartyOutput {
+		t.Errorf("输出 %q，期望 %q", got, thirdpartyOutput)
+	}
+}
@@ -0,0 +1,324 @@
+package obfuscator
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WatchOptions Watch 的参数
+type WatchOptions struct {
+	Interval time.Duration     // 轮询间隔，也是合并连续变化的等待时间（默认 300ms）
+	Poll     bool              // 不使用 inotify，始终轮询
+	Ignore   []string          // 不监视的路径，例如编译出的二进制（输出目录总是被忽略）
+	OnUpdate func(WatchUpdate) // 每次更新输出目录后调用（包括首次完整运行），在下一次更新开始前返回
+}
+
+// WatchUpdate 一次更新的结果
+type WatchUpdate struct {
+	Changed  []string      // 触发更新的文件（相对项目目录），首次运行为空
+	Packages []string      // 重新混淆的包目录（相对项目目录）
+	Full     bool          // 是否重新混淆了全部包
+	Result   *Result       // 本次运行的结果，失败时为 nil
+	Err      error         // 失败原因，之后的变化会触发完整运行
+	Duration time.Duration // 耗时
+}
+
+// Watch 先完整混淆一次，然后监视 projectRoot，源码变化后只重新混淆受影响的包并更新 outputDir。
+// 每次运行沿用上一次运行分配的名称（WithPreviousResult），未改动的输出文件保持不变；
+// Config.Seed 为空时生成一个种子供整个监视过程使用。Linux 下使用 inotify，其他平台或 Poll 为 true 时轮询。
+// 不支持 WithSourceFS、WithOutput 和 Config.Overlay。ctx 取消后返回 ctx.Err()；首次运行失败时直接返回错误
+func Watch(ctx context.Context, projectRoot, outputDir string, config *Config, wopts WatchOptions, opts ...Option) error {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	opt := applyOptions(opts)
+	if opt.sourceFS != nil || opt.output != nil {
+		return fmt.Errorf("监视模式需要读写真实目录，不支持 WithSourceFS 和 WithOutput")
+	}
+	if config.Overlay {
+		return fmt.Errorf("监视模式需要完整的输出目录，不支持 overlay 模式")
+	}
+	seed := config.Seed
+	if opt.seed != nil {
+		seed = *opt.seed
+	}
+	if seed == "" {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return err
+		}
+		seed = hex.EncodeToString(b)
+	}
+	if wopts.Interval <= 0 {
+		wopts.Interval = 300 * time.Millisecond
+	}
+
+	root, err := filepath.Abs(projectRoot)
+	if err != nil {
+		return err
+	}
+	out, err := filepath.Abs(outputDir)
+	if err != nil {
+		return err
+	}
+	ignore := []string{out}
+	for _, path := range wopts.Ignore {
+		if abs, err := filepath.Abs(path); err == nil {
+			ignore = append(ignore, abs)
+		}
+	}
+
+	// 先开始监视再运行，首次运行期间的修改不会丢失
+	watcher, err := newChangeWatcher(root, ignore, wopts)
+	if err != nil {
+		return err
+	}
+	defer watcher.close()
+
+	s := &watchSession{
+		root:   root,
+		out:    out,
+		config: config,
+		opts:   append(append([]Option(nil), opts...), WithSeed(seed)),
+		notify: wopts.OnUpdate,
+	}
+	if err := s.update(ctx, nil); err != nil {
+		return err
+	}
+	for {
+		changed, err := watcher.wait(ctx)
+		if err != nil {
+			return err
+		}
+		s.update(ctx, changed)
+	}
+}
+
+// watchSession 监视过程中保存上一次运行的状态
+type watchSession struct {
+	root, out string
+	config    *Config
+	opts      []Option
+	notify    func(WatchUpdate)
+
+	prev  *Result
+	last  *Obfuscator
+	dirty bool // 上一次运行失败，输出目录可能不完整
+}
+
+// update 处理一批变化的路径（绝对路径，nil 表示首次运行）并更新输出目录
+func (s *watchSession) update(ctx context.Context, changed []string) error {
+	start := time.Now()
+	u := WatchUpdate{Full: s.prev == nil || s.dirty || s.config.ObfuscateVendor}
+
+	dirs := make(map[string]bool)
+	for _, path := range changed {
+		rel, err := filepath.Rel(s.root, path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		u.Changed = append(u.Changed, rel)
+		if s.needsFullRun(path, rel) {
+			u.Full = true
+			continue
+		}
+		info, err := os.Stat(path)
+		switch {
+		case strings.HasSuffix(path, ".go"):
+			if err != nil {
+				// 删除的 Go 文件：移除对应的输出文件
+				os.Remove(filepath.Join(s.out, s.outputPath(rel)))
+			}
+			dirs[filepath.Dir(rel)] = true
+		case err != nil:
+			os.Remove(filepath.Join(s.out, rel))
+		case !info.IsDir():
+			// 其他文件原样同步
+			if err := s.last.copyFile(path, filepath.Join(s.out, rel)); err != nil {
+				u.Err = err
+			}
+		}
+	}
+	if !u.Full && len(dirs) == 0 {
+		if len(u.Changed) > 0 && s.notify != nil {
+			u.Result = s.prev
+			u.Duration = time.Since(start)
+			s.notify(u)
+		}
+		return nil
+	}
+
+	opts := s.opts
+	if s.prev != nil {
+		opts = append(append([]Option(nil), opts...), WithPreviousResult(s.prev))
+	}
+	o := New(s.root, s.out, s.config, opts...)
+	if !u.Full {
+		o.onlyDirs = dirs
+	}
+	result, err := o.Run(ctx)
+	u.Duration = time.Since(start)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		s.dirty = true
+		u.Err = err
+		if s.prev == nil {
+			return err
+		}
+	} else {
+		s.prev, s.last, s.dirty = result, o, false
+		u.Result = result
+		u.Full = o.onlyDirs == nil
+		for _, pkg := range o.transformed {
+			u.Packages = append(u.Packages, pkg.Dir)
+		}
+		sort.Strings(u.Packages)
+	}
+	if s.notify != nil {
+		s.notify(u)
+	}
+	return nil
+}
+
+// needsFullRun 判断变化是否影响全部包：模块文件、目录、被 //go:embed 嵌入的文件和模板文件
+// （后两者决定哪些名称受保护）
+func (s *watchSession) needsFullRun(path, rel string) bool {
+	switch filepath.Base(rel) {
+	case "go.mod", "go.sum", "go.work", "go.work.sum":
+		return true
+	}
+	if rel == "." {
+		return true
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return true
+	}
+	// 删除或移走的目录：输出目录中对应的仍是目录
+	if info, err := os.Stat(filepath.Join(s.out, rel)); err == nil && info.IsDir() {
+		return true
+	}
+	return s.last != nil && (s.last.embeddedFiles[path] || s.last.parsedTemplates[path])
+}
+
+// outputPath 返回源文件（相对项目目录）在输出目录中的相对路径
+func (s *watchSession) outputPath(rel string) string {
+	if s.prev != nil && s.prev.Mapping != nil {
+		if to, ok := s.prev.Mapping.Files[rel]; ok {
+			return to
+		}
+	}
+	return rel
+}
+
+// changeWatcher 报告项目目录中发生变化的路径
+type changeWatcher interface {
+	// wait 阻塞到有文件变化或 ctx 取消，返回变化的路径（绝对路径，去重排序）
+	wait(ctx context.Context) ([]string, error)
+	close() error
+}
+
+// newChangeWatcher 优先使用 inotify，不可用时回退到轮询
+func newChangeWatcher(root string, ignore []string, wopts WatchOptions) (changeWatcher, error) {
+	skip := watchSkipper(root, ignore)
+	if !wopts.Poll {
+		if w, err := newInotifyWatcher(root, skip, wopts.Interval); err == nil {
+			return w, nil
+		}
+	}
+	return newPollWatcher(root, skip, wopts.Interval), nil
+}
+
+// watchSkipper 返回判断路径是否不需要监视的函数：被忽略的路径及其子路径、以 . 开头的文件和目录
+// （.git、编辑器的临时文件）和以 ~ 结尾的备份文件
+func watchSkipper(root string, ignore []string) func(path string) bool {
+	return func(path string) bool {
+		for _, p := range ignore {
+			if path == p || strings.HasPrefix(path, p+string(filepath.Separator)) {
+				return true
+			}
+		}
+		if path == root {
+			return false
+		}
+		name := filepath.Base(path)
+		return strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~")
+	}
+}
+
+// fileStamp 轮询时比较的文件状态
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+	mode    os.FileMode
+}
+
+// pollWatcher 定期遍历项目目录，比较文件大小、修改时间和权限
+type pollWatcher struct {
+	root     string
+	skip     func(string) bool
+	interval time.Duration
+	files    map[string]fileStamp
+}
+
+func newPollWatcher(root string, skip func(string) bool, interval time.Duration) *pollWatcher {
+	w := &pollWatcher{root: root, skip: skip, interval: interval}
+	w.files = w.snapshot()
+	return w
+}
+
+func (w *pollWatcher) snapshot() map[string]fileStamp {
+	files := make(map[string]fileStamp)
+	filepath.Walk(w.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || w.skip(path) {
+			if err == nil && info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() {
+			files[path] = fileStamp{size: info.Size(), modTime: info.ModTime(), mode: info.Mode()}
+		}
+		return nil
+	})
+	return files
+}
+
+func (w *pollWatcher) wait(ctx context.Context) ([]string, error) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+		files := w.snapshot()
+		var changed []string
+		for path, stamp := range files {
+			if old, ok := w.files[path]; !ok || old != stamp {
+				changed = append(changed, path)
+			}
+		}
+		for path := range w.files {
+			if _, ok := files[path]; !ok {
+				changed = append(changed, path)
+			}
+		}
+		w.files = files
+		if len(changed) > 0 {
+			sort.Strings(changed)
+			return changed, nil
+		}
+	}
+}
+
+func (w *pollWatcher) close() error { return nil }
@@ -0,0 +1,149 @@
+package obfuscator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// inotifyMask 监视的事件：文件写入、创建、删除和移动
+const inotifyMask = syscall.IN_CLOSE_WRITE | syscall.IN_MODIFY | syscall.IN_CREATE | syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_ATTRIB
+
+// inotifyWatcher 用 inotify 监视项目中的每个目录。文件描述符为非阻塞模式，
+// 交给运行时的网络轮询器，读取可以设置超时
+type inotifyWatcher struct {
+	fd       int // 添加监视用（File.Fd 会把描述符改回阻塞模式）
+	file     *os.File
+	root     string
+	skip     func(string) bool
+	debounce time.Duration
+	dirs     map[int32]string // 监视描述符 -> 目录
+}
+
+func newInotifyWatcher(root string, skip func(string) bool, debounce time.Duration) (changeWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	w := &inotifyWatcher{
+		fd:       fd,
+		file:     os.NewFile(uintptr(fd), "inotify"),
+		root:     root,
+		skip:     skip,
+		debounce: debounce,
+		dirs:     make(map[int32]string),
+	}
+	if _, err := w.addTree(root); err != nil {
+		w.close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// addTree 监视 dir 及其全部子目录，返回其中已有的文件（新建的目录中可能已经写入了文件）
+func (w *inotifyWatcher) addTree(dir string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			return nil
+		}
+		if w.skip(path) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			return nil
+		}
+		wd, err := syscall.InotifyAddWatch(w.fd, path, inotifyMask)
+		if err != nil {
+			return err
+		}
+		w.dirs[int32(wd)] = path
+		return nil
+	})
+	return files, err
+}
+
+func (w *inotifyWatcher) wait(ctx context.Context) ([]string, error) {
+	changed := make(map[string]bool)
+	buf := make([]byte, 64*1024)
+	for {
+		// 没有变化时每 200ms 检查一次 ctx；收到变化后再等待 debounce，合并同一次保存产生的多个事件
+		timeout := 200 * time.Millisecond
+		if len(changed) > 0 {
+			timeout = w.debounce
+		}
+		w.file.SetReadDeadline(time.Now().Add(timeout))
+		n, err := w.file.Read(buf)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if len(changed) > 0 {
+				paths := make([]string, 0, len(changed))
+				for path := range changed {
+					paths = append(paths, path)
+				}
+				sort.Strings(paths)
+				return paths, nil
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		w.parse(buf[:n], changed)
+	}
+}
+
+// parse 解析一批 inotify 事件，记录变化的路径；新建的目录加入监视
+func (w *inotifyWatcher) parse(buf []byte, changed map[string]bool) {
+	for off := 0; off+syscall.SizeofInotifyEvent <= len(buf); {
+		ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+		nameStart := off + syscall.SizeofInotifyEvent
+		off = nameStart + int(ev.Len)
+
+		if ev.Mask&syscall.IN_Q_OVERFLOW != 0 {
+			// 事件队列溢出，无法知道哪些文件变化，报告项目目录本身（完整运行）
+			changed[w.root] = true
+			continue
+		}
+		if ev.Mask&syscall.IN_IGNORED != 0 {
+			delete(w.dirs, ev.Wd)
+			continue
+		}
+		dir, ok := w.dirs[ev.Wd]
+		if !ok || ev.Len == 0 {
+			continue
+		}
+		name := string(bytes.TrimRight(buf[nameStart:off], "\x00"))
+		path := filepath.Join(dir, name)
+		if w.skip(path) {
+			continue
+		}
+		changed[path] = true
+		if ev.Mask&syscall.IN_ISDIR != 0 && ev.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+			files, _ := w.addTree(path)
+			for _, f := range files {
+				changed[f] = true
+			}
+		}
+	}
+}
+
+func (w *inotifyWatcher) close() error {
+	return w.file.Close()
+}
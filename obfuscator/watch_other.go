@@ -0,0 +1,13 @@
+//go:build !linux
+
+package obfuscator
+
+import (
+	"errors"
+	"time"
+)
+
+// newInotifyWatcher inotify 只在 Linux 上可用，其他平台回退到轮询
+func newInotifyWatcher(root string, skip func(string) bool, debounce time.Duration) (changeWatcher, error) {
+	return nil, errors.New("inotify 不可用")
+}
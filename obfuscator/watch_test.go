@@ -0,0 +1,78 @@
+package obfuscator_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cross-file-obfuscator/obfuscator"
+)
+
+// TestWatchIncremental 监视模式下修改一个包只重新混淆该包，其他输出文件保持不变，输出仍可编译运行
+func TestWatchIncremental(t *testing.T) {
+	skipIfShort(t)
+	project := copyTestdata(t, "app")
+	out := filepath.Join(t.TempDir(), "out")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	updates := make(chan obfuscator.WatchUpdate, 4)
+	done := make(chan error, 1)
+	go func() {
+		done <- obfuscator.Watch(ctx, project, out, obfuscator.DefaultConfig(), obfuscator.WatchOptions{
+			Interval: 50 * time.Millisecond,
+			Poll:     true,
+			OnUpdate: func(u obfuscator.WatchUpdate) { updates <- u },
+		}, obfuscator.WithSeed("test-seed"))
+	}()
+	defer func() {
+		cancel()
+		if err := <-done; err != context.Canceled {
+			t.Errorf("Watch 返回 %v，期望 context.Canceled", err)
+		}
+	}()
+
+	next := func() obfuscator.WatchUpdate {
+		t.Helper()
+		select {
+		case u := <-updates:
+			if u.Err != nil {
+				t.Fatalf("更新失败: %v", u.Err)
+			}
+			return u
+		case <-time.After(time.Minute):
+			t.Fatal("等待更新超时")
+		}
+		return obfuscator.WatchUpdate{}
+	}
+
+	if first := next(); !first.Full {
+		t.Fatalf("首次运行应完整混淆: %+v", first)
+	}
+	mainBefore, err := os.ReadFile(filepath.Join(out, "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	greet := filepath.Join(project, "greet", "greet.go")
+	src, err := os.ReadFile(greet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(greet, []byte(strings.Replace(string(src), `"hello, "`, `"hi, "`, 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	second := next()
+	if second.Full || len(second.Packages) != 1 || second.Packages[0] != "greet" {
+		t.Fatalf("只应重新混淆 greet 包: Full=%v Packages=%v", second.Full, second.Packages)
+	}
+	if mainAfter, err := os.ReadFile(filepath.Join(out, "main.go")); err != nil || string(mainAfter) != string(mainBefore) {
+		t.Error("未改动的包的输出文件不应变化")
+	}
+	if got := runGo(t, out, "run", ".", "gopher"); got != "[hi, GOPHER]\n" {
+		t.Errorf("增量更新后的输出 %q", got)
+	}
+}